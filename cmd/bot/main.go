@@ -13,11 +13,16 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+	// Встроенная база часовых поясов для пользователей в разных поясах
+	_ "time/tzdata"
 
 	"GreenAssistantBot/internal/database"
 	"GreenAssistantBot/internal/storage"
+	"GreenAssistantBot/internal/telegram"
+	"GreenAssistantBot/internal/weather"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/joho/godotenv"
+	"gorm.io/gorm"
 )
 
 // BotMode представляет режим работы бота
@@ -82,6 +87,52 @@ func monitorStorage(storage storage.BotStorage) {
 	}
 }
 
+// monitorWeather периодически пишет в лог счетчики кэша погоды
+func monitorWeather(weatherService *weather.WeatherService) {
+	ticker := time.NewTicker(30 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if stats := weatherService.GetStats(); stats != nil {
+			log.Printf("Weather cache stats: %+v", stats)
+		}
+	}
+}
+
+// monitorSender периодически пишет в лог счетчики очереди исходящих сообщений
+func monitorSender(queue *telegram.Queue) {
+	ticker := time.NewTicker(30 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		log.Printf("Telegram queue stats: %+v", queue.GetStats())
+	}
+}
+
+// monitorUpdates периодически пишет в лог счетчики обработки обновлений
+func monitorUpdates(updateHandler *bot.UpdateHandler) {
+	ticker := time.NewTicker(30 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		log.Printf("Update handling stats: %+v", updateHandler.GetStats())
+	}
+}
+
+// newStorage создает хранилище состояний по значению STORAGE_DRIVER
+func newStorage(driver string, db *gorm.DB) (storage.BotStorage, error) {
+	switch strings.ToLower(driver) {
+	case "", "memory":
+		log.Println("Using in-memory storage")
+		return storage.NewMemoryStorage()
+	case "sql":
+		log.Println("Using SQL storage")
+		return storage.NewSQLStorage(db)
+	default:
+		return nil, fmt.Errorf("unknown storage driver: %s", driver)
+	}
+}
+
 // setupWebhook настраивает вебхук для бота
 func setupWebhook(api *tgbotapi.BotAPI, webhookURL string) error {
 	log.Println("Setting up webhook...")
@@ -128,6 +179,16 @@ func setupPolling(api *tgbotapi.BotAPI) tgbotapi.UpdatesChannel {
 	return updates
 }
 
+// setupCommands публикует список слеш-команд, который Telegram показывает в меню бота
+func setupCommands(api *tgbotapi.BotAPI) {
+	commands := bot.BotCommands()
+	if _, err := api.Request(tgbotapi.NewSetMyCommands(commands...)); err != nil {
+		log.Printf("Warning: failed to set bot commands: %v", err)
+		return
+	}
+	log.Printf("Bot commands configured: %d", len(commands))
+}
+
 // getBotMode определяет режим работы бота
 func getBotMode() BotMode {
 	mode := strings.ToLower(os.Getenv("BOT_MODE"))
@@ -185,10 +246,9 @@ func main() {
 
 	// Инициализация базы данных
 	db := database.GetConnect()
-	_ = db
 
 	// Инициализация хранилища
-	botStorage, err := storage.NewMemoryStorage()
+	botStorage, err := newStorage(os.Getenv("STORAGE_DRIVER"), db)
 	if err != nil {
 		log.Fatalf("Failed to create storage: %v", err)
 	}
@@ -202,11 +262,21 @@ func main() {
 	}
 
 	log.Printf("Authorized on account %s", api.Self.UserName)
+	setupCommands(api)
 
 	// Инициализация обработчиков
-	updateHandler := bot.NewUpdateHandler(api, botStorage)
-	scheduler := scheduler.NewScheduler(updateHandler.GetMessageHandler())
+	weatherService := weather.NewWeatherService()
+	go monitorWeather(weatherService)
+	// Все исходящие запросы, включая планировщик, идут через общую очередь с лимитами Telegram
+	sender := telegram.NewQueue(telegram.NewBotSender(api), telegram.DefaultQueueConfig())
+	go monitorSender(sender)
+	updateHandler := bot.NewUpdateHandler(sender, botStorage, weatherService)
+	go monitorUpdates(updateHandler)
+	scheduler := scheduler.NewScheduler(updateHandler.GetMessageHandler(), sender, weatherService)
 	scheduler.StartWeatherNotifications()
+	scheduler.StartTrashPurge()
+	scheduler.StartReminders()
+	scheduler.StartWeatherAlerts()
 
 	var updates tgbotapi.UpdatesChannel
 	var server *http.Server
@@ -284,6 +354,11 @@ func main() {
 
 	log.Println("Shutting down bot...")
 
+	// Перестаем забирать обновления у Telegram
+	if config.Mode == PollingMode {
+		api.StopReceivingUpdates()
+	}
+
 	// Graceful shutdown
 	if config.Mode == WebhookMode || (config.Mode == PollingMode && config.HTTPPort != "") {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -304,5 +379,12 @@ func main() {
 		}
 	}
 
+	// Дожидаемся обработки уже полученных обновлений
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelDrain()
+	if err := updateHandler.Shutdown(drainCtx); err != nil {
+		log.Printf("Not all updates were processed before shutdown: %v", err)
+	}
+
 	log.Println("Bot gracefully stopped")
 }
@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"GreenAssistantBot/internal/telegram"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Telegram ограничивает callback_data 64 байтами
+const maxCallbackDataLength = 64
+
+// Длина подписи в байтах до кодирования в base64 (8 символов)
+const callbackSignatureLength = 6
+
+var ErrInvalidCallback = errors.New("invalid callback data")
+
+// CallbackCodec кодирует данные inline-кнопок в компактный вид
+// "действие:арг1:арг2|подпись" и проверяет подпись при разборе,
+// чтобы нельзя было подделать ID в нажатой кнопке
+type CallbackCodec struct {
+	secret []byte
+}
+
+func NewCallbackCodec(secret string) *CallbackCodec {
+	return &CallbackCodec{secret: []byte(secret)}
+}
+
+// Encode формирует callback_data для кнопки
+func (c *CallbackCodec) Encode(action string, args ...string) string {
+	payload := strings.Join(append([]string{action}, args...), ":")
+	data := payload + "|" + c.sign(payload)
+	if len(data) > maxCallbackDataLength {
+		log.Printf("Warning: callback data %q exceeds %d bytes", data, maxCallbackDataLength)
+	}
+	return data
+}
+
+// Decode проверяет подпись и возвращает действие и аргументы
+func (c *CallbackCodec) Decode(data string) (string, []string, error) {
+	sep := strings.LastIndex(data, "|")
+	if sep == -1 {
+		return "", nil, ErrInvalidCallback
+	}
+
+	payload, signature := data[:sep], data[sep+1:]
+	if !hmac.Equal([]byte(signature), []byte(c.sign(payload))) {
+		return "", nil, ErrInvalidCallback
+	}
+
+	parts := strings.Split(payload, ":")
+	return parts[0], parts[1:], nil
+}
+
+func (c *CallbackCodec) sign(payload string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:callbackSignatureLength])
+}
+
+// callbackHandler обрабатывает нажатие кнопки и возвращает текст
+// всплывающего уведомления (может быть пустым)
+type callbackHandler func(query *tgbotapi.CallbackQuery, args []string) string
+
+// callbackRouter направляет нажатия inline-кнопок в обработчики по действию
+type callbackRouter struct {
+	sender   telegram.Sender
+	codec    *CallbackCodec
+	handlers map[string]callbackHandler
+}
+
+func newCallbackRouter(sender telegram.Sender, codec *CallbackCodec) *callbackRouter {
+	return &callbackRouter{
+		sender:   sender,
+		codec:    codec,
+		handlers: make(map[string]callbackHandler),
+	}
+}
+
+func (r *callbackRouter) Register(action string, handler callbackHandler) {
+	if _, exists := r.handlers[action]; exists {
+		log.Printf("Warning: callback action %q registered twice", action)
+	}
+	r.handlers[action] = handler
+}
+
+// Handle обрабатывает нажатие и всегда отвечает на callback query,
+// чтобы у пользователя пропал индикатор загрузки на кнопке
+func (r *callbackRouter) Handle(query *tgbotapi.CallbackQuery) {
+	answer := ""
+
+	action, args, err := r.codec.Decode(query.Data)
+	if err != nil {
+		log.Printf("Rejected callback %q from %d: %v", query.Data, query.From.ID, err)
+		answer = "⚠️ Кнопка устарела"
+	} else if handler, ok := r.handlers[action]; !ok {
+		log.Printf("Unknown callback action %q", action)
+		answer = "⚠️ Неизвестное действие"
+	} else if query.Message == nil {
+		answer = "⚠️ Сообщение недоступно"
+	} else {
+		answer = handler(query, args)
+	}
+
+	if err := r.sender.AnswerCallback(query.ID, answer); err != nil {
+		log.Printf("Error answering callback query: %v", err)
+	}
+}
+
+// callbackID кодирует числовой ID для callback_data
+func callbackID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// callbackIDs кодирует список ID в один аргумент callback_data ("1.5.9")
+func callbackIDs(ids []uint) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = callbackID(id)
+	}
+	return strings.Join(parts, ".")
+}
+
+// callbackArgIDs разбирает аргумент, закодированный через callbackIDs
+func callbackArgIDs(arg string) []uint {
+	if arg == "" {
+		return nil
+	}
+
+	var ids []uint
+	for _, part := range strings.Split(arg, ".") {
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids
+}
+
+// callbackArgID извлекает числовой ID из аргументов callback_data
+func callbackArgID(args []string, index int) (uint, bool) {
+	if index >= len(args) {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(args[index], 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint(id), true
+}
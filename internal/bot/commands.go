@@ -3,9 +3,12 @@ package bot
 import (
 	"GreenAssistantBot/internal/database"
 	"GreenAssistantBot/internal/storage"
+	"GreenAssistantBot/internal/telegram"
 	"GreenAssistantBot/internal/weather"
+	pmodel "GreenAssistantBot/pkg/models"
 	"fmt"
 	"log"
+	"os"
 	_ "strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -18,45 +21,79 @@ const (
 	StateChangingNameFromProfile = "changing_name_from_profile"
 	StateChangingCityFromProfile = "changing_city_from_profile"
 	StateWaitingForWeatherCity   = "waiting_for_weather_city"
+
+	StateWaitingForTimezone         = "waiting_for_timezone"
+	StateWaitingForNotificationTime = "waiting_for_notification_time"
+	StateWaitingForFrostThreshold   = "waiting_for_frost_threshold"
+	StateWaitingForQuietHours       = "waiting_for_quiet_hours"
+	StateWaitingForLocationLabel    = "waiting_for_location_label"
+	StateWaitingForLocationCity     = "waiting_for_location_city"
 )
 
 const (
-	StateWaitingForNoteCategory = "waiting_for_note_category"
-	StateWaitingForCategoryName = "waiting_for_category_name"
-	StateWaitingForNoteContent  = "waiting_for_note_content"
-	StateEditingCategory        = "editing_category"
-	StateDeletingCategory       = "deleting_category"
+	StateWaitingForNoteCategory    = "waiting_for_note_category"
+	StateWaitingForCategoryName    = "waiting_for_category_name"
+	StateWaitingForNoteContent     = "waiting_for_note_content"
+	StateSelectingCategoryToView   = "selecting_category_to_view"
+	StateSelectingCategoryToEdit   = "selecting_category_to_edit"
+	StateSelectingCategoryToDelete = "selecting_category_to_delete"
+	StateEditingCategory           = "editing_category"
+	StateDeletingCategory          = "deleting_category"
 )
 
 const (
-	StateWaitingForNoteSelection = "waiting_for_note_selection"
-	StateEditingNote             = "editing_note"
-	StateDeletingNote            = "deleting_note"
-	SaveForwardedMessage         = "save_forwarded_message"
+	StateEditingNote            = "editing_note"
+	SaveForwardedMessage        = "save_forwarded_message"
+	StateWaitingForSearchQuery  = "waiting_for_search_query"
+	StateWaitingForTagRename    = "waiting_for_tag_rename"
+	StateWaitingForTagMerge     = "waiting_for_tag_merge"
+	StateWaitingForReminderTime = "waiting_for_reminder_time"
 )
 
+// categorySelectionStates — состояние выбора категории для каждой цели
+var categorySelectionStates = map[pmodel.Purpose]string{
+	pmodel.PurposeNewNote:        StateWaitingForNoteCategory,
+	pmodel.PurposeViewNotes:      StateSelectingCategoryToView,
+	pmodel.PurposeEditCategory:   StateSelectingCategoryToEdit,
+	pmodel.PurposeDeleteCategory: StateSelectingCategoryToDelete,
+	pmodel.PurposeSaveForwarded:  SaveForwardedMessage,
+}
+
 type MessageHandler struct {
-	bot     *tgbotapi.BotAPI
-	storage storage.BotStorage
+	sender    telegram.Sender
+	storage   storage.BotStorage
+	callbacks *CallbackCodec
+	weather   *weather.WeatherService
 }
 
-func NewMessageHandler(bot *tgbotapi.BotAPI, storage storage.BotStorage) *MessageHandler {
-	return &MessageHandler{bot: bot, storage: storage}
+func NewMessageHandler(sender telegram.Sender, storage storage.BotStorage, weatherService *weather.WeatherService) *MessageHandler {
+	// Подписываем callback_data секретом из окружения или токеном бота
+	secret := os.Getenv("CALLBACK_SECRET")
+	if secret == "" {
+		secret = os.Getenv("BOT_TOKEN")
+	}
+
+	return &MessageHandler{sender: sender, storage: storage, callbacks: NewCallbackCodec(secret), weather: weatherService}
 }
 
 func (h *MessageHandler) sendMessage(chatID int64, text string, replyMarkup interface{}) error {
-	msg := tgbotapi.NewMessage(chatID, text)
-
-	if replyMarkup != nil {
-		msg.ReplyMarkup = replyMarkup
+	messageID, err := h.sender.SendText(telegram.Text{ChatID: chatID, Text: text, ReplyMarkup: replyMarkup})
+	if err != nil {
+		return fmt.Errorf("send message failed: %w", err)
 	}
 
-	sentMsg, err := h.bot.Send(msg)
+	h.storage.SetLastMessageID(chatID, messageID)
+	return nil
+}
+
+// sendHTML отправляет сообщение с HTML-разметкой
+func (h *MessageHandler) sendHTML(chatID int64, text string, replyMarkup interface{}) error {
+	messageID, err := h.sender.SendText(telegram.Text{ChatID: chatID, Text: text, ParseMode: tgbotapi.ModeHTML, ReplyMarkup: replyMarkup})
 	if err != nil {
 		return fmt.Errorf("send message failed: %w", err)
 	}
 
-	h.storage.SetLastMessageID(chatID, sentMsg.MessageID)
+	h.storage.SetLastMessageID(chatID, messageID)
 	return nil
 }
 
@@ -74,15 +111,23 @@ func (h *MessageHandler) SendStartMessage(chatID int64) {
 }
 
 func (h *MessageHandler) AskForName(chatID int64) {
-	h.sendMessage(chatID, "✏️ Пожалуйста, введите ваше имя:", CreateMainMenuKeyboard())
+	h.promptName(chatID)
 	h.storage.SetUserState(chatID, StateWaitingForName)
 }
 
 func (h *MessageHandler) AskForCity(chatID int64) {
-	h.sendMessage(chatID, "🚩 Пожалуйста, введите ваш город:", CreateMainMenuKeyboard())
+	h.promptCity(chatID)
 	h.storage.SetUserState(chatID, StateWaitingForCity)
 }
 
+func (h *MessageHandler) promptName(chatID int64) {
+	h.sendMessage(chatID, "✏️ Пожалуйста, введите ваше имя:", CreateMainMenuKeyboard())
+}
+
+func (h *MessageHandler) promptCity(chatID int64) {
+	h.sendMessage(chatID, "🚩 Пожалуйста, введите ваш город или отправьте местоположение:", CreateCityInputKeyboard())
+}
+
 func (h *MessageHandler) CompleteProfile(chatID int64) {
 	user, err := database.GetUserByTelegramID(chatID)
 	if err != nil {
@@ -91,10 +136,12 @@ func (h *MessageHandler) CompleteProfile(chatID int64) {
 	}
 
 	text := fmt.Sprintf("✅ Анкета заполнена!\n\n👤 Ваш профиль:\n✏️ Имя: %s\n🚩 Город: %s",
-		user.FirstName, user.City)
+		user.FirstName, cityLabel(user))
+	if user.Timezone != "" {
+		text += fmt.Sprintf("\n🕒 Часовой пояс: %s", user.Timezone)
+	}
 
 	h.sendMessage(chatID, text, CreateMainMenuKeyboard())
-	h.storage.SetUserState(chatID, "")
 }
 
 func (h *MessageHandler) SendProfileSettings(chatID int64) {
@@ -104,39 +151,40 @@ func (h *MessageHandler) SendProfileSettings(chatID int64) {
 		return
 	}
 
-	text := fmt.Sprintf("👤 Ваш профиль\n✏️ Имя: %s\n🚩 Город: %s", user.FirstName, user.City)
+	text := fmt.Sprintf("👤 Ваш профиль\n✏️ Имя: %s\n🚩 Город: %s", user.FirstName, cityLabel(user))
+	if user.Timezone != "" {
+		text += fmt.Sprintf("\n🕒 Часовой пояс: %s", user.Timezone)
+	}
 	h.sendMessage(chatID, text, CreateProfileMenuKeyboard())
 }
 
 func (h *MessageHandler) SendWeather(chatID int64, city string) {
-	weatherService := weather.NewWeatherService()
-	weatherData, err := weatherService.GetWeatherData(city)
+	prefs := h.chatWeatherPreferences(chatID)
+	weatherData, err := h.weather.GetWeatherData(city, prefs)
 
-	var text string
-	if err != nil {
-		text = fmt.Sprintf("❌ Не удалось получить данные о погоде для города '%s'", city)
-	} else {
-		text = weatherService.FormatWeatherMessage(weatherData)
-	}
-
-	err = h.sendMessage(chatID, text, CreateMainMenuKeyboard())
 	if err != nil {
+		h.sendMessage(chatID, fmt.Sprintf("❌ Не удалось получить данные о погоде для города '%s'", city), CreateMainMenuKeyboard())
 		return
 	}
+
+	h.sendWeatherData(chatID, weatherData, prefs)
+}
+
+// sendWeatherData отправляет текущую погоду с кнопками прогноза
+func (h *MessageHandler) sendWeatherData(chatID int64, weatherData *weather.WeatherData, prefs weather.Preferences) {
+	// Кнопки прогноза ссылаются на координаты, чтобы уложиться в лимит callback_data
+	text := h.weather.FormatWeatherMessage(weatherData, prefs)
+	h.sendMessage(chatID, text, CreateWeatherKeyboard(h.callbacks, weatherData.Lat, weatherData.Lon, weatherModeNow))
 }
 
 func (h *MessageHandler) DeleteLastBotMessage(chatID int64) {
 	if messageID, exists := h.storage.GetLastMessageID(chatID); exists {
-		deleteConfig := tgbotapi.DeleteMessageConfig{ChatID: chatID, MessageID: messageID}
-		h.bot.Request(deleteConfig) // Игнорируем ошибку
+		h.sender.Delete(chatID, messageID) // Игнорируем ошибку
 	}
 }
 
 func (h *MessageHandler) SendMainMenu(chatID int64) {
-	msg := tgbotapi.NewMessage(chatID, "Выберите один из пунктов")
-	msg.ReplyMarkup = CreateMainMenuKeyboard()
-
-	_, err := h.bot.Send(msg)
+	_, err := h.sender.SendText(telegram.Text{ChatID: chatID, Text: "Выберите один из пунктов", ReplyMarkup: CreateMainMenuKeyboard()})
 	if err != nil {
 		log.Printf("Error sending main menu: %v", err)
 	}
@@ -152,10 +200,7 @@ func (h *MessageHandler) SendSupport(chatID int64) {
 
 Мы всегда готовы помочь!`
 
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ReplyMarkup = CreateMainMenuKeyboard()
-
-	_, err := h.bot.Send(msg)
+	_, err := h.sender.SendText(telegram.Text{ChatID: chatID, Text: text, ReplyMarkup: CreateMainMenuKeyboard()})
 	if err != nil {
 		log.Printf("Error sending support: %v", err)
 	}
@@ -170,10 +215,7 @@ func (h *MessageHandler) SendInfo(chatID int64) {
 
 Используйте меню для навигации.`
 
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ReplyMarkup = CreateMainMenuKeyboard()
-
-	_, err := h.bot.Send(msg)
+	_, err := h.sender.SendText(telegram.Text{ChatID: chatID, Text: text, ReplyMarkup: CreateMainMenuKeyboard()})
 	if err != nil {
 		log.Printf("Error sending info: %v", err)
 	}
@@ -188,10 +230,7 @@ func (h *MessageHandler) SendNotificationsSettings(chatID int64) {
 
 Используйте кнопки ниже для изменения настроек.`
 
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ReplyMarkup = CreateSettingsMenuKeyboard()
-
-	_, err := h.bot.Send(msg)
+	_, err := h.sender.SendText(telegram.Text{ChatID: chatID, Text: text, ReplyMarkup: CreateSettingsMenuKeyboard()})
 	if err != nil {
 		log.Printf("Error sending notifications settings: %v", err)
 	}
@@ -199,10 +238,7 @@ func (h *MessageHandler) SendNotificationsSettings(chatID int64) {
 
 func (h *MessageHandler) SendSettingsMenu(chatID int64) {
 	text := "⚙️ Настройки"
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ReplyMarkup = CreateSettingsMenuKeyboard()
-
-	_, err := h.bot.Send(msg)
+	_, err := h.sender.SendText(telegram.Text{ChatID: chatID, Text: text, ReplyMarkup: CreateSettingsMenuKeyboard()})
 	if err != nil {
 		log.Printf("Error sending settings menu: %v", err)
 	}
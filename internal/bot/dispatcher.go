@@ -0,0 +1,171 @@
+package bot
+
+import (
+	"context"
+	"log"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// Сколько обновлений по умолчанию обрабатывается одновременно (в разных чатах)
+	defaultUpdateWorkers = 8
+	maxUpdateWorkers     = 256
+	// Сколько необработанных обновлений может накопиться в одном чате. Лишние
+	// отбрасываются, чтобы один пользователь не занял всю очередь.
+	chatBacklogLimit = 20
+	// Сколько необработанных обновлений может накопиться всего. При переполнении
+	// Dispatch ждет, и бот перестает забирать новые обновления у Telegram.
+	pendingUpdatesLimit = 1000
+)
+
+// updateDispatcher раздает обновления по чатам: обновления одного чата
+// обрабатываются строго по очереди, а разных чатов — параллельно, не больше
+// workers одновременно
+type updateDispatcher struct {
+	handle func(tgbotapi.Update)
+
+	// workers ограничивает число одновременно обрабатываемых обновлений
+	workers chan struct{}
+	// pending ограничивает общее число принятых и еще не обработанных обновлений
+	pending chan struct{}
+
+	mu sync.Mutex
+	// chats — очереди чатов; чат есть в карте, пока его очередь обрабатывается
+	chats  map[int64][]tgbotapi.Update
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func newUpdateDispatcher(workers int, handle func(tgbotapi.Update)) *updateDispatcher {
+	return &updateDispatcher{
+		handle:  handle,
+		workers: make(chan struct{}, workers),
+		pending: make(chan struct{}, pendingUpdatesLimit),
+		chats:   make(map[int64][]tgbotapi.Update),
+	}
+}
+
+// updateWorkersFromEnv читает число параллельных обработчиков из UPDATE_WORKERS
+func updateWorkersFromEnv() int {
+	value := os.Getenv("UPDATE_WORKERS")
+	if value == "" {
+		return defaultUpdateWorkers
+	}
+
+	workers, err := strconv.Atoi(value)
+	if err != nil || workers < 1 || workers > maxUpdateWorkers {
+		log.Printf("Invalid UPDATE_WORKERS %q, using %d", value, defaultUpdateWorkers)
+		return defaultUpdateWorkers
+	}
+	return workers
+}
+
+// Dispatch ставит обновление в очередь его чата. Возвращает false, если
+// обновление отброшено: очередь чата переполнена или диспетчер остановлен.
+func (d *updateDispatcher) Dispatch(update tgbotapi.Update) bool {
+	d.pending <- struct{}{}
+
+	chatID := updateChatID(update)
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		<-d.pending
+		return false
+	}
+
+	backlog, running := d.chats[chatID]
+	if len(backlog) >= chatBacklogLimit {
+		d.mu.Unlock()
+		<-d.pending
+		log.Printf("Dropping update %d: too many pending updates in chat %d", update.UpdateID, chatID)
+		return false
+	}
+
+	d.chats[chatID] = append(backlog, update)
+	if !running {
+		d.wg.Add(1)
+		go d.run(chatID)
+	}
+	d.mu.Unlock()
+	return true
+}
+
+// Wait ждет, пока будут обработаны все принятые обновления
+func (d *updateDispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// Shutdown перестает принимать обновления и ждет обработки уже принятых,
+// но не дольше, чем позволяет ctx
+func (d *updateDispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	d.closed = true
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run обрабатывает очередь чата, пока она не опустеет
+func (d *updateDispatcher) run(chatID int64) {
+	defer d.wg.Done()
+
+	for {
+		d.mu.Lock()
+		backlog := d.chats[chatID]
+		if len(backlog) == 0 {
+			delete(d.chats, chatID)
+			d.mu.Unlock()
+			return
+		}
+		update := backlog[0]
+		d.chats[chatID] = backlog[1:]
+		d.mu.Unlock()
+
+		// Обработчик занимается только на время одного обновления, чтобы длинная
+		// очередь одного чата не мешала остальным
+		d.workers <- struct{}{}
+		d.process(update)
+		<-d.workers
+		<-d.pending
+	}
+}
+
+// process обрабатывает обновление. Обычно панику перехватывает recoveryMiddleware,
+// здесь она перехватывается, даже если middleware отключен.
+func (d *updateDispatcher) process(update tgbotapi.Update) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Panic while handling update %d: %v\n%s", update.UpdateID, r, debug.Stack())
+		}
+	}()
+
+	d.handle(update)
+}
+
+// updateChatID возвращает чат, к которому относится обновление. Обновления без
+// чата попадают в общую очередь 0.
+func updateChatID(update tgbotapi.Update) int64 {
+	if chat := update.FromChat(); chat != nil {
+		return chat.ID
+	}
+	if user := update.SentFrom(); user != nil {
+		return user.ID
+	}
+	return 0
+}
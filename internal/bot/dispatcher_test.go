@@ -0,0 +1,237 @@
+package bot
+
+import (
+	"GreenAssistantBot/internal/storage"
+	"GreenAssistantBot/pkg/models"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func chatUpdate(updateID int, chatID int64) tgbotapi.Update {
+	return tgbotapi.Update{UpdateID: updateID, Message: &tgbotapi.Message{
+		Chat: &tgbotapi.Chat{ID: chatID},
+		From: &tgbotapi.User{ID: chatID},
+	}}
+}
+
+// blockingHandler задерживает обработку обновлений чата blockedChat до release
+type blockingHandler struct {
+	blockedChat int64
+	started     chan struct{}
+	release     chan struct{}
+
+	mu      sync.Mutex
+	handled map[int64][]int
+}
+
+func newBlockingHandler(blockedChat int64) *blockingHandler {
+	return &blockingHandler{
+		blockedChat: blockedChat,
+		started:     make(chan struct{}, 100),
+		release:     make(chan struct{}),
+		handled:     make(map[int64][]int),
+	}
+}
+
+func (b *blockingHandler) handle(update tgbotapi.Update) {
+	chatID := update.Message.Chat.ID
+	if chatID == b.blockedChat {
+		b.started <- struct{}{}
+		<-b.release
+	}
+
+	b.mu.Lock()
+	b.handled[chatID] = append(b.handled[chatID], update.UpdateID)
+	b.mu.Unlock()
+}
+
+func (b *blockingHandler) updates(chatID int64) []int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]int(nil), b.handled[chatID]...)
+}
+
+func waitStarted(t *testing.T, started chan struct{}) {
+	t.Helper()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler did not start")
+	}
+}
+
+func TestDispatcherOrderAndParallelism(t *testing.T) {
+	handler := newBlockingHandler(1)
+	d := newUpdateDispatcher(4, handler.handle)
+
+	d.Dispatch(chatUpdate(1, 1))
+	waitStarted(t, handler.started)
+	for i := 2; i <= 5; i++ {
+		d.Dispatch(chatUpdate(i, 1))
+	}
+
+	// Пока первый чат занят, второй обрабатывается без ожидания
+	d.Dispatch(chatUpdate(10, 2))
+	d.Dispatch(chatUpdate(11, 2))
+	deadline := time.Now().Add(5 * time.Second)
+	for len(handler.updates(2)) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := handler.updates(2); len(got) != 2 || got[0] != 10 || got[1] != 11 {
+		t.Fatalf("chat 2 should be handled in order while chat 1 is busy, got %v", got)
+	}
+	if got := handler.updates(1); len(got) != 0 {
+		t.Fatalf("chat 1 must wait for its first update, got %v", got)
+	}
+
+	close(handler.release)
+	d.Wait()
+	if got := handler.updates(1); len(got) != 5 || got[0] != 1 || got[4] != 5 {
+		t.Errorf("chat 1 updates out of order: %v", got)
+	}
+	for i, id := range handler.updates(1) {
+		if id != i+1 {
+			t.Errorf("chat 1 updates out of order: %v", handler.updates(1))
+			break
+		}
+	}
+}
+
+func TestDispatcherChatBacklogLimit(t *testing.T) {
+	handler := newBlockingHandler(1)
+	d := newUpdateDispatcher(4, handler.handle)
+
+	d.Dispatch(chatUpdate(1, 1))
+	waitStarted(t, handler.started)
+	for i := 0; i < chatBacklogLimit; i++ {
+		if !d.Dispatch(chatUpdate(i+2, 1)) {
+			t.Fatalf("update %d rejected before the backlog is full", i+2)
+		}
+	}
+	if d.Dispatch(chatUpdate(100, 1)) {
+		t.Error("expected update to be dropped when the chat backlog is full")
+	}
+	if !d.Dispatch(chatUpdate(200, 2)) {
+		t.Error("other chats must not be affected by a full backlog")
+	}
+
+	close(handler.release)
+	d.Wait()
+	if got := len(handler.updates(1)); got != chatBacklogLimit+1 {
+		t.Errorf("expected %d handled updates, got %d", chatBacklogLimit+1, got)
+	}
+}
+
+func TestDispatcherRecoversFromPanic(t *testing.T) {
+	var handled []int
+	d := newUpdateDispatcher(1, func(update tgbotapi.Update) {
+		if update.UpdateID == 1 {
+			panic("boom")
+		}
+		handled = append(handled, update.UpdateID)
+	})
+
+	d.Dispatch(chatUpdate(1, 1))
+	d.Dispatch(chatUpdate(2, 1))
+	d.Wait()
+
+	if len(handled) != 1 || handled[0] != 2 {
+		t.Errorf("expected update after panic to be handled, got %v", handled)
+	}
+}
+
+func TestDispatcherShutdown(t *testing.T) {
+	handler := newBlockingHandler(1)
+	d := newUpdateDispatcher(2, handler.handle)
+
+	d.Dispatch(chatUpdate(1, 1))
+	d.Dispatch(chatUpdate(2, 1))
+	waitStarted(t, handler.started)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := d.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline while update is in progress, got %v", err)
+	}
+	if d.Dispatch(chatUpdate(3, 1)) {
+		t.Error("expected updates to be rejected after shutdown")
+	}
+
+	// Уже принятые обновления дорабатываются
+	close(handler.release)
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if got := handler.updates(1); len(got) != 2 {
+		t.Errorf("expected accepted updates to be drained, got %v", got)
+	}
+}
+
+// Обработчики разных чатов работают параллельно и делят одно хранилище;
+// тест имеет смысл запускать с -race
+func TestDispatcherSharedMemoryStorage(t *testing.T) {
+	store, err := storage.NewMemoryStorage()
+	if err != nil {
+		t.Fatalf("NewMemoryStorage: %v", err)
+	}
+
+	const chats = 8
+	for chatID := int64(1); chatID <= chats; chatID++ {
+		store.SetUserState(chatID, "state")
+		store.SetSession(chatID, models.Session{})
+		store.SetLastMessageID(chatID, 1)
+	}
+
+	// Первые обновления всех чатов начинают читать хранилище одновременно.
+	// Чтение тоже меняет его (время последнего доступа).
+	arrived := make(chan struct{}, chats)
+	start := make(chan struct{})
+	d := newUpdateDispatcher(chats, func(update tgbotapi.Update) {
+		chatID := update.Message.Chat.ID
+		if update.UpdateID <= chats {
+			arrived <- struct{}{}
+			<-start
+		}
+		for i := 0; i < 100; i++ {
+			store.GetUserState(chatID)
+			store.GetSession(chatID)
+			store.GetLastMessageID(chatID)
+			store.GetMessageHistory(chatID)
+		}
+	})
+
+	for i := 0; i < 3; i++ {
+		for chatID := int64(1); chatID <= chats; chatID++ {
+			d.Dispatch(chatUpdate(i*chats+int(chatID), chatID))
+		}
+	}
+	for i := 0; i < chats; i++ {
+		waitStarted(t, arrived)
+	}
+	close(start)
+	d.Wait()
+
+	for chatID := int64(1); chatID <= chats; chatID++ {
+		if state, ok := store.GetUserState(chatID); !ok || state != "state" {
+			t.Errorf("chat %d: unexpected state %q", chatID, state)
+		}
+	}
+}
+
+func TestUpdateChatID(t *testing.T) {
+	callback := tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		From:    &tgbotapi.User{ID: 5},
+		Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 7}},
+	}}
+	if got := updateChatID(callback); got != 7 {
+		t.Errorf("callback chat: got %d, want 7", got)
+	}
+	if got := updateChatID(tgbotapi.Update{}); got != 0 {
+		t.Errorf("empty update: got %d, want 0", got)
+	}
+}
@@ -0,0 +1,414 @@
+package bot
+
+import (
+	"GreenAssistantBot/internal/database"
+	"GreenAssistantBot/internal/database/databasetest"
+	"GreenAssistantBot/internal/storage"
+	"GreenAssistantBot/internal/telegram"
+	"GreenAssistantBot/internal/telegram/telegramtest"
+	"GreenAssistantBot/internal/weather"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const testChatID int64 = 1001
+
+// e2e прогоняет обновления через UpdateHandler, подключенный к фейковому Bot API,
+// базе SQLite и провайдеру погоды без сети
+type e2e struct {
+	t       *testing.T
+	server  *telegramtest.Server
+	api     *tgbotapi.BotAPI
+	handler *UpdateHandler
+
+	nextUpdateID int
+	nextMessage  int
+}
+
+func newE2E(t *testing.T) *e2e {
+	t.Helper()
+	t.Setenv("ADMIN_CHAT_ID", "")
+	t.Setenv("CALLBACK_SECRET", "test-secret")
+
+	databasetest.Setup(t)
+
+	server := telegramtest.NewServer()
+	t.Cleanup(server.Close)
+
+	api, err := server.NewBot()
+	if err != nil {
+		t.Fatalf("connect to fake Bot API: %v", err)
+	}
+
+	store, err := storage.NewMemoryStorage()
+	if err != nil {
+		t.Fatalf("create storage: %v", err)
+	}
+
+	weatherService := weather.NewWeatherServiceWithProvider(fakeWeather{})
+	return &e2e{
+		t:            t,
+		server:       server,
+		api:          api,
+		handler:      NewUpdateHandler(telegram.NewBotSender(api), store, weatherService),
+		nextUpdateID: 1,
+		nextMessage:  1000,
+	}
+}
+
+// handle передает обновление обработчику и возвращает запросы бота к API
+func (e *e2e) handle(update tgbotapi.Update) []telegramtest.Call {
+	e.t.Helper()
+	update.UpdateID = e.nextUpdateID
+	e.nextUpdateID++
+
+	updates := make(chan tgbotapi.Update, 1)
+	updates <- update
+	close(updates)
+	e.handler.HandleUpdates(updates)
+
+	return e.server.TakeCalls()
+}
+
+func (e *e2e) message(configure func(*tgbotapi.Message)) []telegramtest.Call {
+	e.nextMessage++
+	message := &tgbotapi.Message{
+		MessageID: e.nextMessage,
+		From:      &tgbotapi.User{ID: testChatID, FirstName: "Test", UserName: "tester"},
+		Chat:      &tgbotapi.Chat{ID: testChatID, Type: "private"},
+		Date:      int(time.Now().Unix()),
+	}
+	configure(message)
+	return e.handle(tgbotapi.Update{Message: message})
+}
+
+// send отправляет боту текстовое сообщение
+func (e *e2e) send(text string) []telegramtest.Call {
+	return e.message(func(m *tgbotapi.Message) { m.Text = text })
+}
+
+// press нажимает inline-кнопку из сообщения бота
+func (e *e2e) press(reply telegramtest.Call, button string) []telegramtest.Call {
+	e.t.Helper()
+	data, ok := reply.CallbackData(button)
+	if !ok {
+		e.t.Fatalf("no inline button %q in %s", button, reply)
+	}
+
+	e.nextMessage++
+	return e.handle(tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:   "cb" + data,
+		From: &tgbotapi.User{ID: testChatID},
+		Message: &tgbotapi.Message{
+			MessageID: e.nextMessage,
+			Chat:      &tgbotapi.Chat{ID: testChatID, Type: "private"},
+		},
+		Data: data,
+	}})
+}
+
+// expectReply проверяет, что среди запросов есть сообщение в тестовый чат с текстом text,
+// и возвращает его
+func (e *e2e) expectReply(calls []telegramtest.Call, method, text string) telegramtest.Call {
+	e.t.Helper()
+	for _, call := range calls {
+		if call.Method == method && call.ChatID() == testChatID && strings.Contains(call.Text(), text) {
+			return call
+		}
+	}
+	e.t.Fatalf("expected %s containing %q, got %v", method, text, calls)
+	return telegramtest.Call{}
+}
+
+// expectCallbackAnswer проверяет, что бот ответил на нажатие кнопки, и возвращает текст ответа
+func (e *e2e) expectCallbackAnswer(calls []telegramtest.Call) string {
+	e.t.Helper()
+	for _, call := range calls {
+		if call.Method == "answerCallbackQuery" {
+			return call.Params.Get("text")
+		}
+	}
+	e.t.Fatalf("expected answerCallbackQuery, got %v", calls)
+	return ""
+}
+
+func (e *e2e) expectButtons(call telegramtest.Call, buttons ...string) {
+	e.t.Helper()
+	have := strings.Join(call.Buttons(), "\n")
+	for _, button := range buttons {
+		if !strings.Contains(have, button) {
+			e.t.Errorf("expected button %q in %s, have %q", button, call, call.Buttons())
+		}
+	}
+}
+
+func TestProfileFlow(t *testing.T) {
+	e := newE2E(t)
+
+	calls := e.send("/start")
+	e.expectReply(calls, "sendMessage", "Добро пожаловать")
+	e.expectReply(calls, "sendMessage", "введите ваше имя")
+
+	calls = e.send("   ")
+	e.expectReply(calls, "sendMessage", "Имя не может быть пустым")
+
+	calls = e.send("Анна")
+	prompt := e.expectReply(calls, "sendMessage", "введите ваш город")
+	e.expectButtons(prompt, locationButton)
+
+	calls = e.send("Атлантида")
+	e.expectReply(calls, "sendMessage", "Город «Атлантида» не найден")
+
+	calls = e.send("Москва")
+	done := e.expectReply(calls, "sendMessage", "✅ Анкета заполнена")
+	for _, want := range []string{"Имя: Анна", "Город: Москва", "Europe/Moscow"} {
+		if !strings.Contains(done.Text(), want) {
+			t.Errorf("profile summary %q does not contain %q", done.Text(), want)
+		}
+	}
+
+	user, err := database.GetUserByTelegramID(testChatID)
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if user.FirstName != "Анна" || user.City != "Москва" || user.Country != "RU" {
+		t.Errorf("unexpected profile: name=%q city=%q country=%q", user.FirstName, user.City, user.Country)
+	}
+	if !user.HasCoords() || user.Timezone != "Europe/Moscow" {
+		t.Errorf("expected coordinates and timezone, got %.2f, %.2f, %q", user.Latitude, user.Longitude, user.Timezone)
+	}
+	if !user.WeatherNotifications {
+		t.Error("filling in the profile must not turn off daily weather")
+	}
+
+	// После заполнения анкеты повторный /start не спрашивает имя
+	calls = e.send("/start")
+	for _, call := range calls {
+		if strings.Contains(call.Text(), "введите ваше имя") {
+			t.Errorf("unexpected name prompt for registered user: %s", call)
+		}
+	}
+
+	calls = e.send("🌡️Погода")
+	e.expectReply(calls, "sendMessage", "Москва")
+
+	e.send("✏️ Ваше имя")
+	calls = e.send("Аня")
+	e.expectReply(calls, "sendMessage", "Аня")
+	if user, err = database.GetUserByTelegramID(testChatID); err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if user.FirstName != "Аня" || !user.WeatherNotifications {
+		t.Errorf("renaming must only change the name, got name=%q notifications=%v", user.FirstName, user.WeatherNotifications)
+	}
+}
+
+// Пользователь, написавший сначала не /start, все равно проходит анкету
+func TestProfileFlowAfterOtherMessage(t *testing.T) {
+	e := newE2E(t)
+
+	e.send("ℹ️ Информация")
+	if exists, err := database.UserExists(testChatID); err != nil || !exists {
+		t.Fatalf("user must be registered on the first message: exists=%v err=%v", exists, err)
+	}
+
+	calls := e.send("/start")
+	e.expectReply(calls, "sendMessage", "введите ваше имя")
+}
+
+func TestLongPolling(t *testing.T) {
+	e := newE2E(t)
+	e.server.PushUpdate(tgbotapi.Update{UpdateID: 1, Message: &tgbotapi.Message{
+		MessageID: 1,
+		From:      &tgbotapi.User{ID: testChatID, FirstName: "Test"},
+		Chat:      &tgbotapi.Chat{ID: testChatID, Type: "private"},
+		Text:      "/start",
+	}})
+
+	config := tgbotapi.NewUpdate(0)
+	config.Timeout = 1
+	done := make(chan struct{})
+	go func() {
+		e.handler.HandleUpdates(e.api.GetUpdatesChan(config))
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(e.server.Calls()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	e.api.StopReceivingUpdates()
+	<-done
+
+	calls := e.server.TakeCalls()
+	e.expectReply(calls, "sendMessage", "Добро пожаловать")
+	e.expectReply(calls, "sendMessage", "введите ваше имя")
+}
+
+func TestProfileCityFromLocation(t *testing.T) {
+	e := newE2E(t)
+
+	e.send("/start")
+	e.send("Анна")
+	calls := e.message(func(m *tgbotapi.Message) {
+		m.Location = &tgbotapi.Location{Latitude: 59.94, Longitude: 30.31}
+	})
+	e.expectReply(calls, "sendMessage", "Город: Санкт-Петербург")
+
+	user, err := database.GetUserByTelegramID(testChatID)
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if user.Latitude != 59.94 || user.Longitude != 30.31 {
+		t.Errorf("expected exact coordinates of the location, got %.2f, %.2f", user.Latitude, user.Longitude)
+	}
+}
+
+func TestNotesFlow(t *testing.T) {
+	e := newE2E(t)
+	e.send("/start")
+	e.send("Анна")
+	e.send("Москва")
+
+	calls := e.send("📒 Заметки")
+	menu := e.expectReply(calls, "sendMessage", "Управление заметками")
+	e.expectButtons(menu, "📝 Новая заметка", "📁 Мои заметки")
+
+	// Без категорий заметку создать нельзя
+	calls = e.send("📝 Новая заметка")
+	e.expectReply(calls, "sendMessage", "У вас нет категорий")
+
+	e.send("📂 Управление категориями")
+	calls = e.send("➕ Создать категорию")
+	e.expectReply(calls, "sendMessage", "Введите название для новой категории")
+	calls = e.send("Покупки")
+	e.expectReply(calls, "sendMessage", `Категория "Покупки" успешно создана`)
+
+	calls = e.send("📝 Новая заметка")
+	choice := e.expectReply(calls, "sendMessage", "Выберите категорию")
+	e.expectButtons(choice, "Покупки")
+	calls = e.send("Покупки")
+	e.expectReply(calls, "sendMessage", "Отправьте текст, фото")
+	calls = e.send("Купить молоко #магазин")
+	saved := e.expectReply(calls, "sendMessage", `Заметка сохранена в категорию "Покупки"`)
+	if !strings.Contains(saved.Text(), "#магазин") {
+		t.Errorf("expected hashtag in %q", saved.Text())
+	}
+
+	e.send("📝 Новая заметка")
+	e.send("Покупки")
+	calls = e.message(func(m *tgbotapi.Message) {
+		m.Photo = []tgbotapi.PhotoSize{{FileID: "small-photo"}, {FileID: "large-photo"}}
+		m.Caption = "Чек"
+	})
+	e.expectReply(calls, "sendMessage", "Заметка сохранена")
+
+	calls = e.send("📁 Мои заметки")
+	e.expectReply(calls, "sendMessage", "Выберите категорию")
+	calls = e.send("Покупки")
+	list := e.expectReply(calls, "sendMessage", `Заметок в категории "Покупки": 2`)
+
+	calls = e.press(list, "Купить молоко")
+	e.expectReply(calls, "sendMessage", "Купить молоко #магазин")
+	e.expectCallbackAnswer(calls)
+
+	calls = e.press(list, "Чек")
+	photo := e.expectReply(calls, "sendPhoto", "Подпись: Чек")
+	if photo.Params.Get("photo") != "large-photo" {
+		t.Errorf("expected the largest photo size, got %q", photo.Params.Get("photo"))
+	}
+
+	notes, err := database.GetUserNotes(testChatID, 0)
+	if err != nil {
+		t.Fatalf("get notes: %v", err)
+	}
+	if len(notes) != 2 {
+		t.Fatalf("expected 2 notes, got %d", len(notes))
+	}
+}
+
+func TestWeatherPreferenceButtons(t *testing.T) {
+	e := newE2E(t)
+
+	calls := e.send("🌡️ °F, mph")
+	reply := e.expectReply(calls, "sendMessage", "Единицы и язык погоды")
+	if !strings.Contains(reply.Text(), "°F, mph") {
+		t.Errorf("expected imperial units in %q", reply.Text())
+	}
+
+	user, err := database.GetUserByTelegramID(testChatID)
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if user.Units != weather.UnitsImperial {
+		t.Errorf("expected units %q, got %q", weather.UnitsImperial, user.Units)
+	}
+}
+
+func TestForgedCallbackIsRejected(t *testing.T) {
+	e := newE2E(t)
+
+	forged := telegramtest.Call{Params: map[string][]string{
+		"reply_markup": {`{"inline_keyboard":[[{"text":"Открыть","callback_data":"no:1|forged"}]]}`},
+	}}
+	calls := e.press(forged, "Открыть")
+
+	if answer := e.expectCallbackAnswer(calls); answer != "⚠️ Кнопка устарела" {
+		t.Errorf("expected stale button answer, got %q", answer)
+	}
+	if len(calls) != 1 {
+		t.Errorf("expected only the callback answer, got %v", calls)
+	}
+}
+
+// fakeWeather знает два города и всегда возвращает одну и ту же погоду
+type fakeWeather struct{}
+
+var fakePlaces = []weather.Place{
+	{Name: "Москва", Country: "RU", Lat: 55.75, Lon: 37.62, Zone: "Europe/Moscow"},
+	{Name: "Санкт-Петербург", Country: "RU", Lat: 59.94, Lon: 30.31, Zone: "Europe/Moscow"},
+}
+
+func (fakeWeather) Name() string { return "fake" }
+
+func (fakeWeather) Current(lat, lon float64, prefs weather.Preferences) (*weather.WeatherData, error) {
+	return &weather.WeatherData{
+		Name: "Москва", Lat: lat, Lon: lon, Temp: 12, FeelsLike: 10, Pressure: 1012, Humidity: 70,
+		WindSpeed: 3, Visibility: 10000, Condition: "Clouds", Description: "облачно", Zone: "Europe/Moscow",
+	}, nil
+}
+
+func (fakeWeather) Forecast(lat, lon float64, prefs weather.Preferences) (*weather.WeatherForecast, error) {
+	location, _ := time.LoadLocation("Europe/Moscow")
+	start := time.Now().In(location).Truncate(3 * time.Hour)
+
+	forecast := &weather.WeatherForecast{City: "Москва", Location: location}
+	for i := 0; i < 40; i++ {
+		forecast.Items = append(forecast.Items, weather.ForecastItem{
+			Time: start.Add(time.Duration(i) * 3 * time.Hour), Temp: 12, FeelsLike: 10,
+			Humidity: 70, WindSpeed: 3, Main: "Clouds", Description: "облачно",
+		})
+	}
+	return forecast, nil
+}
+
+func (fakeWeather) Geocode(query string) ([]weather.Place, error) {
+	for _, place := range fakePlaces {
+		if strings.EqualFold(place.Name, strings.TrimSpace(query)) {
+			return []weather.Place{place}, nil
+		}
+	}
+	return nil, weather.ErrPlaceNotFound
+}
+
+func (fakeWeather) ReverseGeocode(lat, lon float64) (weather.Place, error) {
+	for _, place := range fakePlaces {
+		if place.Lat == lat && place.Lon == lon {
+			return place, nil
+		}
+	}
+	return weather.Place{}, weather.ErrPlaceNotFound
+}
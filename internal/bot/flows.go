@@ -0,0 +1,621 @@
+package bot
+
+import (
+	"GreenAssistantBot/internal/database"
+	"GreenAssistantBot/internal/fsm"
+	"GreenAssistantBot/internal/weather"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// inputTimeout — сколько ждать ввода текста пользователем
+	inputTimeout = 30 * time.Minute
+	// confirmationTimeout — сколько ждать подтверждения удаления
+	confirmationTimeout = 10 * time.Minute
+)
+
+// cancelWords отменяют любой сценарий
+var cancelWords = []string{"/cancel", "❌ Отмена", btnBack, btnBackToNotes, btnHome}
+
+var (
+	confirmYes = []string{"да", "✅ Да"}
+	confirmNo  = []string{"нет", "❌ Нет"}
+)
+
+// newStateMachine описывает все сценарии диалогов бота
+func (h *UpdateHandler) newStateMachine() (*fsm.Machine, error) {
+	machine, err := fsm.NewMachine(h.storage,
+		h.profileFlow(),
+		h.weatherFlow(),
+		h.notificationSettingsFlow(),
+		h.locationsFlow(),
+		h.noteCreationFlow(),
+		h.notesViewFlow(),
+		h.forwardedMessageFlow(),
+		h.categoryCreationFlow(),
+		h.categoryEditFlow(),
+		h.categoryDeleteFlow(),
+		h.noteEditFlow(),
+		h.searchFlow(),
+		h.tagsFlow(),
+		h.reminderFlow(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	machine.SetCancelWords(cancelWords...)
+	return machine, nil
+}
+
+func (h *UpdateHandler) profileFlow() *fsm.Flow {
+	saveName := func(in fsm.Input) bool {
+		if err := database.SetUserName(in.ChatID, in.Text); err != nil {
+			log.Printf("Error saving user: %v", err)
+			h.msgHandler.sendMessage(in.ChatID, "Произошла ошибка. Попробуйте позже.", CreateMainMenuKeyboard())
+			return false
+		}
+		return true
+	}
+
+	return &fsm.Flow{
+		Name: "profile",
+		States: []fsm.StateDef{
+			{
+				Name:     StateWaitingForName,
+				Validate: textInput("❌ Имя не может быть пустым"),
+				Next:     []fsm.State{StateWaitingForCity},
+				Action: func(in fsm.Input) fsm.State {
+					if !saveName(in) {
+						return StateWaitingForName
+					}
+					h.msgHandler.promptCity(in.ChatID)
+					return StateWaitingForCity
+				},
+			},
+			{
+				Name:     StateWaitingForCity,
+				Validate: cityInput("❌ Название города не может быть пустым"),
+				Action: func(in fsm.Input) fsm.State {
+					place, ok := h.placeFromInput(in)
+					if !ok || !h.msgHandler.saveCity(in.ChatID, place) {
+						return StateWaitingForCity
+					}
+					h.msgHandler.CompleteProfile(in.ChatID)
+					return fsm.None
+				},
+			},
+			{
+				Name:     StateChangingNameFromProfile,
+				Validate: textInput("❌ Имя не может быть пустым"),
+				Timeout:  inputTimeout,
+				Action: func(in fsm.Input) fsm.State {
+					if !saveName(in) {
+						return StateChangingNameFromProfile
+					}
+					h.msgHandler.SendProfileSettings(in.ChatID)
+					return fsm.None
+				},
+			},
+			{
+				Name:     StateChangingCityFromProfile,
+				Validate: cityInput("❌ Название города не может быть пустым"),
+				Timeout:  inputTimeout,
+				Action: func(in fsm.Input) fsm.State {
+					place, ok := h.placeFromInput(in)
+					if !ok || !h.msgHandler.saveCity(in.ChatID, place) {
+						return StateChangingCityFromProfile
+					}
+					h.msgHandler.SendProfileSettings(in.ChatID)
+					return fsm.None
+				},
+			},
+		},
+		OnInvalid: h.replyInvalid(CreateMainMenuKeyboard),
+		OnCancel:  h.replyCancelled(CreateMainMenuKeyboard),
+		OnTimeout: h.replyTimeout(CreateMainMenuKeyboard),
+	}
+}
+
+func (h *UpdateHandler) weatherFlow() *fsm.Flow {
+	return &fsm.Flow{
+		Name: "weather",
+		States: []fsm.StateDef{
+			{
+				Name:     StateWaitingForWeatherCity,
+				Validate: cityInput("❌ Введите название города"),
+				Timeout:  inputTimeout,
+				Action: func(in fsm.Input) fsm.State {
+					if location := messageOf(in).Location; location != nil {
+						h.msgHandler.SendWeatherAt(in.ChatID, location.Latitude, location.Longitude)
+					} else if !h.msgHandler.SendPickedWeather(in.ChatID, in.Text) {
+						h.msgHandler.SendWeather(in.ChatID, in.Text)
+					}
+					return fsm.None
+				},
+			},
+		},
+		OnInvalid: h.replyInvalid(CreateMainMenuKeyboard),
+		OnCancel:  h.replyCancelled(CreateMainMenuKeyboard),
+		OnTimeout: h.replyTimeout(CreateMainMenuKeyboard),
+	}
+}
+
+func (h *UpdateHandler) locationsFlow() *fsm.Flow {
+	return &fsm.Flow{
+		Name: "locations",
+		States: []fsm.StateDef{
+			{
+				Name:     StateWaitingForLocationLabel,
+				Validate: locationLabelInput(),
+				Timeout:  inputTimeout,
+				Next:     []fsm.State{StateWaitingForLocationCity},
+				Action: func(in fsm.Input) fsm.State {
+					return h.msgHandler.HandleLocationLabel(in.ChatID, in.Text)
+				},
+			},
+			{
+				Name:     StateWaitingForLocationCity,
+				Validate: cityInput("❌ Название города не может быть пустым"),
+				Timeout:  inputTimeout,
+				Action: func(in fsm.Input) fsm.State {
+					place, ok := h.placeFromInput(in)
+					if !ok {
+						return StateWaitingForLocationCity
+					}
+					h.msgHandler.saveLocation(in.ChatID, place)
+					return fsm.None
+				},
+			},
+		},
+		OnInvalid: h.replyInvalid(CreateBackKeyboard),
+		OnCancel:  h.replyCancelled(CreateSettingsMenuKeyboard),
+		OnTimeout: h.replyTimeout(CreateSettingsMenuKeyboard),
+	}
+}
+
+func (h *UpdateHandler) notificationSettingsFlow() *fsm.Flow {
+	return &fsm.Flow{
+		Name: "notification_settings",
+		States: []fsm.StateDef{
+			{
+				Name:     StateWaitingForTimezone,
+				Validate: timezoneInput(),
+				Timeout:  inputTimeout,
+				Action: func(in fsm.Input) fsm.State {
+					return h.msgHandler.HandleTimezone(in.ChatID, strings.TrimSpace(in.Text))
+				},
+			},
+			{
+				Name:     StateWaitingForNotificationTime,
+				Validate: clockInput(),
+				Timeout:  inputTimeout,
+				Action: func(in fsm.Input) fsm.State {
+					return h.msgHandler.HandleNotificationTime(in.ChatID, in.Text)
+				},
+			},
+			{
+				Name:     StateWaitingForFrostThreshold,
+				Validate: frostThresholdInput(),
+				Timeout:  inputTimeout,
+				Action: func(in fsm.Input) fsm.State {
+					return h.msgHandler.HandleFrostThreshold(in.ChatID, in.Text)
+				},
+			},
+			{
+				Name:     StateWaitingForQuietHours,
+				Validate: quietHoursInput(),
+				Timeout:  inputTimeout,
+				Action: func(in fsm.Input) fsm.State {
+					return h.msgHandler.HandleQuietHours(in.ChatID, in.Text)
+				},
+			},
+		},
+		OnInvalid: h.replyInvalid(CreateBackKeyboard),
+		OnCancel:  h.replyCancelled(CreateSettingsMenuKeyboard),
+		OnTimeout: h.replyTimeout(CreateSettingsMenuKeyboard),
+	}
+}
+
+func (h *UpdateHandler) noteCreationFlow() *fsm.Flow {
+	return &fsm.Flow{
+		Name: "note_creation",
+		States: []fsm.StateDef{
+			{
+				Name:     StateWaitingForNoteCategory,
+				Validate: fsm.NotEmpty("❌ Выберите категорию"),
+				Timeout:  inputTimeout,
+				Next:     []fsm.State{StateWaitingForNoteContent},
+				Action: func(in fsm.Input) fsm.State {
+					category, err := database.GetCategoryByName(in.ChatID, in.Text)
+					if err != nil {
+						log.Printf("Error finding category: %v", err)
+						h.msgHandler.sendMessage(in.ChatID, "❌ Категория не найдена", CreateNotesMenuKeyboard())
+						return fsm.None
+					}
+
+					// Запоминаем выбранную категорию
+					session, _ := h.storage.GetSession(in.ChatID)
+					session.CategoryID = category.ID
+					h.storage.SetSession(in.ChatID, session)
+					h.msgHandler.sendMessage(in.ChatID,
+						"📝 Отправьте текст, фото, видео, голосовое сообщение или файл для сохранения в заметку:",
+						CreateBackKeyboard())
+					log.Printf("Waiting for note content for category: %s", in.Text)
+					return StateWaitingForNoteContent
+				},
+			},
+			{
+				Name:    StateWaitingForNoteContent,
+				Timeout: inputTimeout,
+				Action: func(in fsm.Input) fsm.State {
+					return h.notesHandler.HandleNoteContent(in.ChatID, updateOf(in))
+				},
+			},
+		},
+		OnInvalid: h.replyInvalid(CreateNotesMenuKeyboard),
+		OnCancel:  h.replyCancelled(CreateNotesMenuKeyboard),
+		OnTimeout: h.replyTimeout(CreateNotesMenuKeyboard),
+	}
+}
+
+func (h *UpdateHandler) notesViewFlow() *fsm.Flow {
+	return &fsm.Flow{
+		Name: "notes_view",
+		States: []fsm.StateDef{
+			{
+				Name:     StateSelectingCategoryToView,
+				Validate: fsm.NotEmpty("❌ Выберите категорию"),
+				Timeout:  inputTimeout,
+				Action: func(in fsm.Input) fsm.State {
+					return h.notesHandler.SendNotesByCategory(in.ChatID, in.Text)
+				},
+			},
+		},
+		OnInvalid: h.replyInvalid(CreateNotesMenuKeyboard),
+		OnCancel:  h.replyCancelled(CreateNotesMenuKeyboard),
+		OnTimeout: h.replyTimeout(CreateNotesMenuKeyboard),
+	}
+}
+
+func (h *UpdateHandler) forwardedMessageFlow() *fsm.Flow {
+	return &fsm.Flow{
+		Name: "forwarded_message",
+		States: []fsm.StateDef{
+			{
+				Name:     SaveForwardedMessage,
+				Validate: fsm.NotEmpty("❌ Выберите категорию"),
+				Timeout:  inputTimeout,
+				Action: func(in fsm.Input) fsm.State {
+					session, _ := h.storage.GetSession(in.ChatID)
+					return h.notesHandler.SaveForwardedMessage(in.ChatID, in.Text, session)
+				},
+			},
+		},
+		OnInvalid: h.replyInvalid(CreateMainMenuKeyboard),
+		OnCancel:  h.replyCancelled(CreateMainMenuKeyboard),
+		OnTimeout: h.replyTimeout(CreateMainMenuKeyboard),
+	}
+}
+
+func (h *UpdateHandler) categoryCreationFlow() *fsm.Flow {
+	return &fsm.Flow{
+		Name: "category_creation",
+		States: []fsm.StateDef{
+			{
+				Name:     StateWaitingForCategoryName,
+				Validate: textInput("❌ Название категории не может быть пустым"),
+				Timeout:  inputTimeout,
+				Action: func(in fsm.Input) fsm.State {
+					return h.notesHandler.HandleCategoryCreation(in.ChatID, strings.TrimSpace(in.Text))
+				},
+			},
+		},
+		OnInvalid: h.replyInvalid(CreateBackKeyboard),
+		OnCancel:  h.replyCancelled(CreateCategoriesManagementKeyboard),
+		OnTimeout: h.replyTimeout(CreateCategoriesManagementKeyboard),
+	}
+}
+
+func (h *UpdateHandler) categoryEditFlow() *fsm.Flow {
+	return &fsm.Flow{
+		Name: "category_edit",
+		States: []fsm.StateDef{
+			{
+				Name:     StateSelectingCategoryToEdit,
+				Validate: fsm.NotEmpty("❌ Выберите категорию"),
+				Timeout:  inputTimeout,
+				Next:     []fsm.State{StateEditingCategory},
+				Action: func(in fsm.Input) fsm.State {
+					return h.notesHandler.HandleEditCategory(in.ChatID, in.Text)
+				},
+			},
+			{
+				Name:     StateEditingCategory,
+				Validate: textInput("❌ Название категории не может быть пустым"),
+				Timeout:  inputTimeout,
+				Action: func(in fsm.Input) fsm.State {
+					return h.notesHandler.HandleCategoryUpdate(in.ChatID, strings.TrimSpace(in.Text))
+				},
+			},
+		},
+		OnInvalid: h.replyInvalid(CreateBackKeyboard),
+		OnCancel:  h.replyCancelled(CreateCategoriesManagementKeyboard),
+		OnTimeout: h.replyTimeout(CreateCategoriesManagementKeyboard),
+	}
+}
+
+func (h *UpdateHandler) categoryDeleteFlow() *fsm.Flow {
+	return &fsm.Flow{
+		Name: "category_delete",
+		States: []fsm.StateDef{
+			{
+				Name:     StateSelectingCategoryToDelete,
+				Validate: fsm.NotEmpty("❌ Выберите категорию"),
+				Timeout:  inputTimeout,
+				Next:     []fsm.State{StateDeletingCategory},
+				Action: func(in fsm.Input) fsm.State {
+					return h.notesHandler.HandleDeleteCategory(in.ChatID, in.Text)
+				},
+				OnInvalid: h.replyInvalid(CreateNotesMenuKeyboard),
+			},
+			{
+				Name:     StateDeletingCategory,
+				Validate: confirmationInput(),
+				Timeout:  confirmationTimeout,
+				Action: func(in fsm.Input) fsm.State {
+					return h.notesHandler.ConfirmDeleteCategory(in.ChatID, isConfirmed(in.Text))
+				},
+			},
+		},
+		OnInvalid: h.replyInvalid(CreateConfirmationKeyboard),
+		OnCancel:  h.replyText("❌ Удаление отменено", CreateCategoriesManagementKeyboard),
+		OnTimeout: h.replyTimeout(CreateCategoriesManagementKeyboard),
+	}
+}
+
+func (h *UpdateHandler) noteEditFlow() *fsm.Flow {
+	return &fsm.Flow{
+		Name: "note_edit",
+		States: []fsm.StateDef{
+			{
+				Name:     StateEditingNote,
+				Validate: textInput("❌ Текст заметки не может быть пустым"),
+				Timeout:  inputTimeout,
+				Action: func(in fsm.Input) fsm.State {
+					return h.notesHandler.HandleNoteContentUpdate(in.ChatID, in.Text)
+				},
+			},
+		},
+		OnInvalid: h.replyInvalid(CreateBackKeyboard),
+		OnCancel:  h.replyCancelled(CreateNotesManagementKeyboard),
+		OnTimeout: h.replyTimeout(CreateNotesManagementKeyboard),
+	}
+}
+
+func (h *UpdateHandler) searchFlow() *fsm.Flow {
+	return &fsm.Flow{
+		Name: "search",
+		States: []fsm.StateDef{
+			{
+				Name:     StateWaitingForSearchQuery,
+				Validate: textInput("❌ Введите текст для поиска"),
+				Timeout:  inputTimeout,
+				Action: func(in fsm.Input) fsm.State {
+					return h.notesHandler.HandleSearchQuery(in.ChatID, in.Text)
+				},
+			},
+		},
+		OnInvalid: h.replyInvalid(CreateBackKeyboard),
+		OnCancel:  h.replyCancelled(CreateNotesMenuKeyboard),
+		OnTimeout: h.replyTimeout(CreateNotesMenuKeyboard),
+	}
+}
+
+func (h *UpdateHandler) tagsFlow() *fsm.Flow {
+	return &fsm.Flow{
+		Name: "tags",
+		States: []fsm.StateDef{
+			{
+				Name:     StateWaitingForTagRename,
+				Validate: tagPairInput(),
+				Timeout:  inputTimeout,
+				Action: func(in fsm.Input) fsm.State {
+					oldName, newName, _ := parseTagPair(in.Text)
+					return h.notesHandler.HandleTagRename(in.ChatID, oldName, newName)
+				},
+			},
+			{
+				Name:     StateWaitingForTagMerge,
+				Validate: tagPairInput(),
+				Timeout:  inputTimeout,
+				Action: func(in fsm.Input) fsm.State {
+					source, target, _ := parseTagPair(in.Text)
+					return h.notesHandler.HandleTagMerge(in.ChatID, source, target)
+				},
+			},
+		},
+		OnInvalid: h.replyInvalid(CreateBackKeyboard),
+		OnCancel:  h.replyCancelled(CreateTagsMenuKeyboard),
+		OnTimeout: h.replyTimeout(CreateTagsMenuKeyboard),
+	}
+}
+
+func (h *UpdateHandler) reminderFlow() *fsm.Flow {
+	return &fsm.Flow{
+		Name: "reminder",
+		States: []fsm.StateDef{
+			{
+				Name:     StateWaitingForReminderTime,
+				Validate: reminderTimeInput(),
+				Timeout:  inputTimeout,
+				Action: func(in fsm.Input) fsm.State {
+					return h.notesHandler.HandleReminderTime(in.ChatID, in.Text)
+				},
+			},
+		},
+		OnInvalid: h.replyInvalid(CreateBackKeyboard),
+		OnCancel:  h.replyCancelled(CreateNotesMenuKeyboard),
+		OnTimeout: h.replyTimeout(CreateNotesMenuKeyboard),
+	}
+}
+
+// replyInvalid показывает пользователю текст ошибки проверки ввода
+func (h *UpdateHandler) replyInvalid(keyboard func() tgbotapi.ReplyKeyboardMarkup) func(fsm.Input, error) {
+	return func(in fsm.Input, err error) {
+		h.msgHandler.sendMessage(in.ChatID, err.Error(), keyboard())
+	}
+}
+
+func (h *UpdateHandler) replyCancelled(keyboard func() tgbotapi.ReplyKeyboardMarkup) func(int64) {
+	return h.replyText("❌ Действие отменено", keyboard)
+}
+
+func (h *UpdateHandler) replyTimeout(keyboard func() tgbotapi.ReplyKeyboardMarkup) func(int64) {
+	return h.replyText("⌛ Время ожидания истекло, действие отменено", keyboard)
+}
+
+func (h *UpdateHandler) replyText(text string, keyboard func() tgbotapi.ReplyKeyboardMarkup) func(int64) {
+	return func(chatID int64) {
+		h.msgHandler.sendMessage(chatID, text, keyboard())
+	}
+}
+
+// textInput принимает непустой текст, который не совпадает с кнопкой меню
+func textInput(emptyMessage string) fsm.Validator {
+	return fsm.All(
+		fsm.NotEmpty(emptyMessage),
+		func(in fsm.Input) error {
+			if isCommand(strings.TrimSpace(in.Text)) {
+				return errors.New("❌ Введите текст, а не команду меню")
+			}
+			return nil
+		},
+	)
+}
+
+// cityInput принимает название города или отправленное местоположение
+func cityInput(emptyMessage string) fsm.Validator {
+	validateText := textInput(emptyMessage)
+	return func(in fsm.Input) error {
+		if messageOf(in).Location != nil {
+			return nil
+		}
+		return validateText(in)
+	}
+}
+
+// tagPairInput принимает ровно два имени тега
+func tagPairInput() fsm.Validator {
+	return func(in fsm.Input) error {
+		if _, _, ok := parseTagPair(in.Text); !ok {
+			return errors.New("❌ Введите два тега через пробел, например: #старый #новый")
+		}
+		return nil
+	}
+}
+
+// timezoneInput принимает часовой пояс или кнопку определения по городу
+func timezoneInput() fsm.Validator {
+	return func(in fsm.Input) error {
+		text := strings.TrimSpace(in.Text)
+		if _, ok := parseTimezone(text); !ok && text != timezoneFromCityButton {
+			return errors.New("❌ Неизвестный часовой пояс. Выберите его кнопкой или введите, например, Europe/Moscow или +3")
+		}
+		return nil
+	}
+}
+
+// clockInput принимает время суток в формате ЧЧ:ММ
+func clockInput() fsm.Validator {
+	return func(in fsm.Input) error {
+		if _, ok := parseClock(in.Text); !ok {
+			return errors.New("❌ Введите время в формате ЧЧ:ММ, например 08:30")
+		}
+		return nil
+	}
+}
+
+// locationLabelInput принимает короткое название места
+func locationLabelInput() fsm.Validator {
+	validateText := textInput("❌ Название места не может быть пустым")
+	return func(in fsm.Input) error {
+		if err := validateText(in); err != nil {
+			return err
+		}
+		if len([]rune(strings.TrimSpace(in.Text))) > maxLocationLabelLength {
+			return fmt.Errorf("❌ Название места должно быть не длиннее %d символов", maxLocationLabelLength)
+		}
+		return nil
+	}
+}
+
+// frostThresholdInput принимает порог мороза в градусах
+func frostThresholdInput() fsm.Validator {
+	return func(in fsm.Input) error {
+		if _, ok := parseFrostThreshold(in.Text); !ok {
+			return fmt.Errorf("❌ Введите температуру от %d до %d, например -15", minFrostThreshold, maxFrostThreshold)
+		}
+		return nil
+	}
+}
+
+// quietHoursInput принимает интервал тихих часов или кнопку их отключения
+func quietHoursInput() fsm.Validator {
+	return func(in fsm.Input) error {
+		if _, _, ok := parseQuietHours(in.Text); !ok {
+			return errors.New("❌ Введите интервал в формате ЧЧ:ММ-ЧЧ:ММ, например 23:00-07:00")
+		}
+		return nil
+	}
+}
+
+// reminderTimeInput принимает время напоминания в будущем
+func reminderTimeInput() fsm.Validator {
+	return func(in fsm.Input) error {
+		if _, ok := parseReminderTime(in.Text, userNow(in.ChatID)); !ok {
+			return errors.New("❌ Не удалось распознать время или оно уже прошло\n\n" + reminderTimeHelp)
+		}
+		return nil
+	}
+}
+
+func confirmationInput() fsm.Validator {
+	options := append(append([]string{}, confirmYes...), confirmNo...)
+	return fsm.OneOf("❌ Пожалуйста, используйте кнопки для подтверждения", options...)
+}
+
+func isConfirmed(text string) bool {
+	for _, yes := range confirmYes {
+		if strings.EqualFold(strings.TrimSpace(text), yes) {
+			return true
+		}
+	}
+	return false
+}
+
+func updateOf(in fsm.Input) tgbotapi.Update {
+	update, _ := in.Payload.(tgbotapi.Update)
+	return update
+}
+
+func messageOf(in fsm.Input) *tgbotapi.Message {
+	if message := updateOf(in).Message; message != nil {
+		return message
+	}
+	return &tgbotapi.Message{}
+}
+
+// placeFromInput определяет город по введенному названию или отправленному местоположению
+func (h *UpdateHandler) placeFromInput(in fsm.Input) (weather.Place, bool) {
+	if location := messageOf(in).Location; location != nil {
+		return h.msgHandler.resolveLocation(in.ChatID, location.Latitude, location.Longitude)
+	}
+	return h.msgHandler.resolveCity(in.ChatID, in.Text)
+}
@@ -1,406 +1,132 @@
 package bot
 
 import (
-	"GreenAssistantBot/internal/database"
-	"GreenAssistantBot/internal/database/models"
+	"GreenAssistantBot/internal/fsm"
 	"GreenAssistantBot/internal/storage"
+	"GreenAssistantBot/internal/telegram"
+	"GreenAssistantBot/internal/weather"
 	pmodel "GreenAssistantBot/pkg/models"
+	"context"
 	"fmt"
 	"log"
-	"os"
-	"strconv"
-	"strings"
+	"sync"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 type UpdateHandler struct {
-	bot          *tgbotapi.BotAPI
+	sender       telegram.Sender
 	storage      storage.BotStorage
 	msgHandler   *MessageHandler
 	notesHandler *NotesHandler
+	callbacks    *callbackRouter
+	flows        *fsm.Machine
+	dispatcher   *updateDispatcher
+	// pipeline — обработка обновления, обернутая в middleware
+	pipeline   updateFunc
+	metrics    updateMetrics
+	knownUsers sync.Map
 }
 
-func NewUpdateHandler(bot *tgbotapi.BotAPI, storage storage.BotStorage) *UpdateHandler {
-	msgHandler := NewMessageHandler(bot, storage)
-	return &UpdateHandler{
-		bot:          bot,
+func NewUpdateHandler(sender telegram.Sender, storage storage.BotStorage, weatherService *weather.WeatherService) *UpdateHandler {
+	msgHandler := NewMessageHandler(sender, storage, weatherService)
+	h := &UpdateHandler{
+		sender:       sender,
 		storage:      storage,
 		msgHandler:   msgHandler,
-		notesHandler: NewNotesHandler(bot, storage, msgHandler),
+		notesHandler: NewNotesHandler(sender, storage, msgHandler),
+		callbacks:    newCallbackRouter(sender, msgHandler.callbacks),
 	}
-}
-
-func (h *UpdateHandler) handleUserState(chatID int64, state, userText, userName, lastName string, update tgbotapi.Update) bool {
-	log.Printf("handleUserState: chatID=%d, state=%s, userText=%s", chatID, state, userText)
-
-	switch state {
-	case StateWaitingForName:
-		user := &models.User{TelegramID: chatID, FirstName: userText, UserName: userName, LastName: lastName}
-		if err := database.SaveOrUpdateUser(user); err != nil {
-			log.Printf("Error saving user: %v", err)
-			return false
-		}
-		h.msgHandler.AskForCity(chatID)
-		return true
-
-	case StateWaitingForCity:
-		user := &models.User{TelegramID: chatID, City: userText}
-		if err := database.SaveOrUpdateUser(user); err != nil {
-			log.Printf("Error saving user: %v", err)
-			return false
-		}
-		h.msgHandler.CompleteProfile(chatID)
-		return true
-
-	case StateChangingNameFromProfile:
-		user := &models.User{TelegramID: chatID, FirstName: userText}
-		if err := database.SaveOrUpdateUser(user); err != nil {
-			log.Printf("Error saving user: %v", err)
-			return false
-		}
-		h.msgHandler.SendProfileSettings(chatID)
-		h.storage.SetUserState(chatID, "")
-		return true
-
-	case StateChangingCityFromProfile:
-		user := &models.User{TelegramID: chatID, City: userText}
-		if err := database.SaveOrUpdateUser(user); err != nil {
-			log.Printf("Error saving user: %v", err)
-			return false
-		}
-		h.msgHandler.SendProfileSettings(chatID)
-		h.storage.SetUserState(chatID, "")
-		return true
-
-	case StateWaitingForWeatherCity:
-		h.msgHandler.SendWeather(chatID, userText)
-		h.storage.SetUserState(chatID, "")
-		return true
-
-	case StateWaitingForCategoryName:
-		h.notesHandler.HandleCategoryCreation(chatID, userText)
-		return true
-
-	case StateWaitingForNoteCategory:
-		// Обработка выбора категории для разных целей
-		userData, exists := h.storage.GetUserData(chatID)
-		if !exists {
-			log.Printf("No user data found for chat %d in state %s", chatID, state)
-			h.msgHandler.sendMessage(chatID, "❌ Сессия истекла, начните заново", CreateNotesMenuKeyboard())
-			h.storage.SetUserState(chatID, "")
-			return true
-		}
-
-		purpose := userData.Data
-		log.Printf("Category selected: %s for purpose: %s", userText, purpose)
-
-		switch purpose {
-		case "new_note":
-			// Сохраняем выбранную категорию в отдельном поле
-			userData.Category = userText
-			h.storage.SetUserData(chatID, userData)
-			h.msgHandler.sendMessage(chatID,
-				"📝 Отправьте текст, фото, видео, голосовое сообщение или файл для сохранения в заметку:",
-				CreateBackKeyboard())
-			h.storage.SetUserState(chatID, StateWaitingForNoteContent)
-			log.Printf("Waiting for note content for category: %s", userText)
-
-		case "view_notes":
-			// Показываем заметки выбранной категории
-			h.notesHandler.SendNotesByCategory(chatID, userText)
-			h.storage.SetUserState(chatID, "")
-		case "delete_category":
-			h.notesHandler.HandleDeleteCategory(chatID, userText)
-		case "edit_category":
-			// Обработка редактирования категории
-			h.notesHandler.HandleEditCategory(chatID, userText)
-		case "save_forwarded_message":
-			// Сохраняем пересланное сообщение в выбранной категории
-			h.notesHandler.SaveForwardedMessage(chatID, userText, userData)
-		default:
-			log.Printf("Unknown purpose: %s", purpose)
-			h.msgHandler.sendMessage(chatID, "❌ Неизвестная операция", CreateNotesMenuKeyboard())
-			h.storage.SetUserState(chatID, "")
-		}
-		return true
-
-	case StateDeletingCategory:
-		if strings.ToLower(userText) == "да" || userText == "✅ Да" {
-			h.notesHandler.ConfirmDeleteCategory(chatID, true)
-		} else if strings.ToLower(userText) == "нет" || userText == "❌ Нет" {
-			h.notesHandler.ConfirmDeleteCategory(chatID, false)
-		} else if userText == "⬅️ Назад" {
-			h.msgHandler.sendMessage(chatID, "❌ Удаление отменено", CreateCategoriesManagementKeyboard())
-			h.storage.SetUserState(chatID, "")
-		} else {
-			h.msgHandler.sendMessage(chatID, "❌ Пожалуйста, используйте кнопки для подтверждения", CreateConfirmationKeyboard())
-		}
-		return true
-
-	case StateEditingCategory:
-		h.notesHandler.HandleCategoryUpdate(chatID, userText)
-		return true
-
-	case StateWaitingForNoteSelection:
-		userData, exists := h.storage.GetUserData(chatID)
-		if !exists {
-			log.Printf("No user data found for chat %d in state %s", chatID, state)
-			h.msgHandler.sendMessage(chatID, "❌ Сессия истекла, начните заново", CreateNotesMenuKeyboard())
-			h.storage.SetUserState(chatID, "")
-			return true
-		}
-
-		purpose := userData.Data
-		log.Printf("Note selected: %s for purpose: %s", userText, purpose)
-
-		// Парсим ID заметки из текста (формат: "ID: текст")
-		noteIDStr := strings.Split(userText, ":")[0]
-		noteID, err := strconv.ParseUint(strings.TrimSpace(noteIDStr), 10, 32)
-		if err != nil {
-			log.Printf("Error parsing note ID: %v", err)
-			h.msgHandler.sendMessage(chatID, "❌ Ошибка при выборе заметки", CreateNotesMenuKeyboard())
-			h.storage.SetUserState(chatID, "")
-			return true
-		}
-
-		switch purpose {
-		case "edit_note":
-			h.notesHandler.HandleEditNoteSelection(chatID, uint(noteID))
-		case "delete_note":
-			h.notesHandler.HandleDeleteNoteSelection(chatID, uint(noteID))
-		default:
-			log.Printf("Unknown purpose: %s", purpose)
-			h.msgHandler.sendMessage(chatID, "❌ Неизвестная операция", CreateNotesMenuKeyboard())
-			h.storage.SetUserState(chatID, "")
-		}
-		return true
-
-	case StateEditingNote:
-		h.notesHandler.HandleNoteContentUpdate(chatID, userText)
-		return true
-
-	case StateDeletingNote:
-		if strings.ToLower(userText) == "да" || userText == "✅ Да" {
-			h.notesHandler.ConfirmDeleteNote(chatID, true)
-		} else if strings.ToLower(userText) == "нет" || userText == "❌ Нет" {
-			h.notesHandler.ConfirmDeleteNote(chatID, false)
-		} else if userText == "⬅️ Назад" {
-			h.msgHandler.sendMessage(chatID, "❌ Удаление отменено", CreateNotesManagementKeyboard())
-			h.storage.SetUserState(chatID, "")
-		} else {
-			h.msgHandler.sendMessage(chatID, "❌ Пожалуйста, используйте кнопки для подтверждения", CreateConfirmationKeyboard())
-		}
-		return true
-
-	case SaveForwardedMessage:
-		// Сохраняем пересланное сообщение в выбранной категории
-		userData, _ := h.storage.GetUserData(chatID)
-		h.notesHandler.SaveForwardedMessage(chatID, userText, userData)
+	h.notesHandler.RegisterCallbacks(h.callbacks)
+	h.notesHandler.RegisterTrashCallbacks(h.callbacks)
+	h.notesHandler.RegisterHistoryCallbacks(h.callbacks)
+	h.notesHandler.RegisterReminderCallbacks(h.callbacks)
+	h.msgHandler.RegisterWeatherCallbacks(h.callbacks)
+	h.msgHandler.RegisterLocationCallbacks(h.callbacks)
+
+	flows, err := h.newStateMachine()
+	if err != nil {
+		log.Fatalf("Invalid conversation flows: %v", err)
 	}
-	return false
+	h.flows = flows
+	h.pipeline = chainMiddlewares(h.handleUpdate, h.middlewares(middlewareOrderFromEnv())...)
+	h.dispatcher = newUpdateDispatcher(updateWorkersFromEnv(), h.process)
+
+	return h
 }
 
+// HandleUpdates раздает обновления обработчикам: обновления одного чата
+// обрабатываются по порядку, разных чатов — параллельно. Когда канал закрывается,
+// метод ждет обработки уже полученных обновлений.
 func (h *UpdateHandler) HandleUpdates(updates tgbotapi.UpdatesChannel) {
 	for update := range updates {
-		if update.Message == nil || update.Message.From.IsBot {
-			continue
-		}
-
-		chatID := update.Message.Chat.ID
-		userText := update.Message.Text
-
-		log.Printf("[%d]: %s", chatID, userText)
-
-		adminChatID := os.Getenv("ADMIN_CHAT_ID")
-		if adminChatID != "" && adminChatID != fmt.Sprintf("%d", chatID) {
-			log.Printf("Chat id: %d", chatID)
-			continue
-		}
-
-		// Проверяем, является ли сообщение пересланным
-		if update.Message.ForwardFrom != nil || update.Message.ForwardFromChat != nil || update.Message.ForwardSenderName != "" {
-			log.Printf("Forwarded message: From=%v, FromChat=%v, SenderName=%s",
-				update.Message.ForwardFrom,
-				update.Message.ForwardFromChat,
-				update.Message.ForwardSenderName)
-		}
-
-		// Обработка состояний
-		if state, exists := h.storage.GetUserState(chatID); exists {
-			if h.handleUserState(chatID, state, userText, update.Message.From.UserName, update.Message.From.LastName, update) {
-				continue
-			}
-		}
-
-		// Проверяем, не находится ли пользователь в режиме добавления заметки
-		if state, exists := h.storage.GetUserState(chatID); exists && state == StateWaitingForNoteContent {
-			h.notesHandler.HandleNoteContent(chatID, update)
-			continue
-		}
-
-		// Обработка команд
-		switch userText {
-		case "/start":
-			h.msgHandler.SendStartMessage(chatID)
-			if exists, _ := database.UserExists(chatID); !exists {
-				user := &models.User{
-					TelegramID: chatID,
-					UserName:   update.Message.From.UserName,
-					FirstName:  update.Message.From.FirstName,
-					LastName:   update.Message.From.LastName,
-				}
-				database.SaveOrUpdateUser(user)
-				h.msgHandler.AskForName(chatID)
-			}
-
-		case "✏️ Ваше имя":
-			h.msgHandler.AskForName(chatID)
-			h.storage.SetUserState(chatID, StateChangingNameFromProfile)
-
-		case "🚩 Ваш город":
-			h.msgHandler.AskForCity(chatID)
-			h.storage.SetUserState(chatID, StateChangingCityFromProfile)
-
-		case "ℹ️ Информация":
-			h.msgHandler.SendInfo(chatID)
-
-		case "⚙️ Настройки":
-			h.msgHandler.SendSettingsMenu(chatID)
-
-		case "📞 Поддержка":
-			h.msgHandler.SendSupport(chatID)
-
-		case "🔔 Уведомления":
-			h.msgHandler.SendNotificationsSettings(chatID)
-
-		case "👤 Профиль":
-			h.msgHandler.SendProfileSettings(chatID)
-
-		case "🌡️Погода":
-			if user, err := database.GetUserByTelegramID(chatID); err == nil && user.City != "" {
-				h.msgHandler.SendWeather(chatID, user.City)
-			} else {
-				h.msgHandler.sendMessage(chatID, "🌍 Введите название города:", CreateMainMenuKeyboard())
-				h.storage.SetUserState(chatID, StateWaitingForWeatherCity)
-			}
-
-		case "🌡️ Уведомления о погоде":
-			// Получаем текущее состояние уведомлений пользователя
-			user, err := database.GetUserByTelegramID(chatID)
-			if err != nil {
-				log.Printf("Error getting user: %v", err)
-				h.msgHandler.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.", CreateSettingsMenuKeyboard())
-				continue
-			}
-
-			// Изменяем состояние уведомлений
-			user.WeatherNotifications = !user.WeatherNotifications
-			err = database.SaveOrUpdateUser(user)
-			if err != nil {
-				log.Printf("Error updating user: %v", err)
-				h.msgHandler.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.", CreateSettingsMenuKeyboard())
-				continue
-			}
-
-			// Отправляем подтверждение
-			status := "включены"
-			if !user.WeatherNotifications {
-				status = "выключены"
-			}
-			h.msgHandler.sendMessage(chatID, fmt.Sprintf("Уведомления о погоде %s", status), CreateSettingsMenuKeyboard())
-
-		case "📒 Заметки":
-			h.notesHandler.SendNotesMenu(chatID)
-
-		case "📝 Новая заметка":
-			h.notesHandler.SendCategoriesForSelection(chatID, "new_note")
-
-		case "📁 Мои заметки":
-			// Предлагаем выбрать категорию или показать все заметки
-			categories, err := database.GetUserCategories(chatID)
-			if err != nil || len(categories) == 0 {
-				// Если категорий нет, показываем все заметки
-				h.notesHandler.SendUserNotes(chatID, 0)
-			} else {
-				// Если есть категории, предлагаем выбрать
-				h.notesHandler.SendCategoriesForViewing(chatID)
-			}
-
-		case "📂 Управление категориями":
-			h.notesHandler.SendCategoriesMenu(chatID)
-
-		case "➕ Создать категорию":
-			h.notesHandler.AskForCategoryName(chatID)
-
-		case "🗑️ Удалить категорию":
-			h.notesHandler.SendCategoriesForSelection(chatID, "delete_category")
-
-		case "✏️ Редактировать категории":
-			h.notesHandler.SendEditCategoriesMenu(chatID)
-
-		case "🛠️ Управление заметками":
-			h.notesHandler.SendNotesManagementMenu(chatID)
-
-		case "✏️ Редактировать заметку":
-			h.notesHandler.SendNotesForSelection(chatID, "edit_note")
+		h.dispatcher.Dispatch(update)
+	}
+	h.dispatcher.Wait()
+}
 
-		case "🗑️ Удалить заметку":
-			h.notesHandler.SendNotesForSelection(chatID, "delete_note")
+// Shutdown перестает принимать обновления и ждет обработки уже полученных
+func (h *UpdateHandler) Shutdown(ctx context.Context) error {
+	return h.dispatcher.Shutdown(ctx)
+}
 
-		case "⬅️ Назад к заметкам":
-			h.notesHandler.SendNotesMenu(chatID)
+// GetStats возвращает счетчики обработки обновлений для мониторинга
+func (h *UpdateHandler) GetStats() map[string]interface{} {
+	return h.metrics.GetStats()
+}
 
-		case "⬅️ Назад к списку":
-			h.notesHandler.SendUserNotes(chatID, 0)
+// process пропускает обновление через middleware и обработчик
+func (h *UpdateHandler) process(update tgbotapi.Update) {
+	h.pipeline(context.WithValue(context.Background(), updateMetaKey{}, &updateMeta{}), update)
+}
 
-		case "⬅️ Назад", "🏠 В начало":
-			h.msgHandler.SendMainMenu(chatID)
+// handleUpdate обрабатывает одно обновление. Логирование, проверка доступа и
+// регистрация пользователя уже выполнены в middleware.
+func (h *UpdateHandler) handleUpdate(ctx context.Context, update tgbotapi.Update) {
+	// Нажатия inline-кнопок
+	if update.CallbackQuery != nil {
+		h.callbacks.Handle(update.CallbackQuery)
+		return
+	}
 
-		case "📸 Медиа-заметки":
-			// Получаем ID категории из текущего состояния или используем 0 (все категории)
-			userData, exists := h.storage.GetUserData(chatID)
-			var categoryID uint = 0
-			if exists && userData.Category != "" {
-				// Если есть сохраненная категория, используем ее
-				if cat, err := database.GetCategoryByName(chatID, userData.Category); err == nil {
-					categoryID = cat.ID
-				}
-			}
-			h.notesHandler.SendMediaNotes(chatID, categoryID)
+	if update.Message == nil {
+		return
+	}
 
-		// В разделе обработки обычных сообщений добавьте:
-		case "✏️ Редактировать":
-			// Запрашиваем новое содержание
-			h.msgHandler.sendMessage(chatID, "📝 Введите новый текст для заметки:", CreateBackKeyboard())
-			h.storage.SetUserState(chatID, StateEditingNote)
+	chatID := update.Message.Chat.ID
+	userText := update.Message.Text
 
-		case "🗑️ Удалить":
-			// Уже обрабатывается в состояниях
+	// Обработка сценариев диалога
+	if h.flows.Handle(fsm.Input{ChatID: chatID, Text: userText, Payload: update}) {
+		return
+	}
 
-		default:
-			// Если это медиа-контент или текст (не команда), предлагаем сразу сохранить в заметки
-			if update.Message.Photo != nil || update.Message.Video != nil ||
-				update.Message.Voice != nil || update.Message.Document != nil ||
-				(update.Message.Text != "" && !isCommand(update.Message.Text)) {
+	// Местоположение вне сценариев — показываем погоду в этой точке
+	if location := update.Message.Location; location != nil {
+		h.msgHandler.SendWeatherAt(chatID, location.Latitude, location.Longitude)
+		return
+	}
 
-				log.Printf("Forwarded message detected: Text=%s, HasPhoto=%t, HasVideo=%t, ForwardFrom=%v",
-					update.Message.Text,
-					update.Message.Photo != nil,
-					update.Message.Video != nil,
-					update.Message.ForwardFrom)
+	// Кнопки меню и слеш-команды
+	if route, ok := routes.Match(userText); ok {
+		route.Handle(h, ctx, update.Message)
+		return
+	}
 
-				// Сохраняем само сообщение для последующего сохранения
-				h.saveMessageForForwarding(chatID, update.Message)
+	// Если это медиа-контент или текст (не команда), предлагаем сразу сохранить в заметки
+	if update.Message.Photo != nil || update.Message.Video != nil ||
+		update.Message.Voice != nil || update.Message.Document != nil ||
+		(update.Message.Text != "" && !isCommand(update.Message.Text)) {
 
-				// Предлагаем выбрать категорию для сохранения
-				h.notesHandler.SendCategoriesForSelection(chatID, "save_forwarded_message")
-				continue
-			}
+		// Сохраняем само сообщение для последующего сохранения
+		h.saveMessageForForwarding(chatID, update.Message)
 
-			h.msgHandler.sendMessage(chatID, "Используйте меню для навигации", CreateMainMenuKeyboard())
-		}
+		// Предлагаем выбрать категорию для сохранения
+		h.notesHandler.SendCategoriesForSelection(chatID, pmodel.PurposeSaveForwarded)
+		return
 	}
+
+	h.msgHandler.sendMessage(chatID, "Используйте меню для навигации", CreateMainMenuKeyboard())
 }
 
 func (h *MessageHandler) SendMessage(chatID int64, text string, keyboard tgbotapi.ReplyKeyboardMarkup) error {
@@ -416,7 +142,7 @@ func (h *UpdateHandler) saveMessageForForwarding(chatID int64, message *tgbotapi
 	// Определяем тип контента
 	var content string
 	var fileID string
-	var noteType string
+	var noteType pmodel.NoteType
 
 	// Добавляем информацию об источнике
 	var sourceInfo string
@@ -429,13 +155,13 @@ func (h *UpdateHandler) saveMessageForForwarding(chatID int64, message *tgbotapi
 	}
 
 	if message.Text != "" {
-		noteType = "text"
+		noteType = pmodel.NoteTypeText
 		content = message.Text
 		if sourceInfo != "" {
 			content = sourceInfo + "\n\n" + content
 		}
 	} else if message.Photo != nil && len(message.Photo) > 0 {
-		noteType = "photo"
+		noteType = pmodel.NoteTypePhoto
 		fileID = message.Photo[len(message.Photo)-1].FileID
 		content = message.Caption
 		if sourceInfo != "" {
@@ -446,7 +172,7 @@ func (h *UpdateHandler) saveMessageForForwarding(chatID int64, message *tgbotapi
 			}
 		}
 	} else if message.Video != nil {
-		noteType = "video"
+		noteType = pmodel.NoteTypeVideo
 		fileID = message.Video.FileID
 		content = message.Caption
 		if sourceInfo != "" {
@@ -457,11 +183,11 @@ func (h *UpdateHandler) saveMessageForForwarding(chatID int64, message *tgbotapi
 			}
 		}
 	} else if message.Voice != nil {
-		noteType = "voice"
+		noteType = pmodel.NoteTypeVoice
 		fileID = message.Voice.FileID
 		content = sourceInfo // для голосовых сохраняем только источник
 	} else if message.Document != nil {
-		noteType = "file"
+		noteType = pmodel.NoteTypeFile
 		fileID = message.Document.FileID
 		content = message.Caption
 		if sourceInfo != "" {
@@ -473,19 +199,21 @@ func (h *UpdateHandler) saveMessageForForwarding(chatID int64, message *tgbotapi
 		}
 	} else {
 		// Если тип не определен, используем текст как fallback
-		noteType = "text"
+		noteType = pmodel.NoteTypeText
 		content = "Пересланное сообщение"
 		if sourceInfo != "" {
 			content = sourceInfo + "\n\n" + content
 		}
 	}
 
-	// Сохраняем данные сообщения
-	h.storage.SetUserData(chatID, pmodel.UserData{
-		Data:     "save_forwarded_message",
-		Name:     noteType,
-		City:     content,
-		Category: fileID,
+	// Сохраняем черновик заметки до выбора категории
+	h.storage.SetSession(chatID, pmodel.Session{
+		Purpose: pmodel.PurposeSaveForwarded,
+		Draft: &pmodel.NoteDraft{
+			Type:    noteType,
+			Content: content,
+			FileID:  fileID,
+		},
 	})
 
 	log.Printf("Saved forwarded message data: Type=%s, Content=%s, FileID=%s, Source=%s",
@@ -505,23 +233,7 @@ func getUserName(user *tgbotapi.User) string {
 	return "Пользователь"
 }
 
-// isCommand проверяет, является ли текст командой
+// isCommand проверяет, является ли текст кнопкой меню или слеш-командой
 func isCommand(text string) bool {
-	commands := []string{
-		"/start", "ℹ️ Информация", "📞 Поддержка", "🌡️Погода", "📒 Заметки",
-		"⚙️ Настройки", "🔔 Уведомления", "👤 Профиль", "🌡️ Уведомления о погоде",
-		"📝 Новая заметка", "📁 Мои заметки", "📂 Управление категориями",
-		"➕ Создать категорию", "🗑️ Удалить категорию", "⬅️ Назад", "🏠 В начало",
-		"✏️ Ваше имя", "🚩 Ваш город", "🛠️ Управление заметками",
-		"✏️ Редактировать заметку", "🗑️ Удалить заметку", "⬅️ Назад к заметкам",
-		"⬅️ Назад к списку", "✏️ Редактировать", "🗑️ Удалить",
-		"✏️ Редактировать категории", "➕ Новая категория",
-	}
-
-	for _, cmd := range commands {
-		if text == cmd {
-			return true
-		}
-	}
-	return false
+	return routes.IsCommand(text)
 }
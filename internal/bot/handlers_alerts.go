@@ -0,0 +1,137 @@
+package bot
+
+import (
+	"GreenAssistantBot/internal/database"
+	"GreenAssistantBot/internal/fsm"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Кнопка отключения тихих часов
+const noQuietHoursButton = "🔕 Без тихих часов"
+
+// Допустимый порог мороза, °C
+const (
+	minFrostThreshold = -60
+	maxFrostThreshold = 10
+)
+
+// SendWeatherAlertSettings показывает настройки погодных предупреждений
+func (h *MessageHandler) SendWeatherAlertSettings(chatID int64) {
+	user, err := database.GetUserByTelegramID(chatID)
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		h.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.", CreateSettingsMenuKeyboard())
+		return
+	}
+
+	status := "выключены"
+	if user.WeatherAlerts {
+		status = "включены"
+	}
+	quietHours := "не заданы"
+	if user.QuietHoursStart != "" && user.QuietHoursEnd != "" {
+		quietHours = fmt.Sprintf("%s–%s", user.QuietHoursStart, user.QuietHoursEnd)
+	}
+
+	text := fmt.Sprintf(`⚠️ Погодные предупреждения %s
+
+Бот каждые полчаса проверяет прогноз для вашего города на 12 часов вперед и предупреждает о грозе, сильном дожде или снеге, сильном ветре и морозе. О каждом явлении сообщается один раз за день.
+
+🥶 Мороз: %d°C и ниже
+🌙 Тихие часы: %s`, status, user.FrostThreshold, quietHours)
+	h.sendMessage(chatID, text, CreateWeatherAlertsKeyboard(user.WeatherAlerts))
+}
+
+// SetWeatherAlerts включает или выключает погодные предупреждения
+func (h *MessageHandler) SetWeatherAlerts(chatID int64, enabled bool) {
+	if enabled {
+		if user, err := database.GetUserByTelegramID(chatID); err != nil || user.City == "" {
+			h.sendMessage(chatID, "❌ Сначала укажите город в профиле", CreateSettingsMenuKeyboard())
+			return
+		}
+	}
+
+	if err := database.SetUserWeatherAlerts(chatID, enabled); err != nil {
+		log.Printf("Error saving weather alerts setting: %v", err)
+		h.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.", CreateSettingsMenuKeyboard())
+		return
+	}
+	h.SendWeatherAlertSettings(chatID)
+}
+
+// AskForFrostThreshold запрашивает температуру, с которой предупреждать о морозе
+func (h *MessageHandler) AskForFrostThreshold(chatID int64) {
+	h.sendMessage(chatID, "🥶 При какой температуре и ниже предупреждать о морозе? Выберите или введите число градусов:",
+		CreateFrostThresholdKeyboard())
+	h.storage.SetUserState(chatID, StateWaitingForFrostThreshold)
+}
+
+// HandleFrostThreshold сохраняет порог мороза
+func (h *MessageHandler) HandleFrostThreshold(chatID int64, text string) fsm.State {
+	temp, _ := parseFrostThreshold(text)
+	if err := database.SetUserFrostThreshold(chatID, temp); err != nil {
+		log.Printf("Error saving frost threshold: %v", err)
+		h.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.", CreateSettingsMenuKeyboard())
+		return fsm.None
+	}
+
+	h.SendWeatherAlertSettings(chatID)
+	return fsm.None
+}
+
+// AskForQuietHours запрашивает интервал, в который предупреждения не отправляются
+func (h *MessageHandler) AskForQuietHours(chatID int64) {
+	h.sendMessage(chatID, "🌙 В тихие часы предупреждения не приходят, а откладываются до их окончания. Выберите или введите интервал в формате ЧЧ:ММ-ЧЧ:ММ:",
+		CreateQuietHoursKeyboard())
+	h.storage.SetUserState(chatID, StateWaitingForQuietHours)
+}
+
+// HandleQuietHours сохраняет тихие часы
+func (h *MessageHandler) HandleQuietHours(chatID int64, text string) fsm.State {
+	start, end, _ := parseQuietHours(text)
+	if err := database.SetUserQuietHours(chatID, start, end); err != nil {
+		log.Printf("Error saving quiet hours: %v", err)
+		h.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.", CreateSettingsMenuKeyboard())
+		return fsm.None
+	}
+
+	h.SendWeatherAlertSettings(chatID)
+	return fsm.None
+}
+
+// parseFrostThreshold принимает целое число градусов, например "-15" или "-15°C"
+func parseFrostThreshold(text string) (int, bool) {
+	text = strings.TrimSpace(text)
+	text = strings.TrimSuffix(strings.TrimSuffix(text, "C"), "°")
+	text = strings.ReplaceAll(text, "−", "-")
+
+	temp, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || temp < minFrostThreshold || temp > maxFrostThreshold {
+		return 0, false
+	}
+	return temp, true
+}
+
+// parseQuietHours принимает интервал "23:00-07:00". Кнопка отключения дает пустой интервал.
+func parseQuietHours(text string) (string, string, bool) {
+	text = strings.TrimSpace(text)
+	if text == noQuietHoursButton {
+		return "", "", true
+	}
+
+	text = strings.NewReplacer("–", "-", "—", "-", " ", "").Replace(text)
+	from, to, found := strings.Cut(text, "-")
+	if !found {
+		return "", "", false
+	}
+
+	start, okStart := parseClock(from)
+	end, okEnd := parseClock(to)
+	if !okStart || !okEnd || start == end {
+		return "", "", false
+	}
+	return start, end, true
+}
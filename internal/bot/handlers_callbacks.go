@@ -0,0 +1,216 @@
+package bot
+
+import (
+	"GreenAssistantBot/internal/database"
+	"GreenAssistantBot/internal/telegram"
+	pmodel "GreenAssistantBot/pkg/models"
+	"fmt"
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Действия inline-кнопок заметок
+const (
+	cbNoteEdit      = "ne"
+	cbNoteDelete    = "nd"
+	cbNoteDeleteYes = "ny"
+	cbNoteDeleteNo  = "nn"
+	cbNoteMove      = "nm"
+	cbNoteMoveTo    = "nt"
+	cbNoteActions   = "na"
+	cbNotePin       = "np"
+	cbNoteOpen      = "no"
+	cbNotesPage     = "pg"
+	cbTagToggle     = "tt"
+	cbTagNotes      = "tn"
+)
+
+// RegisterCallbacks регистрирует обработчики inline-кнопок заметок
+func (h *NotesHandler) RegisterCallbacks(router *callbackRouter) {
+	router.Register(cbNoteEdit, h.onNoteEdit)
+	router.Register(cbNoteDelete, h.onNoteDelete)
+	router.Register(cbNoteDeleteYes, h.onNoteDeleteConfirm)
+	router.Register(cbNoteDeleteNo, h.onNoteDeleteCancel)
+	router.Register(cbNoteMove, h.onNoteMove)
+	router.Register(cbNoteMoveTo, h.onNoteMoveTo)
+	router.Register(cbNoteActions, h.onNoteActions)
+	router.Register(cbNotePin, h.onNotePin)
+	router.Register(cbNoteOpen, h.onNoteOpen)
+	router.Register(cbNotesPage, h.onNotesPage)
+	router.Register(cbTagToggle, h.onTagToggle)
+	router.Register(cbTagNotes, h.onTagNotes)
+}
+
+// onNoteEdit переводит пользователя в режим ввода нового текста заметки
+func (h *NotesHandler) onNoteEdit(query *tgbotapi.CallbackQuery, args []string) string {
+	chatID := query.Message.Chat.ID
+	noteID, ok := callbackArgID(args, 0)
+	if !ok {
+		return "⚠️ Неверная кнопка"
+	}
+
+	note, err := database.GetNoteByID(chatID, noteID)
+	if err != nil {
+		log.Printf("Error getting note: %v", err)
+		return "❌ Заметка не найдена"
+	}
+
+	h.storage.SetSession(chatID, pmodel.Session{Purpose: pmodel.PurposeEditNote, NoteID: noteID})
+	h.storage.SetUserState(chatID, StateEditingNote)
+
+	text := fmt.Sprintf("✏️ Редактирование заметки\n\n%s\n\n📂 Категория: %s\n📅 Создана: %s\n\n📝 Введите новый текст для заметки:",
+		h.formatNoteContent(note), note.Category.Name, note.CreatedAt.Format("02.01.2006 15:04"))
+	h.msgHandler.sendMessage(chatID, text, CreateBackKeyboard())
+	return ""
+}
+
+// onNoteDelete запрашивает подтверждение удаления
+func (h *NotesHandler) onNoteDelete(query *tgbotapi.CallbackQuery, args []string) string {
+	chatID := query.Message.Chat.ID
+	noteID, ok := callbackArgID(args, 0)
+	if !ok {
+		return "⚠️ Неверная кнопка"
+	}
+
+	note, err := database.GetNoteByID(chatID, noteID)
+	if err != nil {
+		log.Printf("Error getting note: %v", err)
+		return "❌ Заметка не найдена"
+	}
+
+	text := fmt.Sprintf("⚠️ Подтверждение удаления\n\n%s\n\n📂 Категория: %s\n📅 Создана: %s\n\nЗаметка будет перемещена в корзину.",
+		h.formatNoteContent(note), note.Category.Name, note.CreatedAt.Format("02.01.2006 15:04"))
+	h.msgHandler.sendMessage(chatID, text, CreateNoteDeleteConfirmKeyboard(h.msgHandler.callbacks, noteID))
+	return ""
+}
+
+func (h *NotesHandler) onNoteDeleteConfirm(query *tgbotapi.CallbackQuery, args []string) string {
+	chatID := query.Message.Chat.ID
+	noteID, ok := callbackArgID(args, 0)
+	if !ok {
+		return "⚠️ Неверная кнопка"
+	}
+
+	if err := database.DeleteNote(chatID, noteID); err != nil {
+		log.Printf("Error deleting note: %v", err)
+		return "❌ Ошибка при удалении заметки"
+	}
+
+	h.editMessageText(query.Message, "✅ Заметка перемещена в корзину")
+	return "🗑️ Заметка в корзине"
+}
+
+func (h *NotesHandler) onNoteDeleteCancel(query *tgbotapi.CallbackQuery, args []string) string {
+	h.editMessageText(query.Message, "❌ Удаление отменено")
+	return ""
+}
+
+// onNoteMove показывает под заметкой список категорий для переноса
+func (h *NotesHandler) onNoteMove(query *tgbotapi.CallbackQuery, args []string) string {
+	chatID := query.Message.Chat.ID
+	noteID, ok := callbackArgID(args, 0)
+	if !ok {
+		return "⚠️ Неверная кнопка"
+	}
+
+	categories, err := database.GetUserCategories(chatID)
+	if err != nil {
+		log.Printf("Error getting categories: %v", err)
+		return "❌ Ошибка при загрузке категорий"
+	}
+
+	h.editReplyMarkup(query.Message, CreateNoteMoveKeyboard(h.msgHandler.callbacks, noteID, categories))
+	return "📂 Выберите категорию"
+}
+
+func (h *NotesHandler) onNoteMoveTo(query *tgbotapi.CallbackQuery, args []string) string {
+	chatID := query.Message.Chat.ID
+	noteID, ok := callbackArgID(args, 0)
+	categoryID, ok2 := callbackArgID(args, 1)
+	if !ok || !ok2 {
+		return "⚠️ Неверная кнопка"
+	}
+
+	if err := database.MoveNote(chatID, noteID, categoryID); err != nil {
+		log.Printf("Error moving note: %v", err)
+		return "❌ Не удалось перенести заметку"
+	}
+
+	note, err := database.GetNoteByID(chatID, noteID)
+	if err != nil {
+		log.Printf("Error getting note: %v", err)
+		return "❌ Заметка не найдена"
+	}
+
+	h.editReplyMarkup(query.Message, CreateNoteInlineKeyboard(h.msgHandler.callbacks, *note))
+	return fmt.Sprintf("📂 Заметка перенесена в \"%s\"", note.Category.Name)
+}
+
+// onNoteActions возвращает под заметкой основные кнопки действий
+func (h *NotesHandler) onNoteActions(query *tgbotapi.CallbackQuery, args []string) string {
+	chatID := query.Message.Chat.ID
+	noteID, ok := callbackArgID(args, 0)
+	if !ok {
+		return "⚠️ Неверная кнопка"
+	}
+
+	note, err := database.GetNoteByID(chatID, noteID)
+	if err != nil {
+		log.Printf("Error getting note: %v", err)
+		return "❌ Заметка не найдена"
+	}
+
+	h.editReplyMarkup(query.Message, CreateNoteInlineKeyboard(h.msgHandler.callbacks, *note))
+	return ""
+}
+
+func (h *NotesHandler) onNotePin(query *tgbotapi.CallbackQuery, args []string) string {
+	chatID := query.Message.Chat.ID
+	noteID, ok := callbackArgID(args, 0)
+	if !ok {
+		return "⚠️ Неверная кнопка"
+	}
+
+	note, err := database.GetNoteByID(chatID, noteID)
+	if err != nil {
+		log.Printf("Error getting note: %v", err)
+		return "❌ Заметка не найдена"
+	}
+
+	note.Pinned = !note.Pinned
+	if err := database.SetNotePinned(chatID, noteID, note.Pinned); err != nil {
+		log.Printf("Error pinning note: %v", err)
+		return "❌ Ошибка при обновлении заметки"
+	}
+
+	h.editReplyMarkup(query.Message, CreateNoteInlineKeyboard(h.msgHandler.callbacks, *note))
+	if note.Pinned {
+		return "📌 Заметка закреплена"
+	}
+	return "📍 Заметка откреплена"
+}
+
+// editMessageText заменяет текст сообщения и убирает inline-кнопки
+func (h *NotesHandler) editMessageText(message *tgbotapi.Message, text string) {
+	edit := telegram.Edit{ChatID: message.Chat.ID, MessageID: message.MessageID, Text: text}
+	if err := h.sender.Edit(edit); err != nil {
+		log.Printf("Error editing message: %v", err)
+	}
+}
+
+// editMessageWithKeyboard заменяет текст сообщения (в формате HTML) и его inline-кнопки
+func (h *NotesHandler) editMessageWithKeyboard(message *tgbotapi.Message, text string, markup tgbotapi.InlineKeyboardMarkup) {
+	edit := telegram.Edit{ChatID: message.Chat.ID, MessageID: message.MessageID, Text: text, ParseMode: tgbotapi.ModeHTML, Markup: &markup}
+	if err := h.sender.Edit(edit); err != nil {
+		log.Printf("Error editing message: %v", err)
+	}
+}
+
+// editReplyMarkup заменяет inline-кнопки под сообщением
+func (h *NotesHandler) editReplyMarkup(message *tgbotapi.Message, markup tgbotapi.InlineKeyboardMarkup) {
+	edit := telegram.Edit{ChatID: message.Chat.ID, MessageID: message.MessageID, Markup: &markup}
+	if err := h.sender.Edit(edit); err != nil {
+		log.Printf("Error editing reply markup: %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package bot
+
+import (
+	"GreenAssistantBot/internal/database"
+	"GreenAssistantBot/internal/database/models"
+	"GreenAssistantBot/internal/weather"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// resolveCity ищет город, введенный пользователем. Если найдено несколько мест,
+// предлагает выбрать одно из них кнопкой и возвращает false; выбранная кнопка
+// приходит следующим сообщением и распознается по полному названию.
+func (h *MessageHandler) resolveCity(chatID int64, text string) (weather.Place, bool) {
+	text = strings.TrimSpace(text)
+
+	// Кнопка выбора содержит регион и страну через запятую, ищем по названию до запятой
+	query, _, _ := strings.Cut(text, ",")
+	places, err := h.weather.Geocode(query)
+	if errors.Is(err, weather.ErrPlaceNotFound) {
+		h.sendMessage(chatID, fmt.Sprintf("❌ Город «%s» не найден. Проверьте название и введите его еще раз:", text), CreateCityInputKeyboard())
+		return weather.Place{}, false
+	}
+	if err != nil {
+		log.Printf("Error geocoding city %q: %v", text, err)
+		h.sendMessage(chatID, "❌ Не удалось проверить город, попробуйте еще раз позже:", CreateCityInputKeyboard())
+		return weather.Place{}, false
+	}
+
+	places = uniquePlaces(places)
+	for _, place := range places {
+		if strings.EqualFold(place.Label(), text) {
+			return place, true
+		}
+	}
+	if len(places) == 1 {
+		return places[0], true
+	}
+
+	h.sendMessage(chatID, fmt.Sprintf("🔎 Нашлось несколько мест с названием «%s». Выберите нужное:", query), CreateCityChoiceKeyboard(places))
+	return weather.Place{}, false
+}
+
+// resolveLocation определяет город по отправленному местоположению. В профиле
+// сохраняются точные координаты пользователя, а не центра города.
+func (h *MessageHandler) resolveLocation(chatID int64, lat, lon float64) (weather.Place, bool) {
+	place, err := h.weather.ReverseGeocode(lat, lon)
+	if err != nil {
+		log.Printf("Error reverse geocoding %.4f, %.4f: %v", lat, lon, err)
+		h.sendMessage(chatID, "❌ Не удалось определить город по местоположению. Введите его название:", CreateCityInputKeyboard())
+		return weather.Place{}, false
+	}
+
+	place.Lat, place.Lon = lat, lon
+	return place, true
+}
+
+// SendWeatherAt отправляет погоду в точке, например по отправленному местоположению
+func (h *MessageHandler) SendWeatherAt(chatID int64, lat, lon float64) {
+	prefs := h.chatWeatherPreferences(chatID)
+	weatherData, err := h.weather.GetWeatherDataByCoords(lat, lon, prefs)
+	if err != nil {
+		log.Printf("Error getting weather data for %.4f, %.4f: %v", lat, lon, err)
+		h.sendMessage(chatID, "❌ Не удалось получить данные о погоде для этого места", CreateMainMenuKeyboard())
+		return
+	}
+
+	if place, err := h.weather.ReverseGeocode(lat, lon); err == nil {
+		weatherData.Name = place.Name
+	} else {
+		log.Printf("Error reverse geocoding %.4f, %.4f: %v", lat, lon, err)
+	}
+	h.sendWeatherData(chatID, weatherData, prefs)
+}
+
+// saveCity сохраняет найденный город пользователя и определяет по нему часовой пояс
+func (h *MessageHandler) saveCity(chatID int64, place weather.Place) bool {
+	if err := database.SetUserCity(chatID, place.Name, place.Country, place.Lat, place.Lon); err != nil {
+		log.Printf("Error saving city: %v", err)
+		h.sendMessage(chatID, "Произошла ошибка при сохранении города. Попробуйте позже.", CreateMainMenuKeyboard())
+		return false
+	}
+
+	h.updateTimezoneFromPlace(chatID, place)
+	return true
+}
+
+// userPlace возвращает город пользователя. Координаты берутся из профиля, а для
+// профилей, заполненных до появления геокодирования, город ищется по названию.
+func (h *MessageHandler) userPlace(user *models.User) (weather.Place, error) {
+	if user.HasCoords() {
+		return weather.Place{Name: user.City, Country: user.Country, Lat: user.Latitude, Lon: user.Longitude}, nil
+	}
+
+	places, err := h.weather.Geocode(user.City)
+	if err != nil {
+		return weather.Place{}, err
+	}
+	return places[0], nil
+}
+
+// UserWeather возвращает текущую погоду в городе пользователя
+func (h *MessageHandler) UserWeather(user *models.User) (*weather.WeatherData, error) {
+	place, err := h.userPlace(user)
+	if err != nil {
+		return nil, err
+	}
+
+	weatherData, err := h.weather.GetWeatherDataByCoords(place.Lat, place.Lon, WeatherPreferences(user))
+	if err != nil {
+		return nil, err
+	}
+	weatherData.Name = user.City
+	return weatherData, nil
+}
+
+// UserForecast возвращает прогноз для города пользователя в единицах и на языке prefs
+func (h *MessageHandler) UserForecast(user *models.User, prefs weather.Preferences) (*weather.WeatherForecast, error) {
+	place, err := h.userPlace(user)
+	if err != nil {
+		return nil, err
+	}
+
+	forecast, err := h.weather.GetForecast(place.Lat, place.Lon, prefs)
+	if err != nil {
+		return nil, err
+	}
+	forecast.City = user.City
+	return forecast, nil
+}
+
+// SendUserWeather отправляет погоду в городе из профиля пользователя
+func (h *MessageHandler) SendUserWeather(chatID int64, user *models.User) {
+	weatherData, err := h.UserWeather(user)
+	if err != nil {
+		log.Printf("Error getting weather data for user %d: %v", chatID, err)
+		h.sendMessage(chatID, fmt.Sprintf("❌ Не удалось получить данные о погоде для города '%s'", user.City), CreateMainMenuKeyboard())
+		return
+	}
+
+	h.sendWeatherData(chatID, weatherData, WeatherPreferences(user))
+}
+
+// cityLabel возвращает город пользователя вместе с кодом страны, если он известен
+func cityLabel(user *models.User) string {
+	if user.Country == "" {
+		return user.City
+	}
+	return user.City + ", " + user.Country
+}
+
+// uniquePlaces убирает варианты с одинаковым названием, регионом и страной
+func uniquePlaces(places []weather.Place) []weather.Place {
+	seen := make(map[string]bool, len(places))
+	var unique []weather.Place
+	for _, place := range places {
+		label := strings.ToLower(place.Label())
+		if !seen[label] {
+			seen[label] = true
+			unique = append(unique, place)
+		}
+	}
+	return unique
+}
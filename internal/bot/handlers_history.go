@@ -0,0 +1,218 @@
+package bot
+
+import (
+	"GreenAssistantBot/internal/database"
+	"GreenAssistantBot/internal/database/models"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Сколько последних ревизий показывать в истории заметки
+const maxHistoryRevisions = 10
+
+// Дальше пословное сравнение слишком затратно, показываем тексты целиком
+const maxDiffCells = 250000
+
+// Ограничение длины сообщения с ревизией в символах HTML-текста: видимый текст
+// не длиннее него, поэтому сообщение укладывается в лимит Telegram (4096)
+const maxRevisionLength = 4000
+
+// Многоточие на месте не уместившейся части сравнения
+const diffEllipsis = " …"
+
+// Действия inline-кнопок истории
+const (
+	cbNoteHistory      = "nh"
+	cbRevisionView     = "rv"
+	cbRevisionRollback = "rr"
+)
+
+// RegisterHistoryCallbacks регистрирует обработчики кнопок истории заметки
+func (h *NotesHandler) RegisterHistoryCallbacks(router *callbackRouter) {
+	router.Register(cbNoteHistory, h.onNoteHistory)
+	router.Register(cbRevisionView, h.onRevisionView)
+	router.Register(cbRevisionRollback, h.onRevisionRollback)
+}
+
+// onNoteHistory показывает список ревизий заметки. Нажатие под превью
+// заметки отправляет новое сообщение, кнопка "назад" из ревизии — редактирует.
+func (h *NotesHandler) onNoteHistory(query *tgbotapi.CallbackQuery, args []string) string {
+	chatID := query.Message.Chat.ID
+	noteID, ok := callbackArgID(args, 0)
+	if !ok {
+		return "⚠️ Неверная кнопка"
+	}
+
+	revisions, err := database.GetNoteRevisions(chatID, noteID, maxHistoryRevisions)
+	if err != nil {
+		log.Printf("Error getting note revisions: %v", err)
+		return "❌ Ошибка при загрузке истории"
+	}
+	if len(revisions) == 0 {
+		return "🕘 Заметка еще не изменялась"
+	}
+
+	text := fmt.Sprintf("🕘 История заметки\n\nПоследние изменения (до %d). Выберите версию, чтобы увидеть отличия от текущей:", maxHistoryRevisions)
+	keyboard := CreateNoteHistoryKeyboard(h.msgHandler.callbacks, revisions)
+
+	if len(args) > 1 {
+		h.editMessageWithKeyboard(query.Message, text, keyboard)
+	} else {
+		h.msgHandler.sendHTML(chatID, text, keyboard)
+	}
+	return ""
+}
+
+// onRevisionView показывает ревизию и ее отличия от текущей версии заметки
+func (h *NotesHandler) onRevisionView(query *tgbotapi.CallbackQuery, args []string) string {
+	chatID := query.Message.Chat.ID
+	revisionID, ok := callbackArgID(args, 0)
+	if !ok {
+		return "⚠️ Неверная кнопка"
+	}
+
+	revision, err := database.GetNoteRevision(chatID, revisionID)
+	if err != nil {
+		log.Printf("Error getting revision: %v", err)
+		return "❌ Версия не найдена"
+	}
+	note, err := database.GetNoteByID(chatID, revision.NoteID)
+	if err != nil {
+		log.Printf("Error getting note: %v", err)
+		return "❌ Заметка не найдена"
+	}
+
+	h.editMessageWithKeyboard(query.Message, revisionText(*revision, note), CreateRevisionKeyboard(h.msgHandler.callbacks, revision))
+	return ""
+}
+
+// revisionText описывает ревизию и ее отличия от текущей версии заметки.
+// Текст не длиннее maxRevisionLength; если изменились и текст, и подпись,
+// каждое сравнение занимает не больше половины свободного места.
+func revisionText(revision models.NoteRevision, note *models.Note) string {
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("🕘 Версия от %s\n", revision.CreatedAt.Format("02.01.2006 15:04")))
+	if revision.CategoryID != note.CategoryID {
+		text.WriteString(fmt.Sprintf("📂 Категория: %s → %s\n",
+			html.EscapeString(revision.Category.Name), html.EscapeString(note.Category.Name)))
+	}
+	text.WriteString("\nИзменения до текущей версии (<s>удалено</s>, <u>добавлено</u>):\n\n")
+
+	captionChanged := revision.Caption != note.Caption
+	contentLimit := maxRevisionLength - utf8.RuneCountInString(text.String())
+	if captionChanged {
+		contentLimit /= 2
+	}
+	if revision.Content != note.Content {
+		text.WriteString(diffWords(revision.Content, note.Content, contentLimit))
+	} else {
+		text.WriteString(html.EscapeString(truncateRunes(note.Content, min(1000, contentLimit))))
+	}
+	if captionChanged {
+		text.WriteString("\n\n📝 Подпись: ")
+		text.WriteString(diffWords(revision.Caption, note.Caption, maxRevisionLength-utf8.RuneCountInString(text.String())))
+	}
+
+	return text.String()
+}
+
+// onRevisionRollback возвращает заметку к выбранной версии
+func (h *NotesHandler) onRevisionRollback(query *tgbotapi.CallbackQuery, args []string) string {
+	chatID := query.Message.Chat.ID
+	revisionID, ok := callbackArgID(args, 0)
+	if !ok {
+		return "⚠️ Неверная кнопка"
+	}
+
+	note, err := database.RollbackNote(chatID, revisionID)
+	if err != nil {
+		log.Printf("Error rolling back note: %v", err)
+		return "❌ Не удалось восстановить версию"
+	}
+
+	// Теги пересчитываются по восстановленному тексту
+	h.applyHashtags(chatID, note)
+
+	h.editMessageText(query.Message, "↩️ Заметка возвращена к выбранной версии")
+	h.sendNotePreview(chatID, *note)
+	return "↩️ Версия восстановлена"
+}
+
+// diffWords сравнивает тексты по словам и возвращает HTML не длиннее limit
+// символов, где удаленные слова зачеркнуты, а добавленные подчеркнуты
+func diffWords(before, after string, limit int) string {
+	a, b := strings.Fields(before), strings.Fields(after)
+	if len(a)*len(b) > maxDiffCells {
+		// Экранирование может удлинить текст, поэтому берем с запасом
+		side := min(1000, (limit-30)/4)
+		return fmt.Sprintf("<s>%s</s>\n\n<u>%s</u>",
+			html.EscapeString(truncateRunes(before, side)), html.EscapeString(truncateRunes(after, side)))
+	}
+
+	// lcs[i][j] — длина общей подпоследовательности a[i:] и b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var parts []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			parts = append(parts, html.EscapeString(a[i]))
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			parts = append(parts, "<u>"+html.EscapeString(b[j])+"</u>")
+			j++
+		default:
+			parts = append(parts, "<s>"+html.EscapeString(a[i])+"</s>")
+			i++
+		}
+	}
+
+	var result strings.Builder
+	length := 0
+	for k, part := range parts {
+		if k > 0 {
+			part = " " + part
+		}
+		// Для последней части место под многоточие не нужно
+		reserve := utf8.RuneCountInString(diffEllipsis)
+		if k == len(parts)-1 {
+			reserve = 0
+		}
+		partLength := utf8.RuneCountInString(part)
+		if length+partLength+reserve > limit {
+			result.WriteString(diffEllipsis)
+			break
+		}
+		result.WriteString(part)
+		length += partLength
+	}
+	return result.String()
+}
+
+// revisionLabel возвращает подпись кнопки ревизии
+func revisionLabel(revision models.NoteRevision) string {
+	text := revision.Content
+	if text == "" {
+		text = revision.Caption
+	}
+	return fmt.Sprintf("%s · %s", revision.CreatedAt.Format("02.01 15:04"), truncateRunes(text, 30))
+}
@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"GreenAssistantBot/internal/database/models"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestDiffWords(t *testing.T) {
+	got := diffWords("купить молоко и хлеб", "купить кефир и хлеб <свежий>", 100)
+	want := "купить <u>кефир</u> <s>молоко</s> и хлеб <u>&lt;свежий&gt;</u>"
+	if got != want {
+		t.Errorf("diffWords:\ngot  %q\nwant %q", got, want)
+	}
+
+	if got := diffWords("раз два три", "раз два три четыре", 12); got != "раз два …" {
+		t.Errorf("diffWords must cut the diff to the limit, got %q", got)
+	}
+}
+
+// Длинные изменения и текста, и подписи вместе укладываются в лимит сообщения
+func TestRevisionTextLimit(t *testing.T) {
+	words := func(word string, n int) string {
+		return strings.TrimSpace(strings.Repeat(word+" ", n))
+	}
+
+	revision := models.NoteRevision{
+		CreatedAt: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		Content:   words("старый", 400),
+		Caption:   words("прежняя", 400),
+	}
+	note := &models.Note{
+		Content: words("новый", 400),
+		Caption: words("обновленная", 400),
+	}
+
+	text := revisionText(revision, note)
+	if length := utf8.RuneCountInString(text); length > maxRevisionLength {
+		t.Fatalf("revision text is %d characters, limit %d", length, maxRevisionLength)
+	}
+	for _, want := range []string{"<u>новый</u>", "📝 Подпись: ", "<u>обновленная</u>"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("revision text does not contain %q", want)
+		}
+	}
+	if strings.Count(text, "<s>")+strings.Count(text, "<u>") != strings.Count(text, "</s>")+strings.Count(text, "</u>") {
+		t.Error("truncation must not break HTML tags")
+	}
+}
@@ -0,0 +1,282 @@
+package bot
+
+import (
+	"GreenAssistantBot/internal/database"
+	"GreenAssistantBot/internal/database/models"
+	"GreenAssistantBot/internal/fsm"
+	"GreenAssistantBot/internal/telegram"
+	"GreenAssistantBot/internal/weather"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+)
+
+// Действия inline-кнопок сохраненных мест
+const (
+	cbLocationAdd    = "la"
+	cbLocationNotify = "lt"
+	cbLocationDelete = "ld"
+)
+
+// Максимальная длина названия места
+const maxLocationLabelLength = 32
+
+// Префиксы кнопок быстрого выбора места для погоды
+const (
+	homePlacePrefix  = "🏠 "
+	savedPlacePrefix = "📍 "
+)
+
+// RegisterLocationCallbacks регистрирует обработчики кнопок сохраненных мест
+func (h *MessageHandler) RegisterLocationCallbacks(router *callbackRouter) {
+	router.Register(cbLocationAdd, h.onLocationAdd)
+	router.Register(cbLocationNotify, h.onLocationNotify)
+	router.Register(cbLocationDelete, h.onLocationDelete)
+}
+
+// SendLocations отправляет список сохраненных мест с кнопками управления
+func (h *MessageHandler) SendLocations(chatID int64) {
+	text, keyboard, err := h.renderLocations(chatID)
+	if err != nil {
+		log.Printf("Error getting locations: %v", err)
+		h.sendMessage(chatID, "❌ Ошибка при загрузке мест", CreateSettingsMenuKeyboard())
+		return
+	}
+	h.sendMessage(chatID, text, keyboard)
+}
+
+func (h *MessageHandler) renderLocations(chatID int64) (string, tgbotapi.InlineKeyboardMarkup, error) {
+	locations, err := database.GetUserLocations(chatID)
+	if err != nil {
+		return "", tgbotapi.InlineKeyboardMarkup{}, err
+	}
+
+	var text strings.Builder
+	text.WriteString("📍 Мои места\n\n")
+	if user, err := database.GetUserByTelegramID(chatID); err == nil && user.City != "" {
+		text.WriteString(fmt.Sprintf("🏠 Основной город: %s\n", cityLabel(user)))
+	}
+	if len(locations) == 0 {
+		text.WriteString("\nДобавьте дом, офис или место поездки, чтобы быстро смотреть там погоду.")
+	} else {
+		for _, location := range locations {
+			notify := ""
+			if location.Notify {
+				notify = " 🔔"
+			}
+			text.WriteString(fmt.Sprintf("📍 %s — %s%s\n", location.Label, locationCity(&location), notify))
+		}
+		text.WriteString("\n🔔 — место попадает в утреннее уведомление о погоде. Нажмите на место, чтобы включить или выключить это.")
+	}
+
+	return text.String(), CreateLocationsKeyboard(h.callbacks, locations), nil
+}
+
+func (h *MessageHandler) onLocationAdd(query *tgbotapi.CallbackQuery, args []string) string {
+	chatID := query.Message.Chat.ID
+
+	locations, err := database.GetUserLocations(chatID)
+	if err != nil {
+		log.Printf("Error getting locations: %v", err)
+		return "❌ Ошибка при загрузке мест"
+	}
+	if len(locations) >= database.MaxUserLocations {
+		return fmt.Sprintf("❌ Можно сохранить не больше %d мест", database.MaxUserLocations)
+	}
+
+	h.sendMessage(chatID, "🏷️ Как назвать место? Например: Дом, Офис, Поездка", CreateBackKeyboard())
+	h.storage.SetUserState(chatID, StateWaitingForLocationLabel)
+	return ""
+}
+
+func (h *MessageHandler) onLocationNotify(query *tgbotapi.CallbackQuery, args []string) string {
+	locationID, ok := callbackArgID(args, 0)
+	if !ok {
+		return "⚠️ Неверная кнопка"
+	}
+
+	location, err := database.ToggleUserLocationNotify(query.Message.Chat.ID, locationID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return "❌ Место не найдено"
+	case err != nil:
+		log.Printf("Error toggling location notify: %v", err)
+		return "❌ Ошибка при сохранении"
+	}
+
+	h.refreshLocations(query)
+	if location.Notify {
+		return "🔔 Место добавлено в утреннее уведомление"
+	}
+	return "🔕 Место убрано из утреннего уведомления"
+}
+
+func (h *MessageHandler) onLocationDelete(query *tgbotapi.CallbackQuery, args []string) string {
+	locationID, ok := callbackArgID(args, 0)
+	if !ok {
+		return "⚠️ Неверная кнопка"
+	}
+
+	err := database.DeleteUserLocation(query.Message.Chat.ID, locationID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return "❌ Место не найдено"
+	case err != nil:
+		log.Printf("Error deleting location: %v", err)
+		return "❌ Ошибка при удалении места"
+	}
+
+	h.refreshLocations(query)
+	return "🗑️ Место удалено"
+}
+
+// refreshLocations перерисовывает список мест в сообщении с кнопками
+func (h *MessageHandler) refreshLocations(query *tgbotapi.CallbackQuery) {
+	text, keyboard, err := h.renderLocations(query.Message.Chat.ID)
+	if err != nil {
+		log.Printf("Error getting locations: %v", err)
+		return
+	}
+
+	edit := telegram.Edit{ChatID: query.Message.Chat.ID, MessageID: query.Message.MessageID, Text: text, Markup: &keyboard}
+	if err := h.sender.Edit(edit); err != nil {
+		log.Printf("Error editing message: %v", err)
+	}
+}
+
+// HandleLocationLabel запоминает название нового места и запрашивает его город
+func (h *MessageHandler) HandleLocationLabel(chatID int64, label string) fsm.State {
+	session, _ := h.storage.GetSession(chatID)
+	session.LocationLabel = strings.TrimSpace(label)
+	h.storage.SetSession(chatID, session)
+
+	h.sendMessage(chatID, fmt.Sprintf("🚩 Введите город для места «%s» или отправьте местоположение:", session.LocationLabel),
+		CreateCityInputKeyboard())
+	return StateWaitingForLocationCity
+}
+
+// saveLocation сохраняет новое место с найденным городом
+func (h *MessageHandler) saveLocation(chatID int64, place weather.Place) {
+	session, _ := h.storage.GetSession(chatID)
+	label := session.LocationLabel
+	session.LocationLabel = ""
+	h.storage.SetSession(chatID, session)
+
+	if label == "" {
+		h.sendMessage(chatID, "❌ Не удалось добавить место, начните заново", CreateSettingsMenuKeyboard())
+		return
+	}
+
+	err := database.CreateUserLocation(&models.UserLocation{
+		TelegramID: chatID,
+		Label:      label,
+		City:       place.Name,
+		Country:    place.Country,
+		Latitude:   place.Lat,
+		Longitude:  place.Lon,
+	})
+	switch {
+	case errors.Is(err, database.ErrLocationExists):
+		h.sendMessage(chatID, fmt.Sprintf("❌ Место «%s» уже есть", label), CreateSettingsMenuKeyboard())
+		return
+	case errors.Is(err, database.ErrTooManyLocations):
+		h.sendMessage(chatID, fmt.Sprintf("❌ Можно сохранить не больше %d мест", database.MaxUserLocations), CreateSettingsMenuKeyboard())
+		return
+	case err != nil:
+		log.Printf("Error saving location: %v", err)
+		h.sendMessage(chatID, "Произошла ошибка при сохранении места. Попробуйте позже.", CreateSettingsMenuKeyboard())
+		return
+	}
+
+	h.sendMessage(chatID, fmt.Sprintf("✅ Место «%s» сохранено: %s", label, place.Label()), CreateSettingsMenuKeyboard())
+	h.SendLocations(chatID)
+}
+
+// AskForWeatherPlace предлагает выбрать место для погоды: основной город или сохраненное место
+func (h *MessageHandler) AskForWeatherPlace(chatID int64, user *models.User, locations []models.UserLocation) {
+	h.sendMessage(chatID, "🌡️ Где показать погоду? Выберите место, введите город или отправьте местоположение:",
+		CreateWeatherPlacesKeyboard(user, locations))
+	h.storage.SetUserState(chatID, StateWaitingForWeatherCity)
+}
+
+// SendPickedWeather показывает погоду для кнопки быстрого выбора места.
+// Возвращает false, если текст не является такой кнопкой.
+func (h *MessageHandler) SendPickedWeather(chatID int64, text string) bool {
+	user, err := database.GetUserByTelegramID(chatID)
+	if err != nil {
+		return false
+	}
+
+	if user.City != "" && text == homePlacePrefix+user.City {
+		h.SendUserWeather(chatID, user)
+		return true
+	}
+
+	label, found := strings.CutPrefix(text, savedPlacePrefix)
+	if !found {
+		return false
+	}
+	locations, err := database.GetUserLocations(chatID)
+	if err != nil {
+		log.Printf("Error getting locations: %v", err)
+		return false
+	}
+	for _, location := range locations {
+		if location.Label == label {
+			h.sendLocationWeather(chatID, user, &location)
+			return true
+		}
+	}
+	return false
+}
+
+func (h *MessageHandler) sendLocationWeather(chatID int64, user *models.User, location *models.UserLocation) {
+	prefs := WeatherPreferences(user)
+	weatherData, err := h.weather.GetWeatherDataByCoords(location.Latitude, location.Longitude, prefs)
+	if err != nil {
+		log.Printf("Error getting weather data for location %d: %v", location.ID, err)
+		h.sendMessage(chatID, fmt.Sprintf("❌ Не удалось получить данные о погоде для места «%s»", location.Label), CreateMainMenuKeyboard())
+		return
+	}
+
+	weatherData.Name = fmt.Sprintf("%s (%s)", location.City, location.Label)
+	h.sendWeatherData(chatID, weatherData, prefs)
+}
+
+// LocationsDigest возвращает краткую погоду в местах, включенных в утреннее уведомление,
+// или пустую строку, если таких мест нет
+func (h *MessageHandler) LocationsDigest(user *models.User) string {
+	locations, err := database.GetNotifyLocations(user.TelegramID)
+	if err != nil {
+		log.Printf("Error getting notify locations for user %d: %v", user.TelegramID, err)
+		return ""
+	}
+	if len(locations) == 0 {
+		return ""
+	}
+
+	prefs := WeatherPreferences(user)
+	lines := []string{"📍 Другие места:"}
+	for _, location := range locations {
+		weatherData, err := h.weather.GetWeatherDataByCoords(location.Latitude, location.Longitude, prefs)
+		if err != nil {
+			log.Printf("Error getting weather data for location %d: %v", location.ID, err)
+			lines = append(lines, fmt.Sprintf("❔ %s: нет данных", location.Label))
+			continue
+		}
+		lines = append(lines, h.weather.FormatWeatherSummary(location.Label, weatherData, prefs))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// locationCity возвращает город места вместе с кодом страны, если он известен
+func locationCity(location *models.UserLocation) string {
+	if location.Country == "" {
+		return location.City
+	}
+	return location.City + ", " + location.Country
+}
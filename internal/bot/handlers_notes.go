@@ -3,28 +3,32 @@ package bot
 import (
 	"GreenAssistantBot/internal/database"
 	"GreenAssistantBot/internal/database/models"
+	"GreenAssistantBot/internal/fsm"
 	"GreenAssistantBot/internal/storage"
+	"GreenAssistantBot/internal/telegram"
 	pmodel "GreenAssistantBot/pkg/models"
+	"errors"
 	"fmt"
 	"log"
-	"strconv"
 	"strings"
-	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
 )
 
 type NotesHandler struct {
-	bot        *tgbotapi.BotAPI
+	sender     telegram.Sender
 	storage    storage.BotStorage
 	msgHandler *MessageHandler
+	pageSize   int
 }
 
-func NewNotesHandler(bot *tgbotapi.BotAPI, storage storage.BotStorage, msgHandler *MessageHandler) *NotesHandler {
+func NewNotesHandler(sender telegram.Sender, storage storage.BotStorage, msgHandler *MessageHandler) *NotesHandler {
 	return &NotesHandler{
-		bot:        bot,
+		sender:     sender,
 		storage:    storage,
 		msgHandler: msgHandler,
+		pageSize:   notesPageSizeFromEnv(),
 	}
 }
 
@@ -39,6 +43,7 @@ func (h *NotesHandler) SendNotesMenu(chatID int64) {
 • 🖼️ Сохранение фото, видео, голосовых сообщений
 • 🔗 Сохранение ссылок и файлов
 • 📂 Сортировка по категориям
+• 🏷️ Теги из #хештегов
 • 🔍 Быстрый поиск и доступ`
 
 	h.msgHandler.sendMessage(chatID, text, CreateNotesMenuKeyboard())
@@ -81,12 +86,7 @@ func (h *NotesHandler) AskForCategoryName(chatID int64) {
 }
 
 // HandleCategoryCreation обрабатывает создание категории
-func (h *NotesHandler) HandleCategoryCreation(chatID int64, categoryName string) {
-	if strings.TrimSpace(categoryName) == "" {
-		h.msgHandler.sendMessage(chatID, "❌ Название категории не может быть пустым", CreateBackKeyboard())
-		return
-	}
-
+func (h *NotesHandler) HandleCategoryCreation(chatID int64, categoryName string) fsm.State {
 	// Цвета для категорий (можно расширить)
 	colors := []string{"🔵", "🟢", "🟡", "🟠", "🔴", "🟣"}
 	colorIndex := len(h.storage.GetMessageHistory(chatID)) % len(colors)
@@ -95,15 +95,15 @@ func (h *NotesHandler) HandleCategoryCreation(chatID int64, categoryName string)
 	if err != nil {
 		log.Printf("Error creating category: %v", err)
 		h.msgHandler.sendMessage(chatID, "❌ Ошибка при создании категории", CreateNotesMenuKeyboard())
-		return
+		return fsm.None
 	}
 
 	h.msgHandler.sendMessage(chatID, fmt.Sprintf("✅ Категория \"%s\" успешно создана!", categoryName), CreateCategoriesManagementKeyboard())
-	h.storage.SetUserState(chatID, "")
+	return fsm.None
 }
 
 // SendCategoriesForSelection отправляет категории для выбора
-func (h *NotesHandler) SendCategoriesForSelection(chatID int64, purpose string) {
+func (h *NotesHandler) SendCategoriesForSelection(chatID int64, purpose pmodel.Purpose) {
 	categories, err := database.GetUserCategories(chatID)
 	if err != nil {
 		log.Printf("Error getting categories: %v", err)
@@ -116,49 +116,40 @@ func (h *NotesHandler) SendCategoriesForSelection(chatID int64, purpose string)
 		return
 	}
 
-	log.Printf("SendCategoriesForSelection: chatID=%d, purpose=%s, categories=%d", chatID, purpose, len(categories))
+	state, ok := categorySelectionStates[purpose]
+	if !ok {
+		log.Printf("Unknown category selection purpose: %s", purpose)
+		h.msgHandler.SendMessage(chatID, "❌ Неизвестная операция", CreateNotesMenuKeyboard())
+		return
+	}
 
-	// Получаем текущие данные и обновляем только поле Data
-	currentData, _ := h.storage.GetUserData(chatID)
-	currentData.Data = purpose
-	h.storage.SetUserData(chatID, currentData)
-	h.storage.SetUserState(chatID, StateWaitingForNoteCategory)
+	log.Printf("SendCategoriesForSelection: chatID=%d, purpose=%s, categories=%d", chatID, purpose, len(categories))
 
-	// Двойная проверка что данные сохранились
-	savedData, exists := h.storage.GetUserData(chatID)
-	savedState, stateExists := h.storage.GetUserState(chatID)
-
-	log.Printf("After setting - Data exists: %t, State exists: %t", exists, stateExists)
-	if exists {
-		log.Printf("Saved user data: %+v", savedData)
-	}
-	if stateExists {
-		log.Printf("Saved user state: %s", savedState)
-	}
+	// Получаем текущую сессию и обновляем только цель выбора (черновик сохраняется)
+	session, _ := h.storage.GetSession(chatID)
+	session.Purpose = purpose
+	h.storage.SetSession(chatID, session)
+	h.storage.SetUserState(chatID, state)
 
 	h.msgHandler.SendMessage(chatID, "📂 Выберите категорию:", CreateCategoriesKeyboard(categories))
 }
 
 // HandleNoteContent обрабатывает контент заметки
-func (h *NotesHandler) HandleNoteContent(chatID int64, update tgbotapi.Update) {
-	userData, exists := h.storage.GetUserData(chatID)
-	if !exists {
-		log.Printf("No user data found for chat %d in HandleNoteContent", chatID)
+func (h *NotesHandler) HandleNoteContent(chatID int64, update tgbotapi.Update) fsm.State {
+	session, exists := h.storage.GetSession(chatID)
+	if !exists || session.CategoryID == 0 {
+		log.Printf("No session found for chat %d in HandleNoteContent", chatID)
 		h.msgHandler.sendMessage(chatID, "❌ Сессия истекла, начните заново", CreateNotesMenuKeyboard())
-		h.storage.SetUserState(chatID, "")
-		return
+		return fsm.None
 	}
 
-	categoryName := userData.Category
-	log.Printf("Processing note content for category: %s", categoryName)
+	log.Printf("Processing note content for category: %d", session.CategoryID)
 
-	// Находим категорию по имени используя новую функцию
-	selectedCategory, err := database.GetCategoryByName(chatID, categoryName)
+	selectedCategory, err := database.GetCategoryByID(chatID, session.CategoryID)
 	if err != nil {
 		log.Printf("Error finding category: %v", err)
 		h.msgHandler.sendMessage(chatID, "❌ Категория не найдена", CreateNotesMenuKeyboard())
-		h.storage.SetUserState(chatID, "")
-		return
+		return fsm.None
 	}
 
 	log.Printf("Selected category: %+v", selectedCategory)
@@ -172,7 +163,7 @@ func (h *NotesHandler) HandleNoteContent(chatID int64, update tgbotapi.Update) {
 	message := update.Message
 	if message == nil {
 		log.Printf("Message is nil")
-		return
+		return fsm.None
 	}
 
 	// Определяем тип контента и сохраняем
@@ -202,185 +193,141 @@ func (h *NotesHandler) HandleNoteContent(chatID int64, update tgbotapi.Update) {
 	} else {
 		log.Printf("Unsupported message type")
 		h.msgHandler.sendMessage(chatID, "❌ Неподдерживаемый тип сообщения", CreateNotesMenuKeyboard())
-		h.storage.SetUserState(chatID, "")
-		return
+		return fsm.None
 	}
 
 	if err := database.CreateNote(note); err != nil {
 		log.Printf("Error creating note: %v", err)
 		h.msgHandler.sendMessage(chatID, "❌ Ошибка при сохранении заметки", CreateNotesMenuKeyboard())
-		h.storage.SetUserState(chatID, "")
-		return
+		return fsm.None
 	}
 
 	log.Printf("Note created successfully")
-	h.msgHandler.sendMessage(chatID,
-		fmt.Sprintf("✅ Заметка сохранена в категорию \"%s\"!", selectedCategory.Name),
-		CreateNotesMenuKeyboard())
-	h.storage.SetUserState(chatID, "")
+	text := fmt.Sprintf("✅ Заметка сохранена в категорию \"%s\"!", selectedCategory.Name)
+	if tags := h.applyHashtags(chatID, note); len(tags) > 0 {
+		text += "\n🏷️ Теги: " + formatTags(tags)
+	}
+	h.msgHandler.sendMessage(chatID, text, CreateNotesMenuKeyboard())
+	return fsm.None
 }
 
 // SendNotesByCategory отправляет заметки конкретной категории
-func (h *NotesHandler) SendNotesByCategory(chatID int64, categoryName string) {
+func (h *NotesHandler) SendNotesByCategory(chatID int64, categoryName string) fsm.State {
 	category, err := database.GetCategoryByName(chatID, categoryName)
 	if err != nil {
 		log.Printf("Error finding category: %v", err)
 		h.msgHandler.sendMessage(chatID, "❌ Категория не найдена", CreateNotesMenuKeyboard())
-		return
+		return fsm.None
 	}
 
+	// Запоминаем категорию, чтобы "📸 Медиа-заметки" показывали заметки из нее
+	h.storage.SetSession(chatID, pmodel.Session{Purpose: pmodel.PurposeViewNotes, CategoryID: category.ID})
+
 	h.SendUserNotes(chatID, category.ID)
+	return fsm.None
 }
 
 // SendCategoriesForViewing отправляет категории для просмотра заметок
 func (h *NotesHandler) SendCategoriesForViewing(chatID int64) {
-	h.SendCategoriesForSelection(chatID, "view_notes")
+	h.SendCategoriesForSelection(chatID, pmodel.PurposeViewNotes)
 }
 
-// SendMediaNotes отправляет только медиа-заметки
+// SendMediaNotes отправляет постраничный список медиа-заметок
 func (h *NotesHandler) SendMediaNotes(chatID int64, categoryID uint) {
-	notes, err := database.GetUserNotes(chatID, categoryID)
-	if err != nil {
-		log.Printf("Error getting notes: %v", err)
-		return
-	}
-
-	// Фильтруем только медиа-заметки
-	var mediaNotes []models.Note
-	for _, note := range notes {
-		if note.Type == models.NoteTypePhoto || note.Type == models.NoteTypeVideo || note.Type == models.NoteTypeVoice {
-			mediaNotes = append(mediaNotes, note)
-		}
-	}
-
-	if len(mediaNotes) == 0 {
-		h.msgHandler.sendMessage(chatID, "📸 В этой категории нет медиа-заметок", CreateNotesViewKeyboard())
-		return
-	}
-
-	// Отправляем информацию о количестве медиа-заметок
-	countMsg := fmt.Sprintf("📸 Медиа-заметок: %d", len(mediaNotes))
-	h.msgHandler.sendMessage(chatID, countMsg, CreateNotesViewKeyboard())
-
-	// Отправляем все медиа-заметки
-	for _, note := range mediaNotes {
-		h.sendNotePreview(chatID, note)
-		// Задержка между отправкой медиа
-		time.Sleep(500 * time.Millisecond)
-	}
+	h.sendNoteList(chatID, noteList{Mode: noteListMedia, CategoryID: categoryID}, "📸 В этой категории нет медиа-заметок")
 }
 
-// SendUserNotes отправляет заметки пользователя
+// SendUserNotes отправляет постраничный список заметок пользователя
 func (h *NotesHandler) SendUserNotes(chatID int64, categoryID uint) {
-	notes, err := database.GetUserNotes(chatID, categoryID)
-	if err != nil {
-		log.Printf("Error getting notes: %v", err)
-		h.msgHandler.sendMessage(chatID, "❌ Ошибка при загрузке заметок", CreateNotesMenuKeyboard())
-		return
-	}
-
-	if len(notes) == 0 {
-		var msg string
-		if categoryID == 0 {
-			msg = "📝 У вас пока нет заметок"
-		} else {
-			category, _ := database.GetCategoryByID(chatID, categoryID)
-			if category != nil {
-				msg = fmt.Sprintf("📝 У вас пока нет заметок в категории \"%s\"", category.Name)
-			} else {
-				msg = "📝 У вас пока нет заметок в этой категории"
-			}
-		}
-		h.msgHandler.sendMessage(chatID, msg, CreateNotesMenuKeyboard())
-		return
-	}
-
-	// Отправляем информацию о количестве заметок
-	var countMsg string
-	if categoryID == 0 {
-		countMsg = fmt.Sprintf("📋 Всего заметок: %d", len(notes))
-	} else {
+	emptyText := "📝 У вас пока нет заметок"
+	if categoryID != 0 {
 		category, _ := database.GetCategoryByID(chatID, categoryID)
 		if category != nil {
-			countMsg = fmt.Sprintf("📋 Заметок в категории \"%s\": %d", category.Name, len(notes))
+			emptyText = fmt.Sprintf("📝 У вас пока нет заметок в категории \"%s\"", category.Name)
 		} else {
-			countMsg = fmt.Sprintf("📋 Заметок: %d", len(notes))
+			emptyText = "📝 У вас пока нет заметок в этой категории"
 		}
 	}
-	h.msgHandler.sendMessage(chatID, countMsg, CreateNotesViewKeyboard())
-
-	// Отправляем все заметки по порядку
-	for _, note := range notes {
-		h.sendNotePreview(chatID, note)
-		// Небольшая задержка между отправкой заметок
-		time.Sleep(300 * time.Millisecond)
-	}
 
-	// Добавляем кнопку управления заметками
-	h.msgHandler.sendMessage(chatID, "🛠️ Для управления заметками используйте меню управления", CreateNotesViewKeyboard())
+	h.sendNoteList(chatID, noteList{Mode: noteListAll, CategoryID: categoryID}, emptyText)
 }
 
-// sendNotePreview отправляет превью заметки
+// sendNotePreview отправляет превью заметки с кнопками действий
 func (h *NotesHandler) sendNotePreview(chatID int64, note models.Note) {
 	var text string
 	emoji := getNoteTypeEmoji(note.Type)
+	if note.Pinned {
+		emoji = "📌 " + emoji
+	}
+	actions := CreateNoteInlineKeyboard(h.msgHandler.callbacks, note)
+
+	// Строка с тегами добавляется после даты создания
+	tagsLine := ""
+	if len(note.Tags) > 0 {
+		tagsLine = "\n🏷️ " + formatTags(noteTagNames(note))
+	}
 
 	switch note.Type {
 	case models.NoteTypeText:
 		// Отправляем полный текст без обрезания
-		text = fmt.Sprintf("%s **Текстовая заметка**\n📂 Категория: %s\n📅 %s\n\n%s",
-			emoji, note.Category.Name, note.CreatedAt.Format("02.01.2006 15:04"), note.Content)
-		h.sendLongMessage(chatID, text)
+		text = fmt.Sprintf("%s **Текстовая заметка**\n📂 Категория: %s\n📅 %s%s\n\n%s",
+			emoji, note.Category.Name, note.CreatedAt.Format("02.01.2006 15:04"), tagsLine, note.Content)
+		h.sendLongMessage(chatID, text, &actions)
 
 	case models.NoteTypePhoto:
-		text = fmt.Sprintf("%s **Фото заметка**\n📂 Категория: %s\n📅 %s",
-			emoji, note.Category.Name, note.CreatedAt.Format("02.01.2006 15:04"))
+		text = fmt.Sprintf("%s **Фото заметка**\n📂 Категория: %s\n📅 %s%s",
+			emoji, note.Category.Name, note.CreatedAt.Format("02.01.2006 15:04"), tagsLine)
 		if note.Caption != "" {
 			text += fmt.Sprintf("\n📝 Подпись: %s", note.Caption)
 		}
 		// Отправляем фото
-		h.sendMediaMessage(chatID, note.FileID, "photo", text)
+		h.sendMediaMessage(chatID, note.FileID, "photo", text, &actions)
 
 	case models.NoteTypeVideo:
-		text = fmt.Sprintf("%s **Видео заметка**\n📂 Категория: %s\n📅 %s",
-			emoji, note.Category.Name, note.CreatedAt.Format("02.01.2006 15:04"))
+		text = fmt.Sprintf("%s **Видео заметка**\n📂 Категория: %s\n📅 %s%s",
+			emoji, note.Category.Name, note.CreatedAt.Format("02.01.2006 15:04"), tagsLine)
 		if note.Caption != "" {
 			text += fmt.Sprintf("\n📝 Подпись: %s", note.Caption)
 		}
 		// Отправляем видео
-		h.sendMediaMessage(chatID, note.FileID, "video", text)
+		h.sendMediaMessage(chatID, note.FileID, "video", text, &actions)
 
 	case models.NoteTypeVoice:
-		text = fmt.Sprintf("%s **Голосовая заметка**\n📂 Категория: %s\n📅 %s",
-			emoji, note.Category.Name, note.CreatedAt.Format("02.01.2006 15:04"))
+		text = fmt.Sprintf("%s **Голосовая заметка**\n📂 Категория: %s\n📅 %s%s",
+			emoji, note.Category.Name, note.CreatedAt.Format("02.01.2006 15:04"), tagsLine)
 		// Отправляем голосовое сообщение
-		h.sendMediaMessage(chatID, note.FileID, "voice", text)
+		h.sendMediaMessage(chatID, note.FileID, "voice", text, &actions)
 
 	case models.NoteTypeFile:
-		text = fmt.Sprintf("%s **Файл**\n📂 Категория: %s\n📅 %s",
-			emoji, note.Category.Name, note.CreatedAt.Format("02.01.2006 15:04"))
+		text = fmt.Sprintf("%s **Файл**\n📂 Категория: %s\n📅 %s%s",
+			emoji, note.Category.Name, note.CreatedAt.Format("02.01.2006 15:04"), tagsLine)
 		if note.Caption != "" {
 			text += fmt.Sprintf("\n📝 Подпись: %s", note.Caption)
 		}
-		h.sendLongMessage(chatID, text)
+		h.sendLongMessage(chatID, text, &actions)
 
 	default:
-		text = fmt.Sprintf("%s **%s заметка**\n📂 Категория: %s\n📅 %s",
-			emoji, strings.Title(string(note.Type)), note.Category.Name, note.CreatedAt.Format("02.01.2006 15:04"))
+		text = fmt.Sprintf("%s **%s заметка**\n📂 Категория: %s\n📅 %s%s",
+			emoji, strings.Title(string(note.Type)), note.Category.Name, note.CreatedAt.Format("02.01.2006 15:04"), tagsLine)
 		if note.Caption != "" {
 			text += fmt.Sprintf("\n📝 Подпись: %s", note.Caption)
 		}
-		h.sendLongMessage(chatID, text)
+		h.sendLongMessage(chatID, text, &actions)
 	}
 }
 
-// sendLongMessage отправляет длинное сообщение, разбивая его на части если нужно
-func (h *NotesHandler) sendLongMessage(chatID int64, text string) {
+// sendLongMessage отправляет длинное сообщение, разбивая его на части если нужно.
+// Inline-кнопки (если переданы) прикрепляются к последней части.
+func (h *NotesHandler) sendLongMessage(chatID int64, text string, inline *tgbotapi.InlineKeyboardMarkup) {
 	// Максимальная длина сообщения в Telegram
 	maxLength := 4096
 
 	if len(text) <= maxLength {
-		h.msgHandler.sendMessage(chatID, text, CreateNotesMenuKeyboard())
+		if inline != nil {
+			h.msgHandler.sendMessage(chatID, text, *inline)
+		} else {
+			h.msgHandler.sendMessage(chatID, text, CreateNotesMenuKeyboard())
+		}
 		return
 	}
 
@@ -394,11 +341,12 @@ func (h *NotesHandler) sendLongMessage(chatID int64, text string) {
 
 	// Отправляем остальные части без клавиатуры
 	for i := 1; i < len(parts); i++ {
-		h.msgHandler.sendMessage(chatID, parts[i], tgbotapi.NewRemoveKeyboard(true))
-		// Небольшая задержка между сообщениями
-		if i < len(parts)-1 {
-			time.Sleep(100 * time.Millisecond)
+		var markup interface{} = tgbotapi.NewRemoveKeyboard(true)
+		if inline != nil && i == len(parts)-1 {
+			markup = *inline
 		}
+		// Частоту отправки ограничивает очередь исходящих сообщений
+		h.msgHandler.sendMessage(chatID, parts[i], markup)
 	}
 }
 
@@ -436,50 +384,46 @@ func splitMessage(text string, maxLength int) []string {
 	return parts
 }
 
-// sendMediaMessage отправляет медиа-файл с подписью
-func (h *NotesHandler) sendMediaMessage(chatID int64, fileID, mediaType, caption string) {
+// sendMediaMessage отправляет медиа-файл с подписью и inline-кнопками
+func (h *NotesHandler) sendMediaMessage(chatID int64, fileID, mediaType, caption string, inline *tgbotapi.InlineKeyboardMarkup) {
+	var markup interface{}
+	if inline != nil {
+		markup = *inline
+	}
+
 	// Убираем ограничение длины подписи
 	// Telegram сам обрежет слишком длинные подписи
 
-	var err error
-	switch mediaType {
-	case "photo":
-		photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileID(fileID))
-		photo.Caption = caption
-		photo.ParseMode = "Markdown"
-		_, err = h.bot.Send(photo)
-
-	case "video":
-		video := tgbotapi.NewVideo(chatID, tgbotapi.FileID(fileID))
-		video.Caption = caption
-		video.ParseMode = "Markdown"
-		_, err = h.bot.Send(video)
-
-	case "voice":
-		voice := tgbotapi.NewVoice(chatID, tgbotapi.FileID(fileID))
-		voice.Caption = caption
-		voice.ParseMode = "Markdown"
-		_, err = h.bot.Send(voice)
-
+	kind := telegram.MediaKind(mediaType)
+	switch kind {
+	case telegram.MediaPhoto, telegram.MediaVideo, telegram.MediaVoice:
 	default:
 		log.Printf("Unsupported media type: %s", mediaType)
 		return
 	}
 
+	_, err := h.sender.SendMedia(telegram.Media{
+		ChatID:      chatID,
+		Kind:        kind,
+		FileID:      fileID,
+		Caption:     caption,
+		ParseMode:   tgbotapi.ModeMarkdown,
+		ReplyMarkup: markup,
+	})
 	if err != nil {
 		log.Printf("Error sending media message: %v", err)
 		// Если не удалось отправить медиа, отправляем текстовое описание
-		h.sendLongMessage(chatID, caption)
+		h.sendLongMessage(chatID, caption, inline)
 	}
 }
 
 // HandleDeleteCategory обрабатывает удаление категории
-func (h *NotesHandler) HandleDeleteCategory(chatID int64, categoryName string) {
+func (h *NotesHandler) HandleDeleteCategory(chatID int64, categoryName string) fsm.State {
 	categories, err := database.GetUserCategories(chatID)
 	if err != nil {
 		log.Printf("Error getting categories: %v", err)
 		h.msgHandler.sendMessage(chatID, "❌ Ошибка при загрузке категорий", CreateNotesMenuKeyboard())
-		return
+		return fsm.None
 	}
 
 	var categoryToDelete *models.Category
@@ -492,44 +436,42 @@ func (h *NotesHandler) HandleDeleteCategory(chatID int64, categoryName string) {
 
 	if categoryToDelete == nil {
 		h.msgHandler.sendMessage(chatID, "❌ Категория не найдена", CreateNotesMenuKeyboard())
-		return
+		return fsm.None
 	}
 
 	// Подтверждение удаления
 	notesCount, _ := database.GetNotesCountByCategory(chatID, categoryToDelete.ID)
-	text := fmt.Sprintf("⚠️ **Подтверждение удаления**\n\nКатегория: **%s**\nКоличество заметок: **%d**\n\nВсе заметки в этой категории будут удалены безвозвратно.\n\nПожалуйста, подтвердите удаление.",
+	text := fmt.Sprintf("⚠️ **Подтверждение удаления**\n\nКатегория: **%s**\nКоличество заметок: **%d**\n\nКатегория и все ее заметки будут перемещены в корзину.\n\nПожалуйста, подтвердите удаление.",
 		categoryToDelete.Name, notesCount)
 
 	// Используем клавиатуру подтверждения вместо обычной клавиатуры "Назад"
 	h.msgHandler.sendMessage(chatID, text, CreateConfirmationKeyboard())
-	h.storage.SetUserData(chatID, pmodel.UserData{Data: strconv.FormatUint(uint64(categoryToDelete.ID), 10)})
-	h.storage.SetUserState(chatID, StateDeletingCategory)
+	h.storage.SetSession(chatID, pmodel.Session{Purpose: pmodel.PurposeDeleteCategory, CategoryID: categoryToDelete.ID})
+	return StateDeletingCategory
 }
 
 // ConfirmDeleteCategory подтверждает удаление категории
-func (h *NotesHandler) ConfirmDeleteCategory(chatID int64, confirm bool) {
+func (h *NotesHandler) ConfirmDeleteCategory(chatID int64, confirm bool) fsm.State {
 	if !confirm {
 		h.msgHandler.sendMessage(chatID, "❌ Удаление отменено", CreateCategoriesManagementKeyboard())
-		h.storage.SetUserState(chatID, "")
-		return
+		return fsm.None
 	}
 
-	userData, _ := h.storage.GetUserData(chatID)
-	categoryID, err := strconv.ParseUint(userData.Data, 10, 32)
-	if err != nil {
-		log.Printf("Error parsing category ID: %v", err)
+	session, _ := h.storage.GetSession(chatID)
+	if session.CategoryID == 0 {
+		log.Printf("No category selected for deletion in chat %d", chatID)
 		h.msgHandler.sendMessage(chatID, "❌ Ошибка при удалении категории", CreateCategoriesManagementKeyboard())
-		return
+		return fsm.None
 	}
 
-	if err := database.DeleteCategory(chatID, uint(categoryID)); err != nil {
+	if err := database.DeleteCategory(chatID, session.CategoryID); err != nil {
 		log.Printf("Error deleting category: %v", err)
 		h.msgHandler.sendMessage(chatID, "❌ Ошибка при удалении категории", CreateCategoriesManagementKeyboard())
-		return
+		return fsm.None
 	}
 
-	h.msgHandler.sendMessage(chatID, "✅ Категория и все связанные заметки удалены", CreateCategoriesManagementKeyboard())
-	h.storage.SetUserState(chatID, "")
+	h.msgHandler.sendMessage(chatID, "✅ Категория и все связанные заметки перемещены в корзину", CreateCategoriesManagementKeyboard())
+	return fsm.None
 }
 
 // Вспомогательные функции
@@ -585,58 +527,47 @@ func (h *NotesHandler) SendEditCategoriesMenu(chatID int64) {
 	}
 
 	// Сохраняем цель выбора категории
-	h.storage.SetUserData(chatID, pmodel.UserData{Data: "edit_category"})
-	h.storage.SetUserState(chatID, StateWaitingForNoteCategory)
+	h.storage.SetSession(chatID, pmodel.Session{Purpose: pmodel.PurposeEditCategory})
+	h.storage.SetUserState(chatID, StateSelectingCategoryToEdit)
 
 	// Отправляем сообщение ПОСЛЕ установки состояния и данных
 	h.msgHandler.SendMessage(chatID, categoriesText.String(), CreateCategoriesKeyboard(categories))
 }
 
 // HandleEditCategory обрабатывает редактирование категории
-func (h *NotesHandler) HandleEditCategory(chatID int64, categoryName string) {
+func (h *NotesHandler) HandleEditCategory(chatID int64, categoryName string) fsm.State {
 	category, err := database.GetCategoryByName(chatID, categoryName)
 	if err != nil {
 		log.Printf("Error finding category: %v", err)
 		h.msgHandler.sendMessage(chatID, "❌ Категория не найдена", CreateCategoriesManagementKeyboard())
-		h.storage.SetUserState(chatID, "")
-		return
+		return fsm.None
 	}
 
 	// Сохраняем ID категории для редактирования
-	h.storage.SetUserData(chatID, pmodel.UserData{
-		Data: strconv.FormatUint(uint64(category.ID), 10),
-	})
+	h.storage.SetSession(chatID, pmodel.Session{Purpose: pmodel.PurposeEditCategory, CategoryID: category.ID})
 
 	text := fmt.Sprintf("✏️ **Редактирование категории**\n\n📂 Текущее название: **%s**\n🎨 Цвет: %s\n\nВведите новое название для категории:",
 		category.Name, category.Color)
 
 	h.msgHandler.sendMessage(chatID, text, CreateBackKeyboard())
-	h.storage.SetUserState(chatID, StateEditingCategory)
+	return StateEditingCategory
 }
 
 // HandleCategoryUpdate обрабатывает обновление названия категории
-func (h *NotesHandler) HandleCategoryUpdate(chatID int64, newName string) {
-	if strings.TrimSpace(newName) == "" {
-		h.msgHandler.sendMessage(chatID, "❌ Название категории не может быть пустым", CreateBackKeyboard())
-		return
-	}
-
-	userData, _ := h.storage.GetUserData(chatID)
-	categoryID, err := strconv.ParseUint(userData.Data, 10, 32)
-	if err != nil {
-		log.Printf("Error parsing category ID: %v", err)
+func (h *NotesHandler) HandleCategoryUpdate(chatID int64, newName string) fsm.State {
+	session, _ := h.storage.GetSession(chatID)
+	if session.CategoryID == 0 {
+		log.Printf("No category selected for editing in chat %d", chatID)
 		h.msgHandler.sendMessage(chatID, "❌ Ошибка при обновлении категории", CreateCategoriesManagementKeyboard())
-		h.storage.SetUserState(chatID, "")
-		return
+		return fsm.None
 	}
 
 	// Получаем текущую категорию
-	category, err := database.GetCategoryByID(chatID, uint(categoryID))
+	category, err := database.GetCategoryByID(chatID, session.CategoryID)
 	if err != nil {
 		log.Printf("Error getting category: %v", err)
 		h.msgHandler.sendMessage(chatID, "❌ Категория не найдена", CreateCategoriesManagementKeyboard())
-		h.storage.SetUserState(chatID, "")
-		return
+		return fsm.None
 	}
 
 	// Обновляем название
@@ -645,12 +576,11 @@ func (h *NotesHandler) HandleCategoryUpdate(chatID int64, newName string) {
 	if err := db.Save(category).Error; err != nil {
 		log.Printf("Error updating category: %v", err)
 		h.msgHandler.sendMessage(chatID, "❌ Ошибка при обновлении категории", CreateCategoriesManagementKeyboard())
-		h.storage.SetUserState(chatID, "")
-		return
+		return fsm.None
 	}
 
 	h.msgHandler.sendMessage(chatID, fmt.Sprintf("✅ Категория успешно переименована в \"%s\"", newName), CreateCategoriesManagementKeyboard())
-	h.storage.SetUserState(chatID, "")
+	return fsm.None
 }
 
 // SendNotesManagementMenu отправляет меню управления заметками
@@ -661,171 +591,83 @@ func (h *NotesHandler) SendNotesManagementMenu(chatID int64) {
 
 ✨ **Доступные действия:**
 • ✏️ Редактировать заметку - изменить содержание или категорию
-• 🗑️ Удалить заметку - безвозвратно удалить заметку`
+• 🗑️ Удалить заметку - переместить заметку в корзину`
 
 	h.msgHandler.sendMessage(chatID, text, CreateNotesManagementKeyboard())
 }
 
-// SendNotesForSelection отправляет список заметок для выбора
-func (h *NotesHandler) SendNotesForSelection(chatID int64, purpose string) {
-	notes, err := database.GetUserNotes(chatID, 0) // 0 - все категории
-	if err != nil {
-		log.Printf("Error getting notes: %v", err)
-		h.msgHandler.sendMessage(chatID, "❌ Ошибка при загрузке заметок", CreateNotesManagementKeyboard())
-		return
-	}
-
-	if len(notes) == 0 {
-		h.msgHandler.sendMessage(chatID, "❌ У вас пока нет заметок", CreateNotesManagementKeyboard())
+// SendNotesForSelection отправляет постраничный список заметок для выбора кнопками
+func (h *NotesHandler) SendNotesForSelection(chatID int64, purpose pmodel.Purpose) {
+	var mode string
+	switch purpose {
+	case pmodel.PurposeEditNote:
+		mode = noteListEdit
+	case pmodel.PurposeDeleteNote:
+		mode = noteListDelete
+	default:
+		log.Printf("Unknown note selection purpose: %s", purpose)
+		h.msgHandler.sendMessage(chatID, "❌ Неизвестная операция", CreateNotesManagementKeyboard())
 		return
 	}
 
-	// Сохраняем цель выбора заметки
-	h.storage.SetUserData(chatID, pmodel.UserData{Data: purpose})
-	h.storage.SetUserState(chatID, StateWaitingForNoteSelection)
-
-	// Отправляем список заметок
-	var notesText strings.Builder
-	notesText.WriteString("📋 **Выберите заметку:**\n\n")
-
-	for i, note := range notes {
-		if i >= 10 { // Ограничиваем показ 10 заметками
-			notesText.WriteString(fmt.Sprintf("\n... и еще %d заметок", len(notes)-10))
-			break
-		}
-
-		var preview string
-		switch note.Type {
-		case models.NoteTypeText:
-			if len(note.Content) > 50 {
-				preview = note.Content[:50] + "..."
-			} else {
-				preview = note.Content
-			}
-		case models.NoteTypePhoto:
-			preview = "🖼️ Фото"
-		case models.NoteTypeVideo:
-			preview = "🎥 Видео"
-		case models.NoteTypeVoice:
-			preview = "🎤 Голосовое сообщение"
-		case models.NoteTypeFile:
-			preview = "📎 Файл"
-		default:
-			preview = "📄 Заметка"
-		}
-
-		emoji := getNoteTypeEmoji(note.Type)
-		notesText.WriteString(fmt.Sprintf("%s `%d`: %s\n", emoji, note.ID, preview))
-	}
-
-	h.msgHandler.sendMessage(chatID, notesText.String(), CreateBackKeyboard())
+	h.sendNoteList(chatID, noteList{Mode: mode}, "❌ У вас пока нет заметок")
 }
 
-// HandleEditNoteSelection обрабатывает выбор заметки для редактирования
-func (h *NotesHandler) HandleEditNoteSelection(chatID int64, noteID uint) {
-	note, err := database.GetNoteByID(chatID, noteID)
-	if err != nil {
-		log.Printf("Error getting note: %v", err)
-		h.msgHandler.sendMessage(chatID, "❌ Заметка не найдена", CreateNotesManagementKeyboard())
-		h.storage.SetUserState(chatID, "")
-		return
+// HandleNoteContentUpdate обрабатывает обновление содержания заметки
+func (h *NotesHandler) HandleNoteContentUpdate(chatID int64, newContent string) fsm.State {
+	session, _ := h.storage.GetSession(chatID)
+	if session.NoteID == 0 {
+		log.Printf("No note selected for editing in chat %d", chatID)
+		h.msgHandler.sendMessage(chatID, "❌ Ошибка при обновлении заметки", CreateNotesManagementKeyboard())
+		return fsm.None
 	}
 
-	// Сохраняем ID заметки для редактирования
-	h.storage.SetUserData(chatID, pmodel.UserData{
-		Data: strconv.FormatUint(uint64(noteID), 10),
-	})
-
-	// Показываем информацию о заметке и действия
-	text := fmt.Sprintf("✏️ **Редактирование заметки**\n\n%s\n\n📂 Категория: %s\n📅 Создана: %s",
-		h.formatNoteContent(note), note.Category.Name, note.CreatedAt.Format("02.01.2006 15:04"))
-
-	h.msgHandler.sendMessage(chatID, text, CreateNoteActionsKeyboard())
-	h.storage.SetUserState(chatID, "")
-}
-
-// HandleDeleteNoteSelection обрабатывает выбор заметки для удаления
-func (h *NotesHandler) HandleDeleteNoteSelection(chatID int64, noteID uint) {
-	note, err := database.GetNoteByID(chatID, noteID)
-	if err != nil {
-		log.Printf("Error getting note: %v", err)
+	// Обновляем содержание, предыдущий текст сохраняется в истории
+	note, err := database.UpdateNoteContent(chatID, session.NoteID, newContent)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		h.msgHandler.sendMessage(chatID, "❌ Заметка не найдена", CreateNotesManagementKeyboard())
-		h.storage.SetUserState(chatID, "")
-		return
-	}
-
-	// Сохраняем ID заметки для удаления
-	h.storage.SetUserData(chatID, pmodel.UserData{
-		Data: strconv.FormatUint(uint64(noteID), 10),
-	})
-
-	// Подтверждение удаления
-	text := fmt.Sprintf("⚠️ **Подтверждение удаления**\n\n%s\n\n📂 Категория: %s\n📅 Создана: %s\n\nЗаметка будет удалена безвозвратно.\n\nПожалуйста, подтвердите удаление.",
-		h.formatNoteContent(note), note.Category.Name, note.CreatedAt.Format("02.01.2006 15:04"))
-
-	h.msgHandler.sendMessage(chatID, text, CreateConfirmationKeyboard())
-	h.storage.SetUserState(chatID, StateDeletingNote)
-}
-
-// ConfirmDeleteNote подтверждает удаление заметки
-func (h *NotesHandler) ConfirmDeleteNote(chatID int64, confirm bool) {
-	if !confirm {
-		h.msgHandler.sendMessage(chatID, "❌ Удаление отменено", CreateNotesManagementKeyboard())
-		h.storage.SetUserState(chatID, "")
-		return
+		return fsm.None
 	}
-
-	userData, _ := h.storage.GetUserData(chatID)
-	noteID, err := strconv.ParseUint(userData.Data, 10, 32)
 	if err != nil {
-		log.Printf("Error parsing note ID: %v", err)
-		h.msgHandler.sendMessage(chatID, "❌ Ошибка при удалении заметки", CreateNotesManagementKeyboard())
-		h.storage.SetUserState(chatID, "")
-		return
+		log.Printf("Error updating note: %v", err)
+		h.msgHandler.sendMessage(chatID, "❌ Ошибка при обновлении заметки", CreateNotesManagementKeyboard())
+		return fsm.None
 	}
 
-	if err := database.DeleteNote(chatID, uint(noteID)); err != nil {
-		log.Printf("Error deleting note: %v", err)
-		h.msgHandler.sendMessage(chatID, "❌ Ошибка при удалении заметки", CreateNotesManagementKeyboard())
-		h.storage.SetUserState(chatID, "")
-		return
+	// Теги пересчитываются по новому тексту
+	text := "✅ Заметка успешно обновлена"
+	if tags := h.applyHashtags(chatID, note); len(tags) > 0 {
+		text += "\n🏷️ Теги: " + formatTags(tags)
 	}
-
-	h.msgHandler.sendMessage(chatID, "✅ Заметка успешно удалена", CreateNotesManagementKeyboard())
-	h.storage.SetUserState(chatID, "")
+	h.msgHandler.sendMessage(chatID, text, CreateNotesManagementKeyboard())
+	return fsm.None
 }
 
-// HandleNoteContentUpdate обрабатывает обновление содержания заметки
-func (h *NotesHandler) HandleNoteContentUpdate(chatID int64, newContent string) {
-	userData, _ := h.storage.GetUserData(chatID)
-	noteID, err := strconv.ParseUint(userData.Data, 10, 32)
-	if err != nil {
-		log.Printf("Error parsing note ID: %v", err)
-		h.msgHandler.sendMessage(chatID, "❌ Ошибка при обновлении заметки", CreateNotesManagementKeyboard())
-		h.storage.SetUserState(chatID, "")
-		return
-	}
-
-	note, err := database.GetNoteByID(chatID, uint(noteID))
-	if err != nil {
-		log.Printf("Error getting note: %v", err)
-		h.msgHandler.sendMessage(chatID, "❌ Заметка не найдена", CreateNotesManagementKeyboard())
-		h.storage.SetUserState(chatID, "")
-		return
+// notePreview возвращает короткое описание заметки для списков
+func notePreview(note models.Note, maxRunes int) string {
+	switch note.Type {
+	case models.NoteTypeText:
+		return truncateRunes(note.Content, maxRunes)
+	case models.NoteTypePhoto:
+		return "Фото " + truncateRunes(note.Caption, maxRunes)
+	case models.NoteTypeVideo:
+		return "Видео " + truncateRunes(note.Caption, maxRunes)
+	case models.NoteTypeVoice:
+		return "Голосовое сообщение"
+	case models.NoteTypeFile:
+		return "Файл " + truncateRunes(note.Caption, maxRunes)
+	default:
+		return "Заметка"
 	}
+}
 
-	// Обновляем содержание
-	note.Content = newContent
-	db := database.GetConnect()
-	if err := db.Save(note).Error; err != nil {
-		log.Printf("Error updating note: %v", err)
-		h.msgHandler.sendMessage(chatID, "❌ Ошибка при обновлении заметки", CreateNotesManagementKeyboard())
-		h.storage.SetUserState(chatID, "")
-		return
+// truncateRunes обрезает строку по символам, а не по байтам
+func truncateRunes(text string, maxRunes int) string {
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) <= maxRunes {
+		return string(runes)
 	}
-
-	h.msgHandler.sendMessage(chatID, "✅ Заметка успешно обновлена", CreateNotesManagementKeyboard())
-	h.storage.SetUserState(chatID, "")
+	return string(runes[:maxRunes]) + "..."
 }
 
 // formatNoteContent форматирует содержание заметки для отображения
@@ -859,28 +701,32 @@ func (h *NotesHandler) formatNoteContent(note *models.Note) string {
 }
 
 // SaveForwardedMessage сохраняет пересланное сообщение в выбранной категории
-func (h *NotesHandler) SaveForwardedMessage(chatID int64, categoryName string, userData pmodel.UserData) {
+func (h *NotesHandler) SaveForwardedMessage(chatID int64, categoryName string, session pmodel.Session) fsm.State {
+	draft := session.Draft
+	if draft == nil {
+		log.Printf("No note draft found for chat %d", chatID)
+		h.msgHandler.sendMessage(chatID, "❌ Сессия истекла, начните заново", CreateMainMenuKeyboard())
+		return fsm.None
+	}
+
 	// Находим категорию
 	category, err := database.GetCategoryByName(chatID, categoryName)
 	if err != nil {
 		log.Printf("Error finding category: %v", err)
 		h.msgHandler.sendMessage(chatID, "❌ Категория не найдена", CreateMainMenuKeyboard())
-		h.storage.SetUserState(chatID, "")
-		return
+		return fsm.None
 	}
 
-	// Преобразуем строковый тип в models.NoteType
+	// Преобразуем тип черновика в models.NoteType
 	var noteType models.NoteType
-	switch userData.Name {
-	case "text":
-		noteType = models.NoteTypeText
-	case "photo":
+	switch draft.Type {
+	case pmodel.NoteTypePhoto:
 		noteType = models.NoteTypePhoto
-	case "video":
+	case pmodel.NoteTypeVideo:
 		noteType = models.NoteTypeVideo
-	case "voice":
+	case pmodel.NoteTypeVoice:
 		noteType = models.NoteTypeVoice
-	case "file":
+	case pmodel.NoteTypeFile:
 		noteType = models.NoteTypeFile
 	default:
 		noteType = models.NoteTypeText // значение по умолчанию
@@ -891,16 +737,16 @@ func (h *NotesHandler) SaveForwardedMessage(chatID int64, categoryName string, u
 		TelegramID: chatID,
 		CategoryID: category.ID,
 		Type:       noteType,
-		FileID:     userData.Category, // FileID для медиа
+		FileID:     draft.FileID,
 	}
 
 	// Для текстовых заметок используем Content, для медиа - Caption
 	if noteType == models.NoteTypeText {
-		note.Content = userData.City
+		note.Content = draft.Content
 	} else {
-		note.Caption = userData.City
+		note.Caption = draft.Content
 		// Для медиа также сохраняем текст в Content для поиска
-		note.Content = userData.City
+		note.Content = draft.Content
 	}
 
 	log.Printf("Creating note: Type=%s, Content=%s, FileID=%s, CategoryID=%d",
@@ -909,14 +755,17 @@ func (h *NotesHandler) SaveForwardedMessage(chatID int64, categoryName string, u
 	if err := database.CreateNote(note); err != nil {
 		log.Printf("Error creating note from forwarded message: %v", err)
 		h.msgHandler.sendMessage(chatID, "❌ Ошибка при сохранении заметки: "+err.Error(), CreateMainMenuKeyboard())
-		h.storage.SetUserState(chatID, "")
-		return
+		return fsm.None
 	}
 
 	// Формируем сообщение об успехе
 	successMsg := h.createSuccessMessage(note, category.Name)
+	if tags := h.applyHashtags(chatID, note); len(tags) > 0 {
+		successMsg += "\n\n🏷️ Теги: " + formatTags(tags)
+	}
 	h.msgHandler.sendMessage(chatID, successMsg, CreateMainMenuKeyboard())
-	h.storage.SetUserState(chatID, "")
+	h.storage.SetSession(chatID, pmodel.Session{})
+	return fsm.None
 }
 
 func (h *NotesHandler) createSuccessMessage(note *models.Note, categoryName string) string {
@@ -963,17 +812,13 @@ func (h *NotesHandler) createSuccessMessage(note *models.Note, categoryName stri
 func (h *NotesHandler) sendMediaPreview(chatID int64, fileID string, mediaType string) {
 	switch mediaType {
 	case "photo":
-		photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileID(fileID))
-		photo.Caption = "📸 Сохраненное фото"
-		_, err := h.bot.Send(photo)
-		if err != nil {
+		media := telegram.Media{ChatID: chatID, Kind: telegram.MediaPhoto, FileID: fileID, Caption: "📸 Сохраненное фото"}
+		if _, err := h.sender.SendMedia(media); err != nil {
 			log.Printf("Error sending photo preview: %v", err)
 		}
 	case "video":
-		video := tgbotapi.NewVideo(chatID, tgbotapi.FileID(fileID))
-		video.Caption = "🎥 Сохраненное видео"
-		_, err := h.bot.Send(video)
-		if err != nil {
+		media := telegram.Media{ChatID: chatID, Kind: telegram.MediaVideo, FileID: fileID, Caption: "🎥 Сохраненное видео"}
+		if _, err := h.sender.SendMedia(media); err != nil {
 			log.Printf("Error sending video preview: %v", err)
 		}
 	}
@@ -0,0 +1,174 @@
+package bot
+
+import (
+	"GreenAssistantBot/internal/database"
+	"GreenAssistantBot/internal/database/models"
+	"GreenAssistantBot/internal/fsm"
+	pmodel "GreenAssistantBot/pkg/models"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+)
+
+// Действия inline-кнопок напоминаний
+const (
+	cbNoteRemind     = "nr"
+	cbReminderSnooze = "rz"
+	cbReminderDone   = "rd"
+	cbReminderCancel = "rx"
+)
+
+// reminderSnoozes — варианты отложить напоминание. Expr разбирается тем же
+// parseReminderTime, что и ввод пользователя.
+var reminderSnoozes = []struct {
+	Arg, Label, Expr string
+}{
+	{"15", "💤 15 мин", "через 15 минут"},
+	{"60", "💤 1 час", "через 1 час"},
+	{"tm", "💤 Завтра", "завтра в 9"},
+}
+
+const reminderTimeHelp = `Примеры:
+• через 30 минут, через 2 часа, через 3 дня
+• сегодня в 18:00, завтра в 9, послезавтра
+• в пятницу в 10, в 9 вечера
+• 25.12 10:00, 25.12.2026`
+
+// RegisterReminderCallbacks регистрирует обработчики кнопок напоминаний
+func (h *NotesHandler) RegisterReminderCallbacks(router *callbackRouter) {
+	router.Register(cbNoteRemind, h.onNoteRemind)
+	router.Register(cbReminderSnooze, h.onReminderSnooze)
+	router.Register(cbReminderDone, h.onReminderDone)
+	router.Register(cbReminderCancel, h.onReminderCancel)
+}
+
+// onNoteRemind запрашивает время напоминания о заметке
+func (h *NotesHandler) onNoteRemind(query *tgbotapi.CallbackQuery, args []string) string {
+	chatID := query.Message.Chat.ID
+	noteID, ok := callbackArgID(args, 0)
+	if !ok {
+		return "⚠️ Неверная кнопка"
+	}
+
+	if _, err := database.GetNoteByID(chatID, noteID); err != nil {
+		log.Printf("Error getting note: %v", err)
+		return "❌ Заметка не найдена"
+	}
+
+	h.storage.SetSession(chatID, pmodel.Session{Purpose: pmodel.PurposeSetReminder, NoteID: noteID})
+	h.storage.SetUserState(chatID, StateWaitingForReminderTime)
+
+	h.msgHandler.sendMessage(chatID, "⏰ Когда напомнить о заметке?\n\n"+reminderTimeHelp, CreateBackKeyboard())
+	return ""
+}
+
+// HandleReminderTime создает напоминание на введенное пользователем время
+func (h *NotesHandler) HandleReminderTime(chatID int64, text string) fsm.State {
+	session, _ := h.storage.GetSession(chatID)
+	if session.NoteID == 0 {
+		h.msgHandler.sendMessage(chatID, "❌ Сессия истекла, начните заново", CreateNotesMenuKeyboard())
+		return fsm.None
+	}
+
+	remindAt, ok := parseReminderTime(text, userNow(chatID))
+	if !ok {
+		h.msgHandler.sendMessage(chatID, "❌ Не удалось распознать время\n\n"+reminderTimeHelp, CreateBackKeyboard())
+		return StateWaitingForReminderTime
+	}
+
+	reminder, err := database.CreateReminder(chatID, session.NoteID, remindAt)
+	if err != nil {
+		log.Printf("Error creating reminder: %v", err)
+		h.msgHandler.sendMessage(chatID, "❌ Ошибка при создании напоминания", CreateNotesMenuKeyboard())
+		return fsm.None
+	}
+
+	h.msgHandler.sendMessage(chatID, "✅ Напоминание сохранено", CreateNotesMenuKeyboard())
+	h.msgHandler.sendMessage(chatID, fmt.Sprintf("⏰ Напомню %s", formatReminderTime(reminder.RemindAt)),
+		CreateReminderCancelKeyboard(h.msgHandler.callbacks, reminder.ID))
+	return fsm.None
+}
+
+// SendReminder отправляет пользователю наступившее напоминание о заметке
+func (h *MessageHandler) SendReminder(reminder models.Reminder) error {
+	note := reminder.Note
+	text := fmt.Sprintf("⏰ Напоминание\n\n%s %s\n\n📂 Категория: %s",
+		getNoteTypeEmoji(note.Type), notePreview(note, 300), note.Category.Name)
+
+	return h.sendMessage(reminder.TelegramID, text, CreateReminderKeyboard(h.callbacks, reminder))
+}
+
+// onReminderSnooze откладывает напоминание
+func (h *NotesHandler) onReminderSnooze(query *tgbotapi.CallbackQuery, args []string) string {
+	chatID := query.Message.Chat.ID
+	reminderID, ok := callbackArgID(args, 0)
+	if !ok || len(args) < 2 {
+		return "⚠️ Неверная кнопка"
+	}
+
+	for _, snooze := range reminderSnoozes {
+		if snooze.Arg != args[1] {
+			continue
+		}
+
+		until, _ := parseReminderTime(snooze.Expr, userNow(chatID))
+		if err := database.SnoozeReminder(chatID, reminderID, until); errors.Is(err, gorm.ErrRecordNotFound) {
+			return "❌ Напоминание не найдено или уже выполнено"
+		} else if err != nil {
+			log.Printf("Error snoozing reminder: %v", err)
+			return "❌ Не удалось отложить напоминание"
+		}
+
+		h.editMessageText(query.Message, fmt.Sprintf("%s\n\n💤 Отложено, напомню %s", query.Message.Text, formatReminderTime(until)))
+		return "💤 Напоминание отложено"
+	}
+
+	return "⚠️ Неверная кнопка"
+}
+
+// onReminderDone закрывает доставленное напоминание
+func (h *NotesHandler) onReminderDone(query *tgbotapi.CallbackQuery, args []string) string {
+	if toast := h.completeReminder(query, args); toast != "" {
+		return toast
+	}
+
+	h.editMessageText(query.Message, query.Message.Text+"\n\n✅ Готово")
+	return "✅ Готово"
+}
+
+// onReminderCancel отменяет напоминание до его отправки
+func (h *NotesHandler) onReminderCancel(query *tgbotapi.CallbackQuery, args []string) string {
+	if toast := h.completeReminder(query, args); toast != "" {
+		return toast
+	}
+
+	h.editMessageText(query.Message, "🔕 Напоминание отменено")
+	return ""
+}
+
+// completeReminder закрывает напоминание и возвращает текст ошибки, если не вышло
+func (h *NotesHandler) completeReminder(query *tgbotapi.CallbackQuery, args []string) string {
+	reminderID, ok := callbackArgID(args, 0)
+	if !ok {
+		return "⚠️ Неверная кнопка"
+	}
+
+	err := database.CompleteReminder(query.Message.Chat.ID, reminderID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return "❌ Напоминание не найдено"
+	case err != nil:
+		log.Printf("Error completing reminder: %v", err)
+		return "❌ Ошибка при обновлении напоминания"
+	}
+	return ""
+}
+
+// formatReminderTime возвращает время напоминания в виде "25.12.2026 в 09:00"
+func formatReminderTime(t time.Time) string {
+	return t.Format("02.01.2006 в 15:04")
+}
@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"GreenAssistantBot/internal/database"
+	"GreenAssistantBot/internal/database/models"
+	"GreenAssistantBot/internal/storage"
+	"GreenAssistantBot/internal/telegram/telegramtest"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func newRecordingHandler(t *testing.T) (*MessageHandler, *telegramtest.Recorder, storage.BotStorage) {
+	t.Helper()
+	t.Setenv("CALLBACK_SECRET", "test-secret")
+
+	store, err := storage.NewMemoryStorage()
+	if err != nil {
+		t.Fatalf("create storage: %v", err)
+	}
+	recorder := telegramtest.NewRecorder()
+	return NewMessageHandler(recorder, store, nil), recorder, store
+}
+
+func TestSendReminder(t *testing.T) {
+	h, recorder, store := newRecordingHandler(t)
+	reminder := models.Reminder{
+		ID:         3,
+		NoteID:     9,
+		TelegramID: 42,
+		Note: models.Note{
+			Type:     models.NoteTypeText,
+			Content:  "Позвонить маме",
+			Category: models.Category{Name: "Дела"},
+		},
+	}
+
+	if err := h.SendReminder(reminder); err != nil {
+		t.Fatalf("SendReminder: %v", err)
+	}
+
+	sent := recorder.Take()
+	if len(sent) != 1 || sent[0].Kind != telegramtest.SentText || sent[0].ChatID != 42 {
+		t.Fatalf("expected one text message to chat 42, got %+v", sent)
+	}
+	for _, want := range []string{"⏰ Напоминание", "Позвонить маме", "Категория: Дела"} {
+		if !strings.Contains(sent[0].Text, want) {
+			t.Errorf("reminder text %q does not contain %q", sent[0].Text, want)
+		}
+	}
+
+	keyboard, ok := sent[0].ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+	if !ok {
+		t.Fatalf("expected inline keyboard, got %T", sent[0].ReplyMarkup)
+	}
+	done := keyboard.InlineKeyboard[len(keyboard.InlineKeyboard)-1][1]
+	action, args, err := h.callbacks.Decode(*done.CallbackData)
+	if err != nil || action != cbReminderDone || len(args) != 1 || args[0] != "3" {
+		t.Errorf("unexpected done button: action=%q args=%v err=%v", action, args, err)
+	}
+
+	if id, ok := store.GetLastMessageID(42); !ok || id != sent[0].MessageID {
+		t.Errorf("expected last message ID %d, got %d", sent[0].MessageID, id)
+	}
+}
+
+func TestSendReminderError(t *testing.T) {
+	h, recorder, store := newRecordingHandler(t)
+	recorder.Err = errors.New("blocked by user")
+
+	if err := h.SendReminder(models.Reminder{TelegramID: 42}); err == nil {
+		t.Fatal("expected send error")
+	}
+	if _, ok := store.GetLastMessageID(42); ok {
+		t.Error("last message ID must not change when sending fails")
+	}
+}
+
+// Старая кнопка «Отложить» не возвращает выполненное напоминание
+func TestSnoozeCompletedReminder(t *testing.T) {
+	e := newE2E(t)
+
+	category, err := database.CreateCategory(testChatID, "Дела", "")
+	if err != nil {
+		t.Fatalf("create category: %v", err)
+	}
+	note := &models.Note{TelegramID: testChatID, CategoryID: category.ID, Type: models.NoteTypeText, Content: "Позвонить маме"}
+	if err := database.CreateNote(note); err != nil {
+		t.Fatalf("create note: %v", err)
+	}
+	reminder, err := database.CreateReminder(testChatID, note.ID, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("create reminder: %v", err)
+	}
+
+	if err := e.handler.msgHandler.SendReminder(*reminder); err != nil {
+		t.Fatalf("SendReminder: %v", err)
+	}
+	message := e.expectReply(e.server.TakeCalls(), "sendMessage", "Напоминание")
+
+	if answer := e.expectCallbackAnswer(e.press(message, "✅ Готово")); answer != "✅ Готово" {
+		t.Fatalf("unexpected done answer %q", answer)
+	}
+	if answer := e.expectCallbackAnswer(e.press(message, "💤 15 мин")); !strings.Contains(answer, "уже выполнено") {
+		t.Errorf("snoozing a completed reminder must be rejected, got %q", answer)
+	}
+
+	due, err := database.GetDueReminders(time.Now().Add(time.Hour), 10)
+	if err != nil {
+		t.Fatalf("get due reminders: %v", err)
+	}
+	if len(due) != 0 {
+		t.Errorf("completed reminder is due again: %d reminders", len(due))
+	}
+}
@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"GreenAssistantBot/internal/database"
+	"GreenAssistantBot/internal/fsm"
+	pmodel "GreenAssistantBot/pkg/models"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Сколько символов показывать вокруг найденного слова
+const searchSnippetRadius = 40
+
+// SearchNotes ищет заметки по запросу или, если запрос пуст, просит его ввести
+func (h *NotesHandler) SearchNotes(chatID int64, query string) {
+	if strings.TrimSpace(query) == "" {
+		h.AskForSearchQuery(chatID)
+		return
+	}
+	h.HandleSearchQuery(chatID, query)
+}
+
+// AskForSearchQuery запрашивает текст для поиска
+func (h *NotesHandler) AskForSearchQuery(chatID int64) {
+	h.msgHandler.sendMessage(chatID, "🔍 Введите слово или фразу для поиска по заметкам:", CreateBackKeyboard())
+	h.storage.SetUserState(chatID, StateWaitingForSearchQuery)
+}
+
+// HandleSearchQuery показывает результаты поиска постраничным списком
+func (h *NotesHandler) HandleSearchQuery(chatID int64, query string) fsm.State {
+	query = strings.TrimSpace(query)
+	if len(database.SearchTerms(query)) == 0 {
+		h.msgHandler.sendMessage(chatID, "❌ Запрос должен содержать буквы или цифры", CreateNotesMenuKeyboard())
+		return fsm.None
+	}
+
+	// Запрос нужен для листания страниц результатов
+	h.storage.SetSession(chatID, pmodel.Session{Purpose: pmodel.PurposeSearchNotes, Query: query})
+
+	h.sendNoteList(chatID, noteList{Mode: noteListSearch, Query: query},
+		fmt.Sprintf("🔍 По запросу «%s» ничего не найдено", query))
+	return fsm.None
+}
+
+// searchSnippet вырезает из текста фрагмент вокруг первого найденного слова
+// и выделяет совпадение жирным. Результат — экранированный HTML.
+func searchSnippet(text string, terms []string, radius int) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+
+	// Посимвольный нижний регистр сохраняет позиции символов
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	lowerText := string(lower)
+
+	start, length := -1, 0
+	for _, term := range terms {
+		index := strings.Index(lowerText, term)
+		if index == -1 {
+			continue
+		}
+		position := utf8.RuneCountInString(lowerText[:index])
+		if start == -1 || position < start {
+			start, length = position, utf8.RuneCountInString(term)
+		}
+	}
+
+	if start == -1 {
+		return html.EscapeString(truncateRunes(string(runes), 2*radius))
+	}
+
+	from := max(0, start-radius)
+	to := min(len(runes), start+length+radius)
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	b.WriteString(html.EscapeString(string(runes[from:start])))
+	b.WriteString("<b>")
+	b.WriteString(html.EscapeString(string(runes[start : start+length])))
+	b.WriteString("</b>")
+	b.WriteString(html.EscapeString(string(runes[start+length : to])))
+	if to < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
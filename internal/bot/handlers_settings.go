@@ -0,0 +1,201 @@
+package bot
+
+import (
+	"GreenAssistantBot/internal/database"
+	"GreenAssistantBot/internal/fsm"
+	"GreenAssistantBot/internal/weather"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Кнопка определения часового пояса по городу из профиля
+	timezoneFromCityButton = "🔄 По городу"
+	// Кнопка отправки местоположения вместо названия города
+	locationButton = "📍 Отправить местоположение"
+)
+
+// timezoneChoices — часовые пояса России для быстрого выбора кнопками
+var timezoneChoices = []struct {
+	Label, Zone string
+}{
+	{"Калининград (UTC+2)", "Europe/Kaliningrad"},
+	{"Москва (UTC+3)", "Europe/Moscow"},
+	{"Самара (UTC+4)", "Europe/Samara"},
+	{"Екатеринбург (UTC+5)", "Asia/Yekaterinburg"},
+	{"Омск (UTC+6)", "Asia/Omsk"},
+	{"Новосибирск (UTC+7)", "Asia/Novosibirsk"},
+	{"Иркутск (UTC+8)", "Asia/Irkutsk"},
+	{"Якутск (UTC+9)", "Asia/Yakutsk"},
+	{"Владивосток (UTC+10)", "Asia/Vladivostok"},
+	{"Магадан (UTC+11)", "Asia/Magadan"},
+	{"Камчатка (UTC+12)", "Asia/Kamchatka"},
+}
+
+// "+3", "UTC+10", "gmt-5"
+var utcOffsetPattern = regexp.MustCompile(`^(?:utc|gmt)?\s*([+-]\d{1,2})(?::?00)?$`)
+
+// AskForTimezone запрашивает часовой пояс пользователя
+func (h *MessageHandler) AskForTimezone(chatID int64) {
+	current := "не задан (время сервера)"
+	if user, err := database.GetUserByTelegramID(chatID); err == nil && user.Timezone != "" {
+		current = user.Timezone
+	}
+
+	text := fmt.Sprintf("🕒 Текущий часовой пояс: %s\n\nВыберите часовой пояс кнопкой, введите его название (например, Europe/Moscow) или смещение от UTC (например, +3). Кнопка «%s» определит пояс по вашему городу.",
+		current, timezoneFromCityButton)
+	h.sendMessage(chatID, text, CreateTimezoneKeyboard())
+	h.storage.SetUserState(chatID, StateWaitingForTimezone)
+}
+
+// HandleTimezone сохраняет выбранный часовой пояс
+func (h *MessageHandler) HandleTimezone(chatID int64, text string) fsm.State {
+	var zone string
+	if text == timezoneFromCityButton {
+		user, err := database.GetUserByTelegramID(chatID)
+		if err != nil || user.City == "" {
+			h.sendMessage(chatID, "❌ Сначала укажите город в профиле", CreateTimezoneKeyboard())
+			return StateWaitingForTimezone
+		}
+
+		place, err := h.userPlace(user)
+		if err == nil {
+			zone, err = h.timezoneForPlace(place)
+		}
+		if err != nil {
+			log.Printf("Error detecting timezone for %s: %v", user.City, err)
+			h.sendMessage(chatID, "❌ Не удалось определить часовой пояс по городу, выберите его вручную", CreateTimezoneKeyboard())
+			return StateWaitingForTimezone
+		}
+	} else {
+		zone, _ = parseTimezone(text)
+	}
+
+	if err := database.SetUserTimezone(chatID, zone); err != nil {
+		log.Printf("Error saving timezone: %v", err)
+		h.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.", CreateSettingsMenuKeyboard())
+		return fsm.None
+	}
+
+	location, _ := time.LoadLocation(zone)
+	h.sendMessage(chatID, fmt.Sprintf("✅ Часовой пояс: %s\n🕒 Местное время: %s",
+		zone, time.Now().In(location).Format("15:04")), CreateSettingsMenuKeyboard())
+	return fsm.None
+}
+
+// AskForNotificationTime запрашивает время уведомления о погоде
+func (h *MessageHandler) AskForNotificationTime(chatID int64) {
+	current := database.DefaultNotificationTime()
+	if user, err := database.GetUserByTelegramID(chatID); err == nil {
+		current = database.UserNotificationTime(user)
+	}
+
+	h.sendMessage(chatID, fmt.Sprintf("⏰ Сейчас уведомление о погоде приходит в %s.\n\nВыберите или введите новое время в формате ЧЧ:ММ:", current),
+		CreateNotificationTimeKeyboard())
+	h.storage.SetUserState(chatID, StateWaitingForNotificationTime)
+}
+
+// HandleNotificationTime сохраняет время уведомления о погоде
+func (h *MessageHandler) HandleNotificationTime(chatID int64, text string) fsm.State {
+	clock, _ := parseClock(text)
+	if err := database.SetUserNotificationTime(chatID, clock); err != nil {
+		log.Printf("Error saving notification time: %v", err)
+		h.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.", CreateSettingsMenuKeyboard())
+		return fsm.None
+	}
+
+	h.sendMessage(chatID, fmt.Sprintf("✅ Уведомление о погоде будет приходить в %s по вашему времени", clock), CreateSettingsMenuKeyboard())
+	return fsm.None
+}
+
+// userNow возвращает текущее время в часовом поясе пользователя
+func userNow(chatID int64) time.Time {
+	if user, err := database.GetUserByTelegramID(chatID); err == nil {
+		return time.Now().In(user.Location())
+	}
+	return time.Now()
+}
+
+// updateTimezoneFromPlace определяет часовой пояс по новому городу пользователя
+func (h *MessageHandler) updateTimezoneFromPlace(chatID int64, place weather.Place) {
+	zone, err := h.timezoneForPlace(place)
+	if err != nil {
+		log.Printf("Error detecting timezone for %s: %v", place.Label(), err)
+		return
+	}
+
+	if err := database.SetUserTimezone(chatID, zone); err != nil {
+		log.Printf("Error saving timezone: %v", err)
+	}
+}
+
+// timezoneForPlace определяет часовой пояс места: берет IANA-имя от геокодера, по
+// координатам или из ответа о погоде. Зона по смещению от UTC не учитывает летнее
+// время и подбирается, только если ничего из этого не сработало.
+func (h *MessageHandler) timezoneForPlace(place weather.Place) (string, error) {
+	if zone := h.weather.PlaceZone(place); zone != "" {
+		return zone, nil
+	}
+
+	weatherData, err := h.weather.GetWeatherDataByCoords(place.Lat, place.Lon, weather.DefaultPreferences())
+	if err != nil {
+		return "", err
+	}
+
+	if weatherData.Zone != "" {
+		if _, err := time.LoadLocation(weatherData.Zone); err == nil {
+			return weatherData.Zone, nil
+		}
+	}
+
+	zone := weather.ZoneForOffset(weatherData.Timezone)
+	if zone == "" {
+		return "", fmt.Errorf("unsupported UTC offset %d", weatherData.Timezone)
+	}
+	return zone, nil
+}
+
+// parseTimezone принимает кнопку быстрого выбора, IANA-имя или смещение от UTC
+func parseTimezone(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	for _, choice := range timezoneChoices {
+		if text == choice.Label {
+			return choice.Zone, true
+		}
+	}
+
+	if m := utcOffsetPattern.FindStringSubmatch(strings.ToLower(text)); m != nil {
+		hours, _ := strconv.Atoi(m[1])
+		if hours < -12 || hours > 14 {
+			return "", false
+		}
+		return weather.ZoneForOffset(hours * 3600), true
+	}
+
+	// LoadLocation принимает и "Local" с пустой строкой, поэтому требуем вид Регион/Город
+	if text != "UTC" && !strings.Contains(text, "/") {
+		return "", false
+	}
+	if _, err := time.LoadLocation(text); err != nil {
+		return "", false
+	}
+	return text, true
+}
+
+// parseClock разбирает время вида "9", "9:30" или "09.30" и возвращает его как ЧЧ:ММ
+func parseClock(text string) (string, bool) {
+	text = strings.ReplaceAll(strings.TrimSpace(text), ".", ":")
+	if !strings.Contains(text, ":") {
+		text += ":00"
+	}
+
+	clock, err := time.Parse("15:04", text)
+	if err != nil {
+		return "", false
+	}
+	return clock.Format("15:04"), true
+}
@@ -0,0 +1,61 @@
+package bot
+
+import (
+	"GreenAssistantBot/internal/weather"
+	"testing"
+	"time"
+)
+
+// offsetOnlyWeather, как OpenWeatherMap, сообщает только смещение от UTC
+type offsetOnlyWeather struct {
+	fakeWeather
+}
+
+func (offsetOnlyWeather) Current(lat, lon float64, prefs weather.Preferences) (*weather.WeatherData, error) {
+	return &weather.WeatherData{Name: "Berlin", Lat: lat, Lon: lon, Timezone: 2 * 60 * 60}, nil
+}
+
+// zoneFindingWeather дополнительно определяет часовой пояс по координатам
+type zoneFindingWeather struct {
+	offsetOnlyWeather
+}
+
+func (zoneFindingWeather) Zone(lat, lon float64) (string, error) {
+	return "Europe/Berlin", nil
+}
+
+func TestTimezoneForPlaceDST(t *testing.T) {
+	berlin := weather.Place{Name: "Berlin", Country: "DE", Lat: 52.52, Lon: 13.41}
+
+	h := &MessageHandler{weather: weather.NewWeatherServiceWithProvider(zoneFindingWeather{})}
+	zone, err := h.timezoneForPlace(berlin)
+	if err != nil {
+		t.Fatalf("timezoneForPlace: %v", err)
+	}
+	if zone != "Europe/Berlin" {
+		t.Fatalf("expected IANA zone of the place, got %q", zone)
+	}
+
+	location, err := time.LoadLocation(zone)
+	if err != nil {
+		t.Fatalf("load %s: %v", zone, err)
+	}
+	_, winter := time.Date(2026, 1, 15, 12, 0, 0, 0, location).Zone()
+	_, summer := time.Date(2026, 7, 15, 12, 0, 0, 0, location).Zone()
+	if winter == summer {
+		t.Errorf("zone %s must follow daylight saving time", zone)
+	}
+
+	// Зона от геокодера важнее определенной по координатам
+	berlin.Zone = "Europe/Paris"
+	if zone, _ := h.timezoneForPlace(berlin); zone != "Europe/Paris" {
+		t.Errorf("expected geocoder zone, got %q", zone)
+	}
+
+	// Без определения по координатам остается только смещение
+	h = &MessageHandler{weather: weather.NewWeatherServiceWithProvider(offsetOnlyWeather{})}
+	berlin.Zone = ""
+	if zone, _ := h.timezoneForPlace(berlin); zone != weather.ZoneForOffset(2*60*60) {
+		t.Errorf("expected offset fallback, got %q", zone)
+	}
+}
@@ -0,0 +1,193 @@
+package bot
+
+import (
+	"GreenAssistantBot/internal/database"
+	"GreenAssistantBot/internal/database/models"
+	"GreenAssistantBot/internal/fsm"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	// Сколько тегов можно выбрать для фильтра (ограничено размером callback_data)
+	maxSelectedTags = 5
+	// Сколько тегов показывать кнопками в облаке
+	maxTagCloudButtons = 30
+)
+
+// applyHashtags назначает заметке теги из #хештегов ее текста и подписи
+func (h *NotesHandler) applyHashtags(chatID int64, note *models.Note) []string {
+	names := database.ExtractHashtags(note.Content + " " + note.Caption)
+	if err := database.SetNoteTags(chatID, note, names); err != nil {
+		log.Printf("Error saving note tags: %v", err)
+		return nil
+	}
+	return names
+}
+
+// formatTags возвращает строку вида "#тег1 #тег2"
+func formatTags(names []string) string {
+	tags := make([]string, len(names))
+	for i, name := range names {
+		tags[i] = "#" + name
+	}
+	return strings.Join(tags, " ")
+}
+
+func noteTagNames(note models.Note) []string {
+	names := make([]string, len(note.Tags))
+	for i, tag := range note.Tags {
+		names[i] = tag.Name
+	}
+	return names
+}
+
+// SendTagCloud отправляет облако тегов и кнопки для фильтрации заметок
+func (h *NotesHandler) SendTagCloud(chatID int64) {
+	cloud, err := database.GetTagCloud(chatID)
+	if err != nil {
+		log.Printf("Error getting tag cloud: %v", err)
+		h.msgHandler.sendMessage(chatID, "❌ Ошибка при загрузке тегов", CreateNotesMenuKeyboard())
+		return
+	}
+
+	if len(cloud) == 0 {
+		h.msgHandler.sendMessage(chatID,
+			"🏷️ У вас пока нет тегов.\n\nДобавьте #хештег в текст или подпись заметки, и он станет тегом.",
+			CreateNotesMenuKeyboard())
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString("☁️ Облако тегов:\n\n")
+	for i, tag := range cloud {
+		if i > 0 {
+			text.WriteString(" · ")
+		}
+		text.WriteString(fmt.Sprintf("#%s (%d)", tag.Name, tag.Count))
+	}
+	h.msgHandler.sendMessage(chatID, text.String(), CreateTagsMenuKeyboard())
+
+	h.msgHandler.sendMessage(chatID, tagSelectionText(nil), CreateTagCloudKeyboard(h.msgHandler.callbacks, cloud, nil))
+}
+
+func tagSelectionText(selected []models.Tag) string {
+	if len(selected) == 0 {
+		return fmt.Sprintf("👇 Выберите до %d тегов, чтобы показать заметки с ними:", maxSelectedTags)
+	}
+
+	names := make([]string, len(selected))
+	for i, tag := range selected {
+		names[i] = tag.Name
+	}
+	return "✅ Выбрано: " + formatTags(names)
+}
+
+// onTagToggle обновляет выбор тегов под облаком
+func (h *NotesHandler) onTagToggle(query *tgbotapi.CallbackQuery, args []string) string {
+	chatID := query.Message.Chat.ID
+	var ids []uint
+	if len(args) > 0 {
+		ids = callbackArgIDs(args[0])
+	}
+
+	cloud, err := database.GetTagCloud(chatID)
+	if err != nil {
+		log.Printf("Error getting tag cloud: %v", err)
+		return "❌ Ошибка при загрузке тегов"
+	}
+
+	var selected []models.Tag
+	if len(ids) > 0 {
+		selected, err = database.GetTagsByIDs(chatID, ids)
+		if err != nil {
+			log.Printf("Error getting tags: %v", err)
+			return "❌ Теги не найдены"
+		}
+	}
+
+	h.editMessageWithKeyboard(query.Message, tagSelectionText(selected),
+		CreateTagCloudKeyboard(h.msgHandler.callbacks, cloud, ids))
+	return ""
+}
+
+// onTagNotes показывает заметки, у которых есть все выбранные теги
+func (h *NotesHandler) onTagNotes(query *tgbotapi.CallbackQuery, args []string) string {
+	if len(args) == 0 {
+		return "⚠️ Неверная кнопка"
+	}
+	ids := callbackArgIDs(args[0])
+	if len(ids) == 0 {
+		return "🏷️ Выберите хотя бы один тег"
+	}
+
+	h.sendNoteList(query.Message.Chat.ID, noteList{Mode: noteListTags, TagIDs: ids}, "🏷️ Нет заметок со всеми выбранными тегами")
+	return ""
+}
+
+// AskForTagRename запрашивает старое и новое имя тега
+func (h *NotesHandler) AskForTagRename(chatID int64) {
+	h.msgHandler.sendMessage(chatID, "✏️ Введите текущее и новое имя тега через пробел, например:\n#работа #проекты", CreateBackKeyboard())
+	h.storage.SetUserState(chatID, StateWaitingForTagRename)
+}
+
+// AskForTagMerge запрашивает теги для объединения
+func (h *NotesHandler) AskForTagMerge(chatID int64) {
+	h.msgHandler.sendMessage(chatID, "🔀 Введите тег, который нужно объединить, и тег, в который его перенести, например:\n#todo #задачи", CreateBackKeyboard())
+	h.storage.SetUserState(chatID, StateWaitingForTagMerge)
+}
+
+// HandleTagRename переименовывает тег
+func (h *NotesHandler) HandleTagRename(chatID int64, oldName, newName string) fsm.State {
+	err := database.RenameTag(chatID, oldName, newName)
+	switch {
+	case err == nil:
+		h.msgHandler.sendMessage(chatID, fmt.Sprintf("✅ Тег #%s переименован в #%s", oldName, newName), CreateTagsMenuKeyboard())
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		h.msgHandler.sendMessage(chatID, fmt.Sprintf("❌ Тег #%s не найден", oldName), CreateTagsMenuKeyboard())
+	case errors.Is(err, database.ErrTagExists):
+		h.msgHandler.sendMessage(chatID,
+			fmt.Sprintf("❌ Тег #%s уже существует. Чтобы перенести в него заметки, используйте «🔀 Объединить теги»", newName),
+			CreateTagsMenuKeyboard())
+	default:
+		log.Printf("Error renaming tag: %v", err)
+		h.msgHandler.sendMessage(chatID, "❌ Ошибка при переименовании тега", CreateTagsMenuKeyboard())
+	}
+	return fsm.None
+}
+
+// HandleTagMerge переносит заметки одного тега в другой
+func (h *NotesHandler) HandleTagMerge(chatID int64, sourceName, targetName string) fsm.State {
+	err := database.MergeTags(chatID, sourceName, targetName)
+	switch {
+	case err == nil:
+		h.msgHandler.sendMessage(chatID, fmt.Sprintf("✅ Тег #%s объединен с #%s", sourceName, targetName), CreateTagsMenuKeyboard())
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		h.msgHandler.sendMessage(chatID, "❌ Оба тега должны существовать", CreateTagsMenuKeyboard())
+	case errors.Is(err, database.ErrSameTag):
+		h.msgHandler.sendMessage(chatID, "❌ Нельзя объединить тег с самим собой", CreateTagsMenuKeyboard())
+	default:
+		log.Printf("Error merging tags: %v", err)
+		h.msgHandler.sendMessage(chatID, "❌ Ошибка при объединении тегов", CreateTagsMenuKeyboard())
+	}
+	return fsm.None
+}
+
+// parseTagPair разбирает ввод из двух имен тегов
+func parseTagPair(text string) (string, string, bool) {
+	fields := strings.Fields(text)
+	if len(fields) != 2 {
+		return "", "", false
+	}
+
+	first, second := database.NormalizeTagName(fields[0]), database.NormalizeTagName(fields[1])
+	if first == "" || second == "" {
+		return "", "", false
+	}
+	return first, second, true
+}
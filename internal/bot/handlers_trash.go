@@ -0,0 +1,259 @@
+package bot
+
+import (
+	"GreenAssistantBot/internal/database"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+)
+
+// Сколько элементов корзины показывать кнопками
+const maxTrashItems = 20
+
+// Действия inline-кнопок корзины
+const (
+	cbTrashList            = "bl"
+	cbTrashNote            = "bn"
+	cbTrashCategory        = "bc"
+	cbTrashRestoreNote     = "rn"
+	cbTrashRestoreCategory = "rc"
+	cbTrashPurgeNote       = "xn"
+	cbTrashPurgeCategory   = "xc"
+	cbTrashEmpty           = "xe"
+	cbTrashEmptyConfirm    = "xy"
+)
+
+// RegisterTrashCallbacks регистрирует обработчики кнопок корзины
+func (h *NotesHandler) RegisterTrashCallbacks(router *callbackRouter) {
+	router.Register(cbTrashList, h.onTrashList)
+	router.Register(cbTrashNote, h.onTrashNote)
+	router.Register(cbTrashCategory, h.onTrashCategory)
+	router.Register(cbTrashRestoreNote, h.onTrashRestoreNote)
+	router.Register(cbTrashRestoreCategory, h.onTrashRestoreCategory)
+	router.Register(cbTrashPurgeNote, h.onTrashPurgeNote)
+	router.Register(cbTrashPurgeCategory, h.onTrashPurgeCategory)
+	router.Register(cbTrashEmpty, h.onTrashEmpty)
+	router.Register(cbTrashEmptyConfirm, h.onTrashEmptyConfirm)
+}
+
+// SendTrash отправляет содержимое корзины
+func (h *NotesHandler) SendTrash(chatID int64) {
+	text, keyboard, err := h.renderTrash(chatID)
+	if err != nil {
+		log.Printf("Error getting trash: %v", err)
+		h.msgHandler.sendMessage(chatID, "❌ Ошибка при загрузке корзины", CreateNotesMenuKeyboard())
+		return
+	}
+
+	if keyboard == nil {
+		h.msgHandler.sendMessage(chatID, text, CreateNotesMenuKeyboard())
+		return
+	}
+	h.msgHandler.sendMessage(chatID, text, *keyboard)
+}
+
+// renderTrash формирует список корзины. Если корзина пуста, кнопок нет.
+func (h *NotesHandler) renderTrash(chatID int64) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	categories, err := database.GetDeletedCategories(chatID)
+	if err != nil {
+		return "", nil, err
+	}
+	notes, err := database.GetDeletedNotes(chatID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(categories) == 0 && len(notes) == 0 {
+		return "🗑️ Корзина пуста", nil, nil
+	}
+
+	retentionDays := int(database.TrashRetention().Hours() / 24)
+	text := fmt.Sprintf("🗑️ Корзина\n\n📂 Категорий: %d\n📝 Заметок: %d\n\nУдаленное хранится %d дн., затем удаляется автоматически. Выберите элемент, чтобы восстановить его или удалить навсегда:",
+		len(categories), len(notes), retentionDays)
+
+	hidden := 0
+	if len(categories) > maxTrashItems {
+		hidden += len(categories) - maxTrashItems
+		categories = categories[:maxTrashItems]
+	}
+	if limit := maxTrashItems - len(categories); len(notes) > limit {
+		hidden += len(notes) - limit
+		notes = notes[:limit]
+	}
+	if hidden > 0 {
+		text += fmt.Sprintf("\n\n... и еще %d", hidden)
+	}
+
+	keyboard := CreateTrashKeyboard(h.msgHandler.callbacks, categories, notes)
+	return text, &keyboard, nil
+}
+
+func (h *NotesHandler) onTrashList(query *tgbotapi.CallbackQuery, args []string) string {
+	text, keyboard, err := h.renderTrash(query.Message.Chat.ID)
+	if err != nil {
+		log.Printf("Error getting trash: %v", err)
+		return "❌ Ошибка при загрузке корзины"
+	}
+
+	if keyboard == nil {
+		h.editMessageText(query.Message, text)
+		return ""
+	}
+	h.editMessageWithKeyboard(query.Message, text, *keyboard)
+	return ""
+}
+
+// onTrashNote показывает удаленную заметку с кнопками восстановления
+func (h *NotesHandler) onTrashNote(query *tgbotapi.CallbackQuery, args []string) string {
+	chatID := query.Message.Chat.ID
+	noteID, ok := callbackArgID(args, 0)
+	if !ok {
+		return "⚠️ Неверная кнопка"
+	}
+
+	notes, err := database.GetDeletedNotes(chatID)
+	if err != nil {
+		log.Printf("Error getting trash: %v", err)
+		return "❌ Ошибка при загрузке корзины"
+	}
+
+	for _, note := range notes {
+		if note.ID != noteID {
+			continue
+		}
+		text := fmt.Sprintf("🗑️ Удаленная заметка\n\n%s %s\n\n📂 Категория: %s\n📅 Удалена: %s",
+			getNoteTypeEmoji(note.Type), html.EscapeString(notePreview(note, 200)), html.EscapeString(note.Category.Name),
+			note.DeletedAt.Time.Format("02.01.2006 15:04"))
+		h.editMessageWithKeyboard(query.Message, text, CreateTrashItemKeyboard(h.msgHandler.callbacks, cbTrashRestoreNote, cbTrashPurgeNote, noteID))
+		return ""
+	}
+
+	return "❌ Заметка не найдена в корзине"
+}
+
+// onTrashCategory показывает удаленную категорию с кнопками восстановления
+func (h *NotesHandler) onTrashCategory(query *tgbotapi.CallbackQuery, args []string) string {
+	chatID := query.Message.Chat.ID
+	categoryID, ok := callbackArgID(args, 0)
+	if !ok {
+		return "⚠️ Неверная кнопка"
+	}
+
+	categories, err := database.GetDeletedCategories(chatID)
+	if err != nil {
+		log.Printf("Error getting trash: %v", err)
+		return "❌ Ошибка при загрузке корзины"
+	}
+
+	for _, category := range categories {
+		if category.ID != categoryID {
+			continue
+		}
+		text := fmt.Sprintf("🗑️ Удаленная категория\n\n📂 %s\n📝 Заметок: %d\n📅 Удалена: %s\n\nПри восстановлении вернутся и заметки, удаленные вместе с категорией.",
+			html.EscapeString(category.Name), category.NotesCount, category.DeletedAt.Time.Format("02.01.2006 15:04"))
+		h.editMessageWithKeyboard(query.Message, text, CreateTrashItemKeyboard(h.msgHandler.callbacks, cbTrashRestoreCategory, cbTrashPurgeCategory, categoryID))
+		return ""
+	}
+
+	return "❌ Категория не найдена в корзине"
+}
+
+func (h *NotesHandler) onTrashRestoreNote(query *tgbotapi.CallbackQuery, args []string) string {
+	noteID, ok := callbackArgID(args, 0)
+	if !ok {
+		return "⚠️ Неверная кнопка"
+	}
+
+	err := database.RestoreNote(query.Message.Chat.ID, noteID)
+	switch {
+	case errors.Is(err, database.ErrCategoryDeleted):
+		return "📂 Сначала восстановите категорию заметки"
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return "❌ Заметка не найдена в корзине"
+	case err != nil:
+		log.Printf("Error restoring note: %v", err)
+		return "❌ Ошибка при восстановлении заметки"
+	}
+
+	h.onTrashList(query, nil)
+	return "♻️ Заметка восстановлена"
+}
+
+func (h *NotesHandler) onTrashRestoreCategory(query *tgbotapi.CallbackQuery, args []string) string {
+	categoryID, ok := callbackArgID(args, 0)
+	if !ok {
+		return "⚠️ Неверная кнопка"
+	}
+
+	err := database.RestoreCategory(query.Message.Chat.ID, categoryID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return "❌ Категория не найдена в корзине"
+	case err != nil:
+		log.Printf("Error restoring category: %v", err)
+		return "❌ Ошибка при восстановлении категории"
+	}
+
+	h.onTrashList(query, nil)
+	return "♻️ Категория восстановлена"
+}
+
+func (h *NotesHandler) onTrashPurgeNote(query *tgbotapi.CallbackQuery, args []string) string {
+	noteID, ok := callbackArgID(args, 0)
+	if !ok {
+		return "⚠️ Неверная кнопка"
+	}
+
+	err := database.PurgeNote(query.Message.Chat.ID, noteID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return "❌ Заметка не найдена в корзине"
+	case err != nil:
+		log.Printf("Error purging note: %v", err)
+		return "❌ Ошибка при удалении заметки"
+	}
+
+	h.onTrashList(query, nil)
+	return "🗑️ Заметка удалена навсегда"
+}
+
+func (h *NotesHandler) onTrashPurgeCategory(query *tgbotapi.CallbackQuery, args []string) string {
+	categoryID, ok := callbackArgID(args, 0)
+	if !ok {
+		return "⚠️ Неверная кнопка"
+	}
+
+	err := database.PurgeCategory(query.Message.Chat.ID, categoryID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return "❌ Категория не найдена в корзине"
+	case err != nil:
+		log.Printf("Error purging category: %v", err)
+		return "❌ Ошибка при удалении категории"
+	}
+
+	h.onTrashList(query, nil)
+	return "🗑️ Категория удалена навсегда"
+}
+
+// onTrashEmpty запрашивает подтверждение очистки корзины
+func (h *NotesHandler) onTrashEmpty(query *tgbotapi.CallbackQuery, args []string) string {
+	h.editMessageWithKeyboard(query.Message,
+		"⚠️ Очистить корзину?\n\nВсе заметки и категории в корзине будут удалены безвозвратно.",
+		CreateTrashEmptyConfirmKeyboard(h.msgHandler.callbacks))
+	return ""
+}
+
+func (h *NotesHandler) onTrashEmptyConfirm(query *tgbotapi.CallbackQuery, args []string) string {
+	if err := database.EmptyTrash(query.Message.Chat.ID); err != nil {
+		log.Printf("Error emptying trash: %v", err)
+		return "❌ Ошибка при очистке корзины"
+	}
+
+	h.editMessageText(query.Message, "🧹 Корзина очищена")
+	return ""
+}
@@ -0,0 +1,55 @@
+package bot
+
+import (
+	"GreenAssistantBot/internal/database"
+	"GreenAssistantBot/internal/database/models"
+	"GreenAssistantBot/internal/weather"
+	"fmt"
+	"log"
+)
+
+// WeatherPreferences возвращает единицы и язык погоды, выбранные пользователем
+func WeatherPreferences(user *models.User) weather.Preferences {
+	return weather.Preferences{Units: user.Units, Pressure: user.PressureUnit, Lang: user.Language}
+}
+
+// chatWeatherPreferences возвращает настройки погоды пользователя или настройки по умолчанию
+func (h *MessageHandler) chatWeatherPreferences(chatID int64) weather.Preferences {
+	user, err := database.GetUserByTelegramID(chatID)
+	if err != nil {
+		return weather.DefaultPreferences()
+	}
+	return WeatherPreferences(user)
+}
+
+// SendWeatherPreferences показывает текущие единицы и язык погоды
+func (h *MessageHandler) SendWeatherPreferences(chatID int64) {
+	prefs := h.chatWeatherPreferences(chatID)
+
+	units := "°C, м/с"
+	if prefs.Units == weather.UnitsImperial {
+		units = "°F, mph"
+	}
+	pressure := "hPa"
+	if prefs.Pressure == weather.PressureMmHg {
+		pressure = "мм рт. ст."
+	}
+	language := "русский"
+	if prefs.Lang == weather.LangEnglish {
+		language = "английский"
+	}
+
+	text := fmt.Sprintf("📏 Единицы и язык погоды\n\n🌡️ Температура и ветер: %s\n📊 Давление: %s\n🗣️ Описание погоды: %s\n\nВыберите, что изменить:",
+		units, pressure, language)
+	h.sendMessage(chatID, text, CreateWeatherPreferencesKeyboard())
+}
+
+// HandleWeatherPreference сохраняет выбранную кнопкой настройку погоды
+func (h *MessageHandler) HandleWeatherPreference(chatID int64, column, value string) {
+	if err := database.SetUserWeatherPreference(chatID, column, value); err != nil {
+		log.Printf("Error saving weather preference %s: %v", column, err)
+		h.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.", CreateSettingsMenuKeyboard())
+		return
+	}
+	h.SendWeatherPreferences(chatID)
+}
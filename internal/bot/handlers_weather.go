@@ -0,0 +1,80 @@
+package bot
+
+import (
+	"GreenAssistantBot/internal/telegram"
+	"log"
+	"strconv"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Действие inline-кнопок погоды. Аргументы: режим, широта, долгота.
+const cbWeather = "wf"
+
+// Режимы кнопок погоды
+const (
+	weatherModeNow      = "n"
+	weatherModeToday    = "t"
+	weatherModeTomorrow = "m"
+	weatherModeDays     = "5"
+)
+
+// RegisterWeatherCallbacks регистрирует обработчики кнопок погоды
+func (h *MessageHandler) RegisterWeatherCallbacks(router *callbackRouter) {
+	router.Register(cbWeather, h.onWeather)
+}
+
+// onWeather показывает текущую погоду или прогноз в том же сообщении
+func (h *MessageHandler) onWeather(query *tgbotapi.CallbackQuery, args []string) string {
+	if len(args) < 3 {
+		return "⚠️ Неверная кнопка"
+	}
+	lat, latErr := strconv.ParseFloat(args[1], 64)
+	lon, lonErr := strconv.ParseFloat(args[2], 64)
+	if latErr != nil || lonErr != nil {
+		return "⚠️ Неверная кнопка"
+	}
+
+	prefs := h.chatWeatherPreferences(query.From.ID)
+	var text string
+	switch args[0] {
+	case weatherModeNow:
+		weatherData, err := h.weather.GetWeatherDataByCoords(lat, lon, prefs)
+		if err != nil {
+			log.Printf("Error getting weather data: %v", err)
+			return "❌ Не удалось получить данные о погоде"
+		}
+		text = h.weather.FormatWeatherMessage(weatherData, prefs)
+
+	case weatherModeToday, weatherModeTomorrow, weatherModeDays:
+		forecast, err := h.weather.GetForecast(lat, lon, prefs)
+		if err != nil {
+			log.Printf("Error getting forecast: %v", err)
+			return "❌ Не удалось получить прогноз погоды"
+		}
+
+		switch args[0] {
+		case weatherModeToday:
+			text = h.weather.FormatDayForecast(forecast, 0, prefs)
+		case weatherModeTomorrow:
+			text = h.weather.FormatDayForecast(forecast, 1, prefs)
+		default:
+			text = h.weather.FormatDailyForecast(forecast, prefs)
+		}
+
+	default:
+		return "⚠️ Неверная кнопка"
+	}
+
+	keyboard := CreateWeatherKeyboard(h.callbacks, lat, lon, args[0])
+	edit := telegram.Edit{ChatID: query.Message.Chat.ID, MessageID: query.Message.MessageID, Text: text, Markup: &keyboard}
+	if err := h.sender.Edit(edit); err != nil {
+		log.Printf("Error editing message: %v", err)
+	}
+	return ""
+}
+
+// weatherCoord кодирует координату для callback_data (4 знака — точность около 10 м)
+func weatherCoord(value float64) string {
+	return strconv.FormatFloat(value, 'f', 4, 64)
+}
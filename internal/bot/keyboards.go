@@ -1,98 +1,207 @@
 package bot
 
 import (
+	"GreenAssistantBot/internal/database"
 	"GreenAssistantBot/internal/database/models"
 	"GreenAssistantBot/internal/storage"
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"GreenAssistantBot/internal/telegram"
+	"GreenAssistantBot/internal/weather"
+	"fmt"
 	"log"
+	"strconv"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 func CreateMainMenuKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	return routes.Keyboard(menuMain)
+}
+
+func CreateSettingsMenuKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	return routes.Keyboard(menuSettings)
+}
+
+// CreateLocationsKeyboard создает inline-клавиатуру управления сохраненными местами
+func CreateLocationsKeyboard(codec *CallbackCodec, locations []models.UserLocation) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, location := range locations {
+		notify := "🔕 "
+		if location.Notify {
+			notify = "🔔 "
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(notify+location.Label, codec.Encode(cbLocationNotify, callbackID(location.ID))),
+			tgbotapi.NewInlineKeyboardButtonData("🗑️", codec.Encode(cbLocationDelete, callbackID(location.ID))),
+		))
+	}
+
+	if len(locations) < database.MaxUserLocations {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("➕ Добавить место", codec.Encode(cbLocationAdd)),
+		))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// CreateWeatherPlacesKeyboard создает клавиатуру быстрого выбора места для погоды
+func CreateWeatherPlacesKeyboard(user *models.User, locations []models.UserLocation) tgbotapi.ReplyKeyboardMarkup {
+	var buttons []tgbotapi.KeyboardButton
+	if user.City != "" {
+		buttons = append(buttons, tgbotapi.NewKeyboardButton(homePlacePrefix+user.City))
+	}
+	for _, location := range locations {
+		buttons = append(buttons, tgbotapi.NewKeyboardButton(savedPlacePrefix+location.Label))
+	}
+
+	var rows [][]tgbotapi.KeyboardButton
+	for i := 0; i < len(buttons); i += 2 {
+		rows = append(rows, buttons[i:min(i+2, len(buttons))])
+	}
+	rows = append(rows,
+		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButtonLocation(locationButton)),
+		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(btnBack)),
+	)
+	return tgbotapi.NewReplyKeyboard(rows...)
+}
+
+// CreateWeatherPreferencesKeyboard создает клавиатуру выбора единиц и языка погоды
+func CreateWeatherPreferencesKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	return routes.Keyboard(menuWeatherPrefs)
+}
+
+// CreateWeatherAlertsKeyboard создает клавиатуру настроек погодных предупреждений
+func CreateWeatherAlertsKeyboard(enabled bool) tgbotapi.ReplyKeyboardMarkup {
+	toggle := btnAlertsOn
+	if enabled {
+		toggle = btnAlertsOff
+	}
+
+	rows := append([][]tgbotapi.KeyboardButton{tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(toggle))},
+		routes.Rows(menuWeatherAlerts)...)
+	return tgbotapi.NewReplyKeyboard(rows...)
+}
+
+// CreateFrostThresholdKeyboard создает клавиатуру выбора порога мороза
+func CreateFrostThresholdKeyboard() tgbotapi.ReplyKeyboardMarkup {
 	return tgbotapi.NewReplyKeyboard(
 		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("ℹ️ Информация"),
-			tgbotapi.NewKeyboardButton("📞 Поддержка"),
+			tgbotapi.NewKeyboardButton("-5"),
+			tgbotapi.NewKeyboardButton("-10"),
+			tgbotapi.NewKeyboardButton("-15"),
+			tgbotapi.NewKeyboardButton("-20"),
+			tgbotapi.NewKeyboardButton("-25"),
 		),
 		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("🌡️Погода"),
-			tgbotapi.NewKeyboardButton("📒 Заметки"),
+			tgbotapi.NewKeyboardButton(btnBack),
+		),
+	)
+}
+
+// CreateQuietHoursKeyboard создает клавиатуру выбора тихих часов
+func CreateQuietHoursKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	return tgbotapi.NewReplyKeyboard(
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("22:00-07:00"),
+			tgbotapi.NewKeyboardButton("23:00-08:00"),
 		),
 		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("⚙️ Настройки"),
+			tgbotapi.NewKeyboardButton(noQuietHoursButton),
+			tgbotapi.NewKeyboardButton(btnBack),
 		),
 	)
 }
 
-func CreateSettingsMenuKeyboard() tgbotapi.ReplyKeyboardMarkup {
+// CreateTimezoneKeyboard создает клавиатуру выбора часового пояса
+func CreateTimezoneKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	var rows [][]tgbotapi.KeyboardButton
+	for i := 0; i < len(timezoneChoices); i += 2 {
+		row := tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(timezoneChoices[i].Label))
+		if i+1 < len(timezoneChoices) {
+			row = append(row, tgbotapi.NewKeyboardButton(timezoneChoices[i+1].Label))
+		}
+		rows = append(rows, row)
+	}
+
+	rows = append(rows, tgbotapi.NewKeyboardButtonRow(
+		tgbotapi.NewKeyboardButton(timezoneFromCityButton),
+		tgbotapi.NewKeyboardButton(btnBack),
+	))
+	return tgbotapi.NewReplyKeyboard(rows...)
+}
+
+// CreateCityInputKeyboard создает клавиатуру ввода города с кнопкой отправки местоположения
+func CreateCityInputKeyboard() tgbotapi.ReplyKeyboardMarkup {
 	return tgbotapi.NewReplyKeyboard(
 		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("🔔 Уведомления"),
-			tgbotapi.NewKeyboardButton("👤 Профиль"),
+			tgbotapi.NewKeyboardButtonLocation(locationButton),
 		),
 		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("🌡️ Уведомления о погоде"),
-			tgbotapi.NewKeyboardButton("⬅️ Назад"),
+			tgbotapi.NewKeyboardButton("❌ Отмена"),
 		),
 	)
 }
 
-func SendSettingsMenu(bot *tgbotapi.BotAPI, chatID int64, storage storage.BotStorage) {
-	text := "⚙️ Настройки"
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ReplyMarkup = CreateSettingsMenuKeyboard()
+// CreateCityChoiceKeyboard создает клавиатуру выбора города из найденных вариантов
+func CreateCityChoiceKeyboard(places []weather.Place) tgbotapi.ReplyKeyboardMarkup {
+	var rows [][]tgbotapi.KeyboardButton
+	for _, place := range places {
+		rows = append(rows, tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(place.Label())))
+	}
+
+	rows = append(rows, tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("❌ Отмена")))
+	return tgbotapi.NewReplyKeyboard(rows...)
+}
 
-	sentMsg, err := bot.Send(msg)
+// CreateNotificationTimeKeyboard создает клавиатуру выбора времени уведомлений
+func CreateNotificationTimeKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	return tgbotapi.NewReplyKeyboard(
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("07:00"),
+			tgbotapi.NewKeyboardButton("08:00"),
+			tgbotapi.NewKeyboardButton("09:00"),
+		),
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("10:00"),
+			tgbotapi.NewKeyboardButton("12:00"),
+			tgbotapi.NewKeyboardButton("20:00"),
+		),
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton(btnBack),
+		),
+	)
+}
+
+func SendSettingsMenu(sender telegram.Sender, chatID int64, storage storage.BotStorage) {
+	text := "⚙️ Настройки"
+	messageID, err := sender.SendText(telegram.Text{ChatID: chatID, Text: text, ReplyMarkup: CreateSettingsMenuKeyboard()})
 	if err != nil {
 		log.Printf("Error sending settings menu: %v", err)
 	} else {
-		storage.SetLastMessageID(chatID, sentMsg.MessageID)
+		storage.SetLastMessageID(chatID, messageID)
 	}
 
 	// ЗАКОММЕНТИРОВАТЬ ЭТУ СТРОКУ:
 	// storage.ClearUserData(chatID)
 }
 
-func SendMainMenu(bot *tgbotapi.BotAPI, chatID int64, storage storage.BotStorage) {
-	msg := tgbotapi.NewMessage(chatID, "Выберите один из пунктов")
-	msg.ReplyMarkup = CreateMainMenuKeyboard()
-
-	sentMsg, err := bot.Send(msg)
+func SendMainMenu(sender telegram.Sender, chatID int64, storage storage.BotStorage) {
+	messageID, err := sender.SendText(telegram.Text{ChatID: chatID, Text: "Выберите один из пунктов", ReplyMarkup: CreateMainMenuKeyboard()})
 	if err != nil {
 		log.Printf("Error sending main menu: %v", err)
 	} else {
-		storage.SetLastMessageID(chatID, sentMsg.MessageID)
+		storage.SetLastMessageID(chatID, messageID)
 	}
 
 	//storage.ClearUserData(chatID)
 }
 
 func CreateProfileMenuKeyboard() tgbotapi.ReplyKeyboardMarkup {
-	return tgbotapi.NewReplyKeyboard(
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("✏️ Ваше имя"),
-			tgbotapi.NewKeyboardButton("🚩 Ваш город"),
-		),
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("🏠 В начало"),
-		),
-	)
+	return routes.Keyboard(menuProfile)
 }
 
 // CreateNotesMenuKeyboard создает клавиатуру для меню заметок
 func CreateNotesMenuKeyboard() tgbotapi.ReplyKeyboardMarkup {
-	return tgbotapi.NewReplyKeyboard(
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("📝 Новая заметка"),
-			tgbotapi.NewKeyboardButton("📁 Мои заметки"),
-		),
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("🛠️ Управление заметками"),
-			tgbotapi.NewKeyboardButton("📂 Управление категориями"),
-		),
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("⬅️ Назад"),
-		),
-	)
+	return routes.Keyboard(menuNotes)
 }
 
 // CreateCategoriesKeyboard создает клавиатуру с категориями пользователя
@@ -114,8 +223,8 @@ func CreateCategoriesKeyboard(categories []models.Category) tgbotapi.ReplyKeyboa
 	// Добавляем кнопку возврата
 	keyboard.Keyboard = append(keyboard.Keyboard,
 		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("➕ Новая категория"),
-			tgbotapi.NewKeyboardButton("⬅️ Назад к заметкам"),
+			tgbotapi.NewKeyboardButton(btnNewCategory),
+			tgbotapi.NewKeyboardButton(btnBackToNotes),
 		),
 	)
 
@@ -124,38 +233,20 @@ func CreateCategoriesKeyboard(categories []models.Category) tgbotapi.ReplyKeyboa
 
 // CreateCategoriesManagementKeyboard создает клавиатуру для управления категориями
 func CreateCategoriesManagementKeyboard() tgbotapi.ReplyKeyboardMarkup {
-	return tgbotapi.NewReplyKeyboard(
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("➕ Создать категорию"),
-			tgbotapi.NewKeyboardButton("✏️ Редактировать категории"),
-		),
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("🗑️ Удалить категорию"),
-			tgbotapi.NewKeyboardButton("⬅️ Назад к заметкам"),
-		),
-	)
+	return routes.Keyboard(menuCategories)
 }
 
 // CreateBackKeyboard создает простую клавиатуру с кнопкой назад
 func CreateBackKeyboard() tgbotapi.ReplyKeyboardMarkup {
 	return tgbotapi.NewReplyKeyboard(
 		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("⬅️ Назад"),
+			tgbotapi.NewKeyboardButton(btnBack),
 		),
 	)
 }
 
 func CreateNotesViewKeyboard() tgbotapi.ReplyKeyboardMarkup {
-	return tgbotapi.NewReplyKeyboard(
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("📝 Новая заметка"),
-			tgbotapi.NewKeyboardButton("📸 Медиа-заметки"),
-		),
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("🛠️ Управление заметками"),
-			tgbotapi.NewKeyboardButton("⬅️ Назад к заметкам"),
-		),
-	)
+	return routes.Keyboard(menuNotesView)
 }
 
 // CreateConfirmationKeyboard создает клавиатуру для подтверждения действий
@@ -166,55 +257,311 @@ func CreateConfirmationKeyboard() tgbotapi.ReplyKeyboardMarkup {
 			tgbotapi.NewKeyboardButton("❌ Нет"),
 		),
 		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("⬅️ Назад"),
+			tgbotapi.NewKeyboardButton(btnBack),
 		),
 	)
 }
 
 // CreateNotesManagementKeyboard создает клавиатуру для управления заметками
 func CreateNotesManagementKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	return routes.Keyboard(menuNotesManagement)
+}
+
+// CreateNoteEditKeyboard создает клавиатуру для редактирования заметки
+func CreateNoteEditKeyboard() tgbotapi.ReplyKeyboardMarkup {
 	return tgbotapi.NewReplyKeyboard(
 		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("✏️ Редактировать заметку"),
-			tgbotapi.NewKeyboardButton("🗑️ Удалить заметку"),
+			tgbotapi.NewKeyboardButton("📝 Редактировать текст"),
+			tgbotapi.NewKeyboardButton("📂 Изменить категорию"),
 		),
 		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("⬅️ Назад к заметкам"),
+			tgbotapi.NewKeyboardButton(btnBack),
 		),
 	)
 }
 
-// CreateNoteActionsKeyboard создает клавиатуру действий для конкретной заметки
-func CreateNoteActionsKeyboard() tgbotapi.ReplyKeyboardMarkup {
-	return tgbotapi.NewReplyKeyboard(
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("✏️ Редактировать"),
-			tgbotapi.NewKeyboardButton("🗑️ Удалить"),
+func (h *NotesHandler) sendMessageWithoutKeyboard(chatID int64, text string) {
+	_, err := h.sender.SendText(telegram.Text{ChatID: chatID, Text: text, ReplyMarkup: tgbotapi.NewRemoveKeyboard(true)})
+	if err != nil {
+		log.Printf("Error sending message without keyboard: %v", err)
+	}
+}
+
+// CreateNoteInlineKeyboard создает кнопки действий под заметкой
+func CreateNoteInlineKeyboard(codec *CallbackCodec, note models.Note) tgbotapi.InlineKeyboardMarkup {
+	id := callbackID(note.ID)
+
+	pinLabel := "📌 Закрепить"
+	if note.Pinned {
+		pinLabel = "📍 Открепить"
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✏️ Изменить", codec.Encode(cbNoteEdit, id)),
+			tgbotapi.NewInlineKeyboardButtonData("🗑️ Удалить", codec.Encode(cbNoteDelete, id)),
 		),
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("⬅️ Назад к списку"),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📂 Переместить", codec.Encode(cbNoteMove, id)),
+			tgbotapi.NewInlineKeyboardButtonData(pinLabel, codec.Encode(cbNotePin, id)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🕘 История", codec.Encode(cbNoteHistory, id)),
+			tgbotapi.NewInlineKeyboardButtonData("⏰ Напомнить", codec.Encode(cbNoteRemind, id)),
 		),
 	)
 }
 
-// CreateNoteEditKeyboard создает клавиатуру для редактирования заметки
-func CreateNoteEditKeyboard() tgbotapi.ReplyKeyboardMarkup {
-	return tgbotapi.NewReplyKeyboard(
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("📝 Редактировать текст"),
-			tgbotapi.NewKeyboardButton("📂 Изменить категорию"),
+// CreateNoteMoveKeyboard создает кнопки выбора категории для переноса заметки
+func CreateNoteMoveKeyboard(codec *CallbackCodec, noteID uint, categories []models.Category) tgbotapi.InlineKeyboardMarkup {
+	id := callbackID(noteID)
+	var rows [][]tgbotapi.InlineKeyboardButton
+
+	// Добавляем категории по 2 в ряд
+	for i := 0; i < len(categories); i += 2 {
+		row := tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(categories[i].Name, codec.Encode(cbNoteMoveTo, id, callbackID(categories[i].ID))),
+		)
+		if i+1 < len(categories) {
+			row = append(row,
+				tgbotapi.NewInlineKeyboardButtonData(categories[i+1].Name, codec.Encode(cbNoteMoveTo, id, callbackID(categories[i+1].ID))))
+		}
+		rows = append(rows, row)
+	}
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", codec.Encode(cbNoteActions, id)),
+	))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// CreateNoteDeleteConfirmKeyboard создает кнопки подтверждения удаления заметки
+func CreateNoteDeleteConfirmKeyboard(codec *CallbackCodec, noteID uint) tgbotapi.InlineKeyboardMarkup {
+	id := callbackID(noteID)
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Да, удалить", codec.Encode(cbNoteDeleteYes, id)),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Нет", codec.Encode(cbNoteDeleteNo, id)),
 		),
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("⬅️ Назад"),
+	)
+}
+
+// CreateNoteListKeyboard создает кнопки заметок страницы и навигацию между страницами
+func CreateNoteListKeyboard(codec *CallbackCodec, list noteList, notes []models.Note, totalPages int) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, note := range notes {
+		label := fmt.Sprintf("%s %s", getNoteTypeEmoji(note.Type), notePreview(note, 40))
+		if note.Pinned {
+			label = "📌 " + label
+		}
+		if list.Mode == noteListSearch {
+			// Номера совпадают с номерами фрагментов в тексте сообщения
+			label = fmt.Sprintf("%d. %s", i+1, label)
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, codec.Encode(list.itemAction(), callbackID(note.ID))),
+		))
+	}
+
+	pageButton := func(label string, page int) tgbotapi.InlineKeyboardButton {
+		args := []string{list.Mode, callbackID(list.CategoryID), strconv.Itoa(page)}
+		if len(list.TagIDs) > 0 {
+			args = append(args, callbackIDs(list.TagIDs))
+		}
+		return tgbotapi.NewInlineKeyboardButtonData(label, codec.Encode(cbNotesPage, args...))
+	}
+
+	var navigation []tgbotapi.InlineKeyboardButton
+	if list.Page > 0 {
+		navigation = append(navigation, pageButton("◀️ Назад", list.Page-1))
+	}
+	if list.Page < totalPages-1 {
+		navigation = append(navigation, pageButton("Вперед ▶️", list.Page+1))
+	}
+	if len(navigation) > 0 {
+		rows = append(rows, navigation)
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// CreateTagsMenuKeyboard создает клавиатуру раздела тегов
+func CreateTagsMenuKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	return routes.Keyboard(menuTags)
+}
+
+// CreateTagCloudKeyboard создает кнопки тегов; нажатие добавляет тег в фильтр или убирает из него
+func CreateTagCloudKeyboard(codec *CallbackCodec, cloud []database.TagUsage, selected []uint) tgbotapi.InlineKeyboardMarkup {
+	isSelected := make(map[uint]bool, len(selected))
+	for _, id := range selected {
+		isSelected[id] = true
+	}
+
+	// toggle возвращает выбор после нажатия на тег
+	toggle := func(id uint) []uint {
+		var result []uint
+		for _, selectedID := range selected {
+			if selectedID != id {
+				result = append(result, selectedID)
+			}
+		}
+		if !isSelected[id] {
+			result = append(result, id)
+		}
+		return result
+	}
+
+	if len(cloud) > maxTagCloudButtons {
+		cloud = cloud[:maxTagCloudButtons]
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, tag := range cloud {
+		label := fmt.Sprintf("#%s (%d)", tag.Name, tag.Count)
+		next := toggle(tag.ID)
+		if isSelected[tag.ID] {
+			label = "✅ " + label
+		} else if len(selected) >= maxSelectedTags {
+			// Больше тегов выбрать нельзя, кнопка только обновляет сообщение
+			next = selected
+		}
+
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(label, codec.Encode(cbTagToggle, callbackIDs(next))))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	if len(selected) > 0 {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📋 Показать заметки", codec.Encode(cbTagNotes, callbackIDs(selected))),
+			tgbotapi.NewInlineKeyboardButtonData("✖️ Сбросить", codec.Encode(cbTagToggle)),
+		))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// CreateTrashKeyboard создает кнопки элементов корзины
+func CreateTrashKeyboard(codec *CallbackCodec, categories []database.DeletedCategory, notes []models.Note) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, category := range categories {
+		label := fmt.Sprintf("📂 %s (%d)", category.Name, category.NotesCount)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, codec.Encode(cbTrashCategory, callbackID(category.ID))),
+		))
+	}
+	for _, note := range notes {
+		label := fmt.Sprintf("%s %s", getNoteTypeEmoji(note.Type), notePreview(note, 40))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, codec.Encode(cbTrashNote, callbackID(note.ID))),
+		))
+	}
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🧹 Очистить корзину", codec.Encode(cbTrashEmpty)),
+	))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// CreateTrashItemKeyboard создает кнопки действий с элементом корзины
+func CreateTrashItemKeyboard(codec *CallbackCodec, restoreAction, purgeAction string, id uint) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("♻️ Восстановить", codec.Encode(restoreAction, callbackID(id))),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Удалить навсегда", codec.Encode(purgeAction, callbackID(id))),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", codec.Encode(cbTrashList)),
 		),
 	)
 }
 
-func (h *NotesHandler) sendMessageWithoutKeyboard(chatID int64, text string) {
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ReplyMarkup = tgbotapi.NewRemoveKeyboard(true)
-	_, err := h.bot.Send(msg)
-	if err != nil {
-		log.Printf("Error sending message without keyboard: %v", err)
+// CreateTrashEmptyConfirmKeyboard создает кнопки подтверждения очистки корзины
+func CreateTrashEmptyConfirmKeyboard(codec *CallbackCodec) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Да, очистить", codec.Encode(cbTrashEmptyConfirm)),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Нет", codec.Encode(cbTrashList)),
+		),
+	)
+}
+
+// CreateNoteHistoryKeyboard создает кнопки ревизий заметки
+func CreateNoteHistoryKeyboard(codec *CallbackCodec, revisions []models.NoteRevision) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, revision := range revisions {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(revisionLabel(revision), codec.Encode(cbRevisionView, callbackID(revision.ID))),
+		))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// CreateRevisionKeyboard создает кнопки просмотра ревизии
+func CreateRevisionKeyboard(codec *CallbackCodec, revision *models.NoteRevision) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("↩️ Вернуть эту версию", codec.Encode(cbRevisionRollback, callbackID(revision.ID))),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			// Второй аргумент означает, что список нужно показать в этом же сообщении
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ К истории", codec.Encode(cbNoteHistory, callbackID(revision.NoteID), "e")),
+		),
+	)
+}
+
+// CreateReminderKeyboard создает кнопки доставленного напоминания
+func CreateReminderKeyboard(codec *CallbackCodec, reminder models.Reminder) tgbotapi.InlineKeyboardMarkup {
+	id := callbackID(reminder.ID)
+
+	var snoozeRow []tgbotapi.InlineKeyboardButton
+	for _, snooze := range reminderSnoozes {
+		snoozeRow = append(snoozeRow, tgbotapi.NewInlineKeyboardButtonData(snooze.Label, codec.Encode(cbReminderSnooze, id, snooze.Arg)))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		snoozeRow,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📄 Открыть", codec.Encode(cbNoteOpen, callbackID(reminder.NoteID))),
+			tgbotapi.NewInlineKeyboardButtonData("✅ Готово", codec.Encode(cbReminderDone, id)),
+		),
+	)
+}
+
+// CreateReminderCancelKeyboard создает кнопку отмены запланированного напоминания
+func CreateReminderCancelKeyboard(codec *CallbackCodec, reminderID uint) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔕 Отменить напоминание", codec.Encode(cbReminderCancel, callbackID(reminderID))),
+		),
+	)
+}
+
+// CreateWeatherKeyboard создает кнопки переключения между текущей погодой и прогнозом.
+// Кнопка текущего режима не показывается.
+func CreateWeatherKeyboard(codec *CallbackCodec, lat, lon float64, current string) tgbotapi.InlineKeyboardMarkup {
+	modes := []struct{ Mode, Label string }{
+		{weatherModeNow, "🌡️ Сейчас"},
+		{weatherModeToday, "📅 Сегодня"},
+		{weatherModeTomorrow, "📅 Завтра"},
+		{weatherModeDays, "📆 5 дней"},
+	}
+
+	var row []tgbotapi.InlineKeyboardButton
+	for _, mode := range modes {
+		if mode.Mode == current {
+			continue
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(mode.Label,
+			codec.Encode(cbWeather, mode.Mode, weatherCoord(lat), weatherCoord(lon))))
 	}
+	return tgbotapi.NewInlineKeyboardMarkup(row)
 }
@@ -0,0 +1,482 @@
+package bot
+
+import (
+	"GreenAssistantBot/internal/database"
+	"GreenAssistantBot/internal/database/models"
+	"GreenAssistantBot/internal/telegram"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+)
+
+// updateFunc обрабатывает одно обновление
+type updateFunc func(ctx context.Context, update tgbotapi.Update)
+
+// middleware оборачивает обработку обновления: может сделать что-то до и после
+// next или не вызывать его вовсе
+type middleware func(next updateFunc) updateFunc
+
+// Имена middleware для UPDATE_MIDDLEWARES
+const (
+	mwTracing   = "tracing"
+	mwMetrics   = "metrics"
+	mwLogging   = "logging"
+	mwRecovery  = "recovery"
+	mwAccess    = "access"
+	mwRateLimit = "ratelimit"
+	mwRegister  = "register"
+)
+
+// defaultMiddlewares — порядок по умолчанию, первый оборачивает остальные.
+// Трассировка идет первой, чтобы ID был в логах всех остальных, а метрики —
+// снаружи recovery, чтобы учитывать и обновления, на которых случилась паника.
+var defaultMiddlewares = []string{mwTracing, mwMetrics, mwLogging, mwRecovery, mwAccess, mwRateLimit, mwRegister}
+
+const (
+	// Обновления, которые обрабатываются дольше, попадают в лог
+	slowUpdateThreshold = 2 * time.Second
+	// Сколько обновлений в минуту по умолчанию принимается от одного чата
+	defaultUserRateLimit = 60
+)
+
+// Почему обновление не дошло до обработчика
+const (
+	dropDenied  = "denied"
+	dropBot     = "bot"
+	dropLimited = "limited"
+)
+
+// updateMeta — сведения об обновлении, которые middleware передают друг другу
+// и обработчикам через контекст
+type updateMeta struct {
+	TraceID string
+	// Dropped — почему обновление отброшено, пусто, если обработано
+	Dropped  string
+	Panicked bool
+}
+
+type updateMetaKey struct{}
+
+// updateMetaFrom возвращает сведения об обновлении из контекста
+func updateMetaFrom(ctx context.Context) *updateMeta {
+	if meta, ok := ctx.Value(updateMetaKey{}).(*updateMeta); ok {
+		return meta
+	}
+	return &updateMeta{}
+}
+
+// chainMiddlewares оборачивает handler в middlewares; первый из них выполняется первым
+func chainMiddlewares(handler updateFunc, middlewares ...middleware) updateFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// middlewareOrderFromEnv читает порядок middleware из UPDATE_MIDDLEWARES,
+// например "tracing,logging,recovery". Middleware, которых нет в списке, отключены.
+func middlewareOrderFromEnv() []string {
+	value := os.Getenv("UPDATE_MIDDLEWARES")
+	if value == "" {
+		return defaultMiddlewares
+	}
+
+	var order []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(strings.ToLower(name)); name != "" {
+			order = append(order, name)
+		}
+	}
+	return order
+}
+
+// middlewares создает middleware в порядке order. При неизвестном имени
+// используется порядок по умолчанию.
+func (h *UpdateHandler) middlewares(order []string) []middleware {
+	available := map[string]func() middleware{
+		mwTracing:   tracingMiddleware,
+		mwMetrics:   h.metrics.middleware,
+		mwLogging:   loggingMiddleware,
+		mwRecovery:  h.recoveryMiddleware,
+		mwAccess:    accessMiddleware,
+		mwRateLimit: h.rateLimitMiddleware,
+		mwRegister:  h.registrationMiddleware,
+	}
+
+	var result []middleware
+	for _, name := range order {
+		create, ok := available[name]
+		if !ok {
+			log.Printf("Unknown middleware %q in UPDATE_MIDDLEWARES, using default order", name)
+			return h.middlewares(defaultMiddlewares)
+		}
+		result = append(result, create())
+	}
+	return result
+}
+
+// tracingMiddleware присваивает обновлению ID трассировки, по которому в логе
+// можно найти все записи об этом обновлении
+func tracingMiddleware() middleware {
+	return func(next updateFunc) updateFunc {
+		return func(ctx context.Context, update tgbotapi.Update) {
+			updateMetaFrom(ctx).TraceID = newTraceID()
+			next(ctx, update)
+		}
+	}
+}
+
+func newTraceID() string {
+	buf := make([]byte, 4)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// loggingMiddleware пишет в лог входящие сообщения, нажатия кнопок и медленные обновления
+func loggingMiddleware() middleware {
+	return func(next updateFunc) updateFunc {
+		return func(ctx context.Context, update tgbotapi.Update) {
+			traceID := updateMetaFrom(ctx).TraceID
+
+			switch {
+			case update.CallbackQuery != nil:
+				query := update.CallbackQuery
+				log.Printf("[%d] %s: callback %s", query.From.ID, traceID, query.Data)
+			case update.Message != nil:
+				message := update.Message
+				log.Printf("[%d] %s: %s", message.Chat.ID, traceID, message.Text)
+
+				// Проверяем, является ли сообщение пересланным
+				if message.ForwardFrom != nil || message.ForwardFromChat != nil || message.ForwardSenderName != "" {
+					log.Printf("Forwarded message: From=%v, FromChat=%v, SenderName=%s",
+						message.ForwardFrom,
+						message.ForwardFromChat,
+						message.ForwardSenderName)
+				}
+			}
+
+			start := time.Now()
+			next(ctx, update)
+			if elapsed := time.Since(start); elapsed > slowUpdateThreshold {
+				log.Printf("Slow update %d (trace %s) handled in %s", update.UpdateID, traceID, elapsed)
+			}
+		}
+	}
+}
+
+// recoveryMiddleware перехватывает панику обработчика и сообщает пользователю об ошибке
+func (h *UpdateHandler) recoveryMiddleware() middleware {
+	return func(next updateFunc) updateFunc {
+		return func(ctx context.Context, update tgbotapi.Update) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+
+				meta := updateMetaFrom(ctx)
+				meta.Panicked = true
+				log.Printf("Panic while handling update %d (trace %s): %v\n%s", update.UpdateID, meta.TraceID, r, debug.Stack())
+
+				if query := update.CallbackQuery; query != nil {
+					if err := h.sender.AnswerCallback(query.ID, "⚠️ Произошла ошибка"); err != nil {
+						log.Printf("Error answering callback query: %v", err)
+					}
+				} else if chatID := updateChatID(update); chatID != 0 {
+					_, err := h.sender.SendText(telegram.Text{
+						ChatID:      chatID,
+						Text:        "⚠️ Произошла ошибка. Попробуйте еще раз.",
+						ReplyMarkup: CreateMainMenuKeyboard(),
+					})
+					if err != nil {
+						log.Printf("Error sending error message: %v", err)
+					}
+				}
+			}()
+
+			next(ctx, update)
+		}
+	}
+}
+
+// accessMiddleware пропускает только людей и, если задан ADMIN_CHAT_ID, только чат администратора
+func accessMiddleware() middleware {
+	return func(next updateFunc) updateFunc {
+		return func(ctx context.Context, update tgbotapi.Update) {
+			if from := update.SentFrom(); from != nil && from.IsBot {
+				updateMetaFrom(ctx).Dropped = dropBot
+				return
+			}
+			if !isAllowedChat(updateChatID(update)) {
+				updateMetaFrom(ctx).Dropped = dropDenied
+				return
+			}
+			next(ctx, update)
+		}
+	}
+}
+
+// isAllowedChat ограничивает работу бота чатом администратора, если задан ADMIN_CHAT_ID
+func isAllowedChat(chatID int64) bool {
+	adminChatID := os.Getenv("ADMIN_CHAT_ID")
+	if adminChatID != "" && adminChatID != fmt.Sprintf("%d", chatID) {
+		log.Printf("Chat id: %d", chatID)
+		return false
+	}
+	return true
+}
+
+// rateLimitMiddleware отбрасывает обновления чата сверх USER_RATE_LIMIT в минуту.
+// О превышении лимита пользователь узнает один раз, пока лимит не восстановится.
+func (h *UpdateHandler) rateLimitMiddleware() middleware {
+	limiter := newChatRateLimiter(userRateLimitFromEnv(), time.Now)
+
+	return func(next updateFunc) updateFunc {
+		return func(ctx context.Context, update tgbotapi.Update) {
+			chatID := updateChatID(update)
+			allowed, notify := limiter.Allow(chatID)
+			if allowed {
+				next(ctx, update)
+				return
+			}
+
+			updateMetaFrom(ctx).Dropped = dropLimited
+			log.Printf("Rate limit exceeded for chat %d, dropping update %d", chatID, update.UpdateID)
+			if query := update.CallbackQuery; query != nil {
+				if err := h.sender.AnswerCallback(query.ID, "⏳ Слишком много запросов"); err != nil {
+					log.Printf("Error answering callback query: %v", err)
+				}
+			} else if notify {
+				if _, err := h.sender.SendText(telegram.Text{ChatID: chatID, Text: "⏳ Слишком много запросов. Подождите немного и попробуйте снова."}); err != nil {
+					log.Printf("Error sending message: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// userRateLimitFromEnv читает лимит обновлений в минуту из USER_RATE_LIMIT; 0 отключает лимит
+func userRateLimitFromEnv() int {
+	value := os.Getenv("USER_RATE_LIMIT")
+	if value == "" {
+		return defaultUserRateLimit
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 0 {
+		log.Printf("Invalid USER_RATE_LIMIT %q, using %d", value, defaultUserRateLimit)
+		return defaultUserRateLimit
+	}
+	return limit
+}
+
+// chatRateLimiter — лимит обновлений от чатов: у каждого чата limit жетонов,
+// которые восстанавливаются равномерно за минуту
+type chatRateLimiter struct {
+	limit int
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[int64]*rateBucket
+	lastSweep time.Time
+}
+
+type rateBucket struct {
+	tokens  float64
+	updated time.Time
+	// warned — пользователь уже предупрежден о превышении лимита
+	warned bool
+}
+
+func newChatRateLimiter(limit int, now func() time.Time) *chatRateLimiter {
+	return &chatRateLimiter{limit: limit, now: now, buckets: make(map[int64]*rateBucket), lastSweep: now()}
+}
+
+// Allow расходует жетон чата. notify сообщает, что обновление отброшено
+// впервые с момента последнего успешного.
+func (l *chatRateLimiter) Allow(chatID int64) (allowed, notify bool) {
+	if l.limit <= 0 {
+		return true, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweepLocked(now)
+
+	bucket, ok := l.buckets[chatID]
+	if !ok {
+		bucket = &rateBucket{tokens: float64(l.limit), updated: now}
+		l.buckets[chatID] = bucket
+	}
+
+	perSecond := float64(l.limit) / time.Minute.Seconds()
+	bucket.tokens = min(float64(l.limit), bucket.tokens+now.Sub(bucket.updated).Seconds()*perSecond)
+	bucket.updated = now
+
+	if bucket.tokens < 1 {
+		notify = !bucket.warned
+		bucket.warned = true
+		return false, notify
+	}
+	bucket.tokens--
+	bucket.warned = false
+	return true, false
+}
+
+// sweepLocked раз в минуту удаляет чаты, которые давно ничего не присылали.
+// Вызывается под l.mu.
+func (l *chatRateLimiter) sweepLocked(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	for chatID, bucket := range l.buckets {
+		if now.Sub(bucket.updated) >= time.Minute {
+			delete(l.buckets, chatID)
+		}
+	}
+}
+
+// registrationMiddleware сохраняет в базе пользователя, впервые написавшего боту
+func (h *UpdateHandler) registrationMiddleware() middleware {
+	return func(next updateFunc) updateFunc {
+		return func(ctx context.Context, update tgbotapi.Update) {
+			if update.Message != nil && update.Message.From != nil {
+				h.registerUser(update.Message)
+			}
+			next(ctx, update)
+		}
+	}
+}
+
+// registerUser создает пользователя, если его еще нет в базе. Известные
+// пользователи запоминаются, чтобы не обращаться к базе на каждое сообщение.
+func (h *UpdateHandler) registerUser(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	if _, known := h.knownUsers.Load(chatID); known {
+		return
+	}
+
+	exists, err := database.UserExists(chatID)
+	if err != nil {
+		log.Printf("Error checking user: %v", err)
+		return
+	}
+	if exists {
+		h.knownUsers.Store(chatID, struct{}{})
+		return
+	}
+
+	user := &models.User{
+		TelegramID: chatID,
+		UserName:   message.From.UserName,
+		FirstName:  message.From.FirstName,
+		LastName:   message.From.LastName,
+	}
+	if err := database.SaveOrUpdateUser(user); err != nil {
+		log.Printf("Error saving user: %v", err)
+		return
+	}
+	h.knownUsers.Store(chatID, struct{}{})
+}
+
+// profileIncomplete сообщает, что пользователь еще не прошел анкету. Имя при
+// регистрации берется из Telegram, поэтому анкета считается пройденной, когда
+// указан город.
+func profileIncomplete(chatID int64) bool {
+	user, err := database.GetUserByTelegramID(chatID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true
+	}
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		return false
+	}
+	return user.City == ""
+}
+
+// updateMetrics — счетчики обработанных обновлений
+type updateMetrics struct {
+	total     atomic.Int64
+	messages  atomic.Int64
+	callbacks atomic.Int64
+	denied    atomic.Int64
+	limited   atomic.Int64
+	panics    atomic.Int64
+	// Время обработки в микросекундах
+	totalMicros atomic.Int64
+	maxMicros   atomic.Int64
+}
+
+func (m *updateMetrics) middleware() middleware {
+	return func(next updateFunc) updateFunc {
+		return func(ctx context.Context, update tgbotapi.Update) {
+			start := time.Now()
+			next(ctx, update)
+			elapsed := time.Since(start).Microseconds()
+
+			m.total.Add(1)
+			switch {
+			case update.CallbackQuery != nil:
+				m.callbacks.Add(1)
+			case update.Message != nil:
+				m.messages.Add(1)
+			}
+
+			meta := updateMetaFrom(ctx)
+			switch meta.Dropped {
+			case dropDenied, dropBot:
+				m.denied.Add(1)
+			case dropLimited:
+				m.limited.Add(1)
+			}
+			if meta.Panicked {
+				m.panics.Add(1)
+			}
+
+			m.totalMicros.Add(elapsed)
+			for {
+				current := m.maxMicros.Load()
+				if elapsed <= current || m.maxMicros.CompareAndSwap(current, elapsed) {
+					break
+				}
+			}
+		}
+	}
+}
+
+// GetStats возвращает счетчики обработки обновлений для мониторинга
+func (m *updateMetrics) GetStats() map[string]interface{} {
+	total := m.total.Load()
+	var average time.Duration
+	if total > 0 {
+		average = time.Duration(m.totalMicros.Load()/total) * time.Microsecond
+	}
+
+	return map[string]interface{}{
+		"total":     total,
+		"messages":  m.messages.Load(),
+		"callbacks": m.callbacks.Load(),
+		"denied":    m.denied.Load(),
+		"limited":   m.limited.Load(),
+		"panics":    m.panics.Load(),
+		"avg":       average.String(),
+		"max":       (time.Duration(m.maxMicros.Load()) * time.Microsecond).String(),
+	}
+}
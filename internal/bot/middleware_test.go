@@ -0,0 +1,155 @@
+package bot
+
+import (
+	"GreenAssistantBot/internal/telegram/telegramtest"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// runPipeline пропускает обновление через middlewares и возвращает сведения о нем
+// и признак того, что обновление дошло до обработчика
+func runPipeline(update tgbotapi.Update, handler updateFunc, middlewares ...middleware) (*updateMeta, bool) {
+	handled := false
+	final := func(ctx context.Context, update tgbotapi.Update) {
+		handled = true
+		if handler != nil {
+			handler(ctx, update)
+		}
+	}
+
+	meta := &updateMeta{}
+	chainMiddlewares(final, middlewares...)(context.WithValue(context.Background(), updateMetaKey{}, meta), update)
+	return meta, handled
+}
+
+func TestChainMiddlewaresOrder(t *testing.T) {
+	var calls []string
+	named := func(name string) middleware {
+		return func(next updateFunc) updateFunc {
+			return func(ctx context.Context, update tgbotapi.Update) {
+				calls = append(calls, name+" before")
+				next(ctx, update)
+				calls = append(calls, name+" after")
+			}
+		}
+	}
+
+	runPipeline(chatUpdate(1, 1), func(context.Context, tgbotapi.Update) {
+		calls = append(calls, "handler")
+	}, named("a"), named("b"))
+
+	want := []string{"a before", "b before", "handler", "b after", "a after"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestMiddlewareOrderFromEnv(t *testing.T) {
+	t.Setenv("UPDATE_MIDDLEWARES", " Tracing, recovery,,access ")
+	if got := middlewareOrderFromEnv(); !reflect.DeepEqual(got, []string{"tracing", "recovery", "access"}) {
+		t.Errorf("unexpected order %v", got)
+	}
+
+	h := &UpdateHandler{sender: telegramtest.NewRecorder()}
+	if got := len(h.middlewares([]string{"tracing", "unknown"})); got != len(defaultMiddlewares) {
+		t.Errorf("unknown middleware should fall back to default order, got %d middlewares", got)
+	}
+}
+
+func TestAccessMiddleware(t *testing.T) {
+	t.Setenv("ADMIN_CHAT_ID", "1")
+
+	if meta, handled := runPipeline(chatUpdate(1, 1), nil, accessMiddleware()); !handled || meta.Dropped != "" {
+		t.Errorf("admin chat must pass, dropped %q", meta.Dropped)
+	}
+	if meta, handled := runPipeline(chatUpdate(1, 2), nil, accessMiddleware()); handled || meta.Dropped != dropDenied {
+		t.Errorf("other chat must be denied, dropped %q", meta.Dropped)
+	}
+
+	fromBot := chatUpdate(1, 1)
+	fromBot.Message.From.IsBot = true
+	if meta, handled := runPipeline(fromBot, nil, accessMiddleware()); handled || meta.Dropped != dropBot {
+		t.Errorf("bot messages must be dropped, dropped %q", meta.Dropped)
+	}
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	recorder := telegramtest.NewRecorder()
+	h := &UpdateHandler{sender: recorder}
+
+	meta, _ := runPipeline(chatUpdate(1, 42), func(context.Context, tgbotapi.Update) {
+		panic("boom")
+	}, h.recoveryMiddleware())
+
+	if !meta.Panicked {
+		t.Error("expected panic to be recorded")
+	}
+	sent := recorder.Take()
+	if len(sent) != 1 || sent[0].ChatID != 42 || !strings.Contains(sent[0].Text, "Произошла ошибка") {
+		t.Errorf("expected error message to the user, got %+v", sent)
+	}
+
+	callback := tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:      "q1",
+		From:    &tgbotapi.User{ID: 42},
+		Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 42}},
+	}}
+	runPipeline(callback, func(context.Context, tgbotapi.Update) { panic("boom") }, h.recoveryMiddleware())
+	if sent := recorder.Take(); len(sent) != 1 || sent[0].Kind != telegramtest.SentCallback || sent[0].QueryID != "q1" {
+		t.Errorf("expected callback answer, got %+v", sent)
+	}
+}
+
+func TestChatRateLimiter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newChatRateLimiter(3, func() time.Time { return now })
+
+	for i := 0; i < 3; i++ {
+		if allowed, _ := limiter.Allow(1); !allowed {
+			t.Fatalf("update %d should be allowed", i+1)
+		}
+	}
+	if allowed, notify := limiter.Allow(1); allowed || !notify {
+		t.Errorf("4th update: allowed=%v notify=%v, want rejected with notification", allowed, notify)
+	}
+	if allowed, notify := limiter.Allow(1); allowed || notify {
+		t.Errorf("5th update: allowed=%v notify=%v, want rejected silently", allowed, notify)
+	}
+	if allowed, _ := limiter.Allow(2); !allowed {
+		t.Error("other chats must not be limited")
+	}
+
+	// 3 обновления в минуту — один жетон восстанавливается за 20 секунд
+	now = now.Add(20 * time.Second)
+	if allowed, _ := limiter.Allow(1); !allowed {
+		t.Error("token should be restored after 20s")
+	}
+	if allowed, notify := limiter.Allow(1); allowed || !notify {
+		t.Errorf("after recovery the user should be warned again: allowed=%v notify=%v", allowed, notify)
+	}
+
+	unlimited := newChatRateLimiter(0, time.Now)
+	for i := 0; i < 100; i++ {
+		if allowed, _ := unlimited.Allow(1); !allowed {
+			t.Fatal("zero limit must disable rate limiting")
+		}
+	}
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	t.Setenv("ADMIN_CHAT_ID", "1")
+	var metrics updateMetrics
+
+	runPipeline(chatUpdate(1, 1), nil, metrics.middleware(), accessMiddleware())
+	runPipeline(chatUpdate(2, 2), nil, metrics.middleware(), accessMiddleware())
+
+	stats := metrics.GetStats()
+	if stats["total"] != int64(2) || stats["messages"] != int64(2) || stats["denied"] != int64(1) {
+		t.Errorf("unexpected stats: %v", stats)
+	}
+}
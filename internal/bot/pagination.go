@@ -0,0 +1,233 @@
+package bot
+
+import (
+	"GreenAssistantBot/internal/database"
+	pmodel "GreenAssistantBot/pkg/models"
+	"fmt"
+	"html"
+	"log"
+	"os"
+	"strconv"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Режимы списка заметок
+const (
+	noteListAll    = "a" // заметки категории, кнопка открывает заметку
+	noteListMedia  = "m" // только медиа-заметки
+	noteListEdit   = "e" // выбор заметки для редактирования
+	noteListDelete = "d" // выбор заметки для удаления
+	noteListSearch = "s" // результаты поиска, запрос хранится в сессии
+	noteListTags   = "t" // заметки со всеми выбранными тегами
+)
+
+const (
+	defaultNotesPageSize = 5
+	// Больше кнопок в одном сообщении неудобно листать
+	maxNotesPageSize = 20
+)
+
+// noteList — страница списка заметок. Описание кодируется в кнопки
+// навигации, поэтому листание не зависит от состояния пользователя.
+// Исключение — поисковый запрос: он не помещается в callback_data
+// и берется из сессии.
+type noteList struct {
+	Mode       string
+	CategoryID uint
+	Page       int
+	Query      string
+	TagIDs     []uint
+}
+
+func (l noteList) filter() database.NoteFilter {
+	return database.NoteFilter{
+		CategoryID: l.CategoryID,
+		MediaOnly:  l.Mode == noteListMedia,
+		Query:      l.Query,
+		TagIDs:     l.TagIDs,
+	}
+}
+
+// itemAction возвращает действие кнопки заметки в списке
+func (l noteList) itemAction() string {
+	switch l.Mode {
+	case noteListEdit:
+		return cbNoteEdit
+	case noteListDelete:
+		return cbNoteDelete
+	default:
+		return cbNoteOpen
+	}
+}
+
+// replyKeyboard возвращает обычную клавиатуру, соответствующую режиму списка
+func (l noteList) replyKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	switch l.Mode {
+	case noteListEdit, noteListDelete:
+		return CreateNotesManagementKeyboard()
+	case noteListSearch:
+		return CreateNotesMenuKeyboard()
+	case noteListTags:
+		return CreateTagsMenuKeyboard()
+	default:
+		return CreateNotesViewKeyboard()
+	}
+}
+
+// notesPageSizeFromEnv читает размер страницы из NOTES_PAGE_SIZE
+func notesPageSizeFromEnv() int {
+	value := os.Getenv("NOTES_PAGE_SIZE")
+	if value == "" {
+		return defaultNotesPageSize
+	}
+
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 1 || size > maxNotesPageSize {
+		log.Printf("Invalid NOTES_PAGE_SIZE %q, using %d", value, defaultNotesPageSize)
+		return defaultNotesPageSize
+	}
+	return size
+}
+
+// renderNoteList загружает страницу списка и формирует HTML-текст с кнопками.
+// Если страница вышла за пределы (например, после удаления), показывается последняя.
+func (h *NotesHandler) renderNoteList(chatID int64, list noteList) (string, tgbotapi.InlineKeyboardMarkup, int64, error) {
+	if list.Page < 0 {
+		list.Page = 0
+	}
+
+	notes, total, err := database.GetUserNotesPage(chatID, list.filter(), h.pageSize, list.Page*h.pageSize)
+	if err != nil {
+		return "", tgbotapi.InlineKeyboardMarkup{}, 0, err
+	}
+
+	totalPages := int((total + int64(h.pageSize) - 1) / int64(h.pageSize))
+	if len(notes) == 0 && total > 0 {
+		list.Page = totalPages - 1
+		notes, total, err = database.GetUserNotesPage(chatID, list.filter(), h.pageSize, list.Page*h.pageSize)
+		if err != nil {
+			return "", tgbotapi.InlineKeyboardMarkup{}, 0, err
+		}
+	}
+
+	text := h.noteListTitle(chatID, list, total)
+	if list.Mode == noteListSearch {
+		terms := database.SearchTerms(list.Query)
+		for i, note := range notes {
+			text += fmt.Sprintf("\n\n%d. %s %s", i+1, getNoteTypeEmoji(note.Type),
+				searchSnippet(note.Content+" "+note.Caption, terms, searchSnippetRadius))
+		}
+	}
+	if totalPages > 1 {
+		text += fmt.Sprintf("\n\n📄 Страница %d из %d", list.Page+1, totalPages)
+	}
+
+	return text, CreateNoteListKeyboard(h.msgHandler.callbacks, list, notes, totalPages), total, nil
+}
+
+func (h *NotesHandler) noteListTitle(chatID int64, list noteList, total int64) string {
+	switch list.Mode {
+	case noteListEdit, noteListDelete:
+		return "📋 Выберите заметку:"
+	case noteListMedia:
+		return fmt.Sprintf("📸 Медиа-заметок: %d", total)
+	case noteListSearch:
+		return fmt.Sprintf("🔍 Найдено по запросу «%s»: %d", html.EscapeString(list.Query), total)
+	case noteListTags:
+		tags, _ := database.GetTagsByIDs(chatID, list.TagIDs)
+		names := make([]string, len(tags))
+		for i, tag := range tags {
+			names[i] = tag.Name
+		}
+		return fmt.Sprintf("🏷️ Заметок с тегами %s: %d", html.EscapeString(formatTags(names)), total)
+	}
+
+	if list.CategoryID == 0 {
+		return fmt.Sprintf("📋 Всего заметок: %d", total)
+	}
+	category, _ := database.GetCategoryByID(chatID, list.CategoryID)
+	if category != nil {
+		return fmt.Sprintf("📋 Заметок в категории \"%s\": %d", html.EscapeString(category.Name), total)
+	}
+	return fmt.Sprintf("📋 Заметок: %d", total)
+}
+
+// sendNoteList отправляет первую страницу списка новым сообщением
+func (h *NotesHandler) sendNoteList(chatID int64, list noteList, emptyText string) {
+	text, keyboard, total, err := h.renderNoteList(chatID, list)
+	if err != nil {
+		log.Printf("Error getting notes: %v", err)
+		h.msgHandler.sendMessage(chatID, "❌ Ошибка при загрузке заметок", list.replyKeyboard())
+		return
+	}
+
+	if total == 0 {
+		h.msgHandler.sendMessage(chatID, emptyText, list.replyKeyboard())
+		return
+	}
+
+	// Inline-кнопки и обычную клавиатуру нельзя прикрепить к одному сообщению
+	if list.Mode != noteListEdit && list.Mode != noteListDelete {
+		h.msgHandler.sendMessage(chatID, "👇 Нажмите на заметку, чтобы открыть ее", list.replyKeyboard())
+	}
+
+	h.msgHandler.sendHTML(chatID, text, keyboard)
+}
+
+// onNotesPage перелистывает список, редактируя то же сообщение
+func (h *NotesHandler) onNotesPage(query *tgbotapi.CallbackQuery, args []string) string {
+	if len(args) < 3 {
+		return "⚠️ Неверная кнопка"
+	}
+	categoryID, ok := callbackArgID(args, 1)
+	page, err := strconv.Atoi(args[2])
+	if !ok || err != nil {
+		return "⚠️ Неверная кнопка"
+	}
+
+	list := noteList{Mode: args[0], CategoryID: categoryID, Page: page}
+	if len(args) > 3 {
+		list.TagIDs = callbackArgIDs(args[3])
+	}
+	if list.Mode == noteListTags && len(list.TagIDs) == 0 {
+		return "⚠️ Неверная кнопка"
+	}
+	if list.Mode == noteListSearch {
+		session, _ := h.storage.GetSession(query.Message.Chat.ID)
+		if session.Purpose != pmodel.PurposeSearchNotes || session.Query == "" {
+			return "⚠️ Поиск устарел, повторите запрос"
+		}
+		list.Query = session.Query
+	}
+	text, keyboard, total, err := h.renderNoteList(query.Message.Chat.ID, list)
+	if err != nil {
+		log.Printf("Error getting notes: %v", err)
+		return "❌ Ошибка при загрузке заметок"
+	}
+	if total == 0 {
+		h.editMessageText(query.Message, "📝 Заметок больше нет")
+		return ""
+	}
+
+	h.editMessageWithKeyboard(query.Message, text, keyboard)
+	return ""
+}
+
+// onNoteOpen отправляет заметку с кнопками действий
+func (h *NotesHandler) onNoteOpen(query *tgbotapi.CallbackQuery, args []string) string {
+	chatID := query.Message.Chat.ID
+	noteID, ok := callbackArgID(args, 0)
+	if !ok {
+		return "⚠️ Неверная кнопка"
+	}
+
+	note, err := database.GetNoteByID(chatID, noteID)
+	if err != nil {
+		log.Printf("Error getting note: %v", err)
+		return "❌ Заметка не найдена"
+	}
+
+	h.sendNotePreview(chatID, *note)
+	return ""
+}
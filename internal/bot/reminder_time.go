@@ -0,0 +1,178 @@
+package bot
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Время напоминания, если указан только день
+const defaultReminderHour = 9
+
+var (
+	// "через 2 часа", "через минуту", "через полчаса"
+	relativeTimePattern = regexp.MustCompile(`^через\s+(?:(\d+)\s+)?(полчаса|минуту|минуты|минут|мин|час|часа|часов|ч|день|дня|дней|неделю|недели|недель)$`)
+	// "25.12", "25.12.2026 10:00", "25.12 в 10"
+	dateTimePattern = regexp.MustCompile(`^(\d{1,2})\.(\d{1,2})(?:\.(\d{2}|\d{4}))?(?:\s+(?:в\s+)?(\d{1,2})(?::(\d{2}))?)?$`)
+	// "2026-12-25", "2026-12-25 10:00"
+	isoDateTimePattern = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})(?:[\st](\d{1,2}):(\d{2}))?$`)
+	// "завтра в 9", "в пятницу в 18:30", "в 9 вечера", "сегодня"
+	dayTimePattern = regexp.MustCompile(`^(?:(сегодня|завтра|послезавтра)|(?:во?\s+)?(понедельник|вторник|среду|среда|четверг|пятницу|пятница|субботу|суббота|воскресенье))?\s*(?:(?:в\s+)?(\d{1,2})(?:[:.](\d{2}))?(?:\s+(утра|дня|вечера|ночи))?)?$`)
+)
+
+var relativeDays = map[string]int{"сегодня": 0, "завтра": 1, "послезавтра": 2}
+
+var weekdays = map[string]time.Weekday{
+	"понедельник": time.Monday,
+	"вторник":     time.Tuesday,
+	"среду":       time.Wednesday,
+	"среда":       time.Wednesday,
+	"четверг":     time.Thursday,
+	"пятницу":     time.Friday,
+	"пятница":     time.Friday,
+	"субботу":     time.Saturday,
+	"суббота":     time.Saturday,
+	"воскресенье": time.Sunday,
+}
+
+// parseReminderTime разбирает время напоминания: относительное ("через 2 часа"),
+// день и время ("завтра в 9", "в пятницу в 18:30") или дату ("25.12 10:00").
+// Время считается в часовом поясе now (см. userNow) и должно быть в будущем.
+func parseReminderTime(text string, now time.Time) (time.Time, bool) {
+	text = strings.Join(strings.Fields(strings.ToLower(strings.ReplaceAll(text, "ё", "е"))), " ")
+	if text == "" {
+		return time.Time{}, false
+	}
+
+	var at time.Time
+	var ok bool
+	switch {
+	case relativeTimePattern.MatchString(text):
+		at, ok = parseRelativeTime(relativeTimePattern.FindStringSubmatch(text), now)
+	case dateTimePattern.MatchString(text):
+		at, ok = parseDateTime(dateTimePattern.FindStringSubmatch(text), now)
+	case isoDateTimePattern.MatchString(text):
+		m := isoDateTimePattern.FindStringSubmatch(text)
+		at, ok = buildTime(now, atoi(m[1]), atoi(m[2]), atoi(m[3]), m[4], m[5])
+	case dayTimePattern.MatchString(text):
+		at, ok = parseDayTime(dayTimePattern.FindStringSubmatch(text), now)
+	}
+
+	if !ok || !at.After(now) {
+		return time.Time{}, false
+	}
+	return at, true
+}
+
+func parseRelativeTime(m []string, now time.Time) (time.Time, bool) {
+	amount := 1
+	if m[1] != "" {
+		amount = atoi(m[1])
+	}
+	if amount <= 0 {
+		return time.Time{}, false
+	}
+
+	switch unit := m[2]; {
+	case unit == "полчаса":
+		if m[1] != "" {
+			return time.Time{}, false
+		}
+		return now.Add(30 * time.Minute), true
+	case strings.HasPrefix(unit, "мин"):
+		return now.Add(time.Duration(amount) * time.Minute), true
+	case strings.HasPrefix(unit, "ч"):
+		return now.Add(time.Duration(amount) * time.Hour), true
+	case strings.HasPrefix(unit, "нед"):
+		return now.AddDate(0, 0, 7*amount), true
+	default:
+		return now.AddDate(0, 0, amount), true
+	}
+}
+
+func parseDateTime(m []string, now time.Time) (time.Time, bool) {
+	day, month := atoi(m[1]), atoi(m[2])
+
+	year := now.Year()
+	if m[3] != "" {
+		year = atoi(m[3])
+		if year < 100 {
+			year += 2000
+		}
+	}
+
+	at, ok := buildTime(now, year, month, day, m[4], m[5])
+	// Дата без года, которая уже прошла, относится к следующему году
+	if ok && m[3] == "" && !at.After(now) {
+		at, ok = buildTime(now, year+1, month, day, m[4], m[5])
+	}
+	return at, ok
+}
+
+func parseDayTime(m []string, now time.Time) (time.Time, bool) {
+	dayWord, weekdayWord, hourText, minuteText, period := m[1], m[2], m[3], m[4], m[5]
+	if dayWord == "" && weekdayWord == "" && hourText == "" {
+		return time.Time{}, false
+	}
+
+	hour, minute := defaultReminderHour, 0
+	if hourText != "" {
+		hour, minute = atoi(hourText), atoi(minuteText)
+		switch period {
+		case "дня", "вечера":
+			if hour < 12 {
+				hour += 12
+			}
+		case "ночи", "утра":
+			if hour == 12 {
+				hour = 0
+			}
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return time.Time{}, false
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+	switch {
+	case dayWord != "":
+		return today.AddDate(0, 0, relativeDays[dayWord]), true
+	case weekdayWord != "":
+		// День недели всегда означает ближайший следующий, а не сегодняшний
+		days := (int(weekdays[weekdayWord]) - int(now.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return today.AddDate(0, 0, days), true
+	default:
+		// Только время: сегодня, а если оно уже прошло — завтра
+		if !today.After(now) {
+			today = today.AddDate(0, 0, 1)
+		}
+		return today, true
+	}
+}
+
+// buildTime собирает время из частей, проверяя, что дата существует
+func buildTime(now time.Time, year, month, day int, hourText, minuteText string) (time.Time, bool) {
+	hour, minute := defaultReminderHour, 0
+	if hourText != "" {
+		hour, minute = atoi(hourText), atoi(minuteText)
+	}
+	if month < 1 || month > 12 || day < 1 || hour > 23 || minute > 59 {
+		return time.Time{}, false
+	}
+
+	at := time.Date(year, time.Month(month), day, hour, minute, 0, 0, now.Location())
+	// time.Date нормализует 31.02 в 03.03 — такие даты считаем ошибкой
+	if at.Day() != day || int(at.Month()) != month {
+		return time.Time{}, false
+	}
+	return at, true
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,69 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseReminderTime(t *testing.T) {
+	msk := time.FixedZone("MSK", 3*60*60)
+	// Среда, 14 октября 2026, 12:00
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, msk)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, msk)
+	}
+
+	tests := []struct {
+		text string
+		want time.Time
+		ok   bool
+	}{
+		// Относительное время
+		{"через 2 часа", now.Add(2 * time.Hour), true},
+		{"через минуту", now.Add(time.Minute), true},
+		{"через 15 минут", now.Add(15 * time.Minute), true},
+		{"через полчаса", now.Add(30 * time.Minute), true},
+		{"через 3 дня", now.AddDate(0, 0, 3), true},
+		{"через неделю", now.AddDate(0, 0, 7), true},
+		{"через 2 полчаса", time.Time{}, false},
+		{"через 0 минут", time.Time{}, false},
+
+		// День и время
+		{"завтра", at(time.October, 15, 9, 0), true},
+		{"Завтра  в 18:30", at(time.October, 15, 18, 30), true},
+		{"послезавтра в 7.15", at(time.October, 16, 7, 15), true},
+		{"в 9 вечера", at(time.October, 14, 21, 0), true},
+		{"в 10", at(time.October, 15, 10, 0), true},
+		{"в 12 ночи", at(time.October, 15, 0, 0), true},
+		{"в пятницу в 18:30", at(time.October, 16, 18, 30), true},
+		{"в среду", at(time.October, 21, 9, 0), true},
+		{"во вторник", at(time.October, 20, 9, 0), true},
+		{"сегодня", time.Time{}, false},
+		{"в 25", time.Time{}, false},
+
+		// Даты
+		{"25.12", at(time.December, 25, 9, 0), true},
+		{"25.12 в 10", at(time.December, 25, 10, 0), true},
+		{"25.12.26 10:30", at(time.December, 25, 10, 30), true},
+		{"01.01", time.Date(2027, time.January, 1, 9, 0, 0, 0, msk), true},
+		{"2026-12-25 10:00", at(time.December, 25, 10, 0), true},
+		{"2026-12-25T10:00", at(time.December, 25, 10, 0), true},
+		{"31.02", time.Time{}, false},
+		{"01.01.2026", time.Time{}, false},
+		{"2025-12-25", time.Time{}, false},
+
+		{"", time.Time{}, false},
+		{"когда-нибудь", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseReminderTime(tt.text, now)
+		if ok != tt.ok {
+			t.Errorf("parseReminderTime(%q) ok = %v, want %v", tt.text, ok, tt.ok)
+			continue
+		}
+		if ok && !got.Equal(tt.want) {
+			t.Errorf("parseReminderTime(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
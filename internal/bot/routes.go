@@ -0,0 +1,446 @@
+package bot
+
+import (
+	"GreenAssistantBot/internal/database"
+	"GreenAssistantBot/internal/database/models"
+	"GreenAssistantBot/internal/weather"
+	pmodel "GreenAssistantBot/pkg/models"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// menuID — reply-клавиатура, в которой размещаются кнопки команд
+type menuID string
+
+const (
+	menuMain            menuID = "main"
+	menuSettings        menuID = "settings"
+	menuProfile         menuID = "profile"
+	menuWeatherAlerts   menuID = "weather_alerts"
+	menuWeatherPrefs    menuID = "weather_preferences"
+	menuNotes           menuID = "notes"
+	menuNotesView       menuID = "notes_view"
+	menuNotesManagement menuID = "notes_management"
+	menuCategories      menuID = "categories_management"
+	menuTags            menuID = "tags"
+)
+
+// Тексты кнопок, которые используются и за пределами реестра команд
+const (
+	btnBack        = "⬅️ Назад"
+	btnHome        = "🏠 В начало"
+	btnBackToNotes = "⬅️ Назад к заметкам"
+	btnNewCategory = "➕ Новая категория"
+	btnAlertsOn    = "🔔 Включить предупреждения"
+	btnAlertsOff   = "🔕 Выключить предупреждения"
+)
+
+// routeHandler обрабатывает нажатие кнопки или слеш-команду
+type routeHandler func(h *UpdateHandler, ctx context.Context, message *tgbotapi.Message)
+
+// placement — место кнопки в клавиатуре: ряд и позиция в ряду
+type placement struct {
+	Menu     menuID
+	Row, Col int
+}
+
+func at(menu menuID, row, col int) placement {
+	return placement{Menu: menu, Row: row, Col: col}
+}
+
+// route — команда бота. Кнопка с текстом Label и слеш-команда /Command ведут
+// к одному обработчику.
+type route struct {
+	Label string
+	// Command — слеш-команда без "/"
+	Command string
+	// Description — описание команды в меню Telegram; без него команда в меню не попадает
+	Description string
+	// Menus — клавиатуры, в которых есть кнопка
+	Menus  []placement
+	Handle routeHandler
+}
+
+// commandRouter — реестр команд. По нему распознаются нажатия кнопок и
+// слеш-команды, строятся клавиатуры и список команд для setMyCommands.
+type commandRouter struct {
+	routes    []*route
+	byLabel   map[string]*route
+	byCommand map[string]*route
+}
+
+func newCommandRouter() *commandRouter {
+	return &commandRouter{
+		byLabel:   make(map[string]*route),
+		byCommand: make(map[string]*route),
+	}
+}
+
+func (r *commandRouter) Register(rt route) {
+	registered := &rt
+	r.routes = append(r.routes, registered)
+
+	if rt.Label != "" {
+		if _, exists := r.byLabel[rt.Label]; exists {
+			log.Printf("Warning: button %q registered twice", rt.Label)
+		}
+		r.byLabel[rt.Label] = registered
+	}
+	if rt.Command != "" {
+		if _, exists := r.byCommand[rt.Command]; exists {
+			log.Printf("Warning: command /%s registered twice", rt.Command)
+		}
+		r.byCommand[rt.Command] = registered
+	}
+}
+
+// Match находит команду по слеш-команде или тексту кнопки
+func (r *commandRouter) Match(text string) (*route, bool) {
+	if name, _, ok := parseCommand(text); ok {
+		rt, found := r.byCommand[name]
+		return rt, found
+	}
+	rt, ok := r.byLabel[text]
+	return rt, ok
+}
+
+// IsCommand проверяет, совпадает ли текст с кнопкой или слеш-командой
+func (r *commandRouter) IsCommand(text string) bool {
+	_, ok := r.Match(text)
+	return ok
+}
+
+// parseCommand разбирает слеш-команду: "/search кофе" и "/start@GreenAssistantBot"
+// возвращают имя команды без "/" и упоминания бота и ее аргументы
+func parseCommand(text string) (name, args string, ok bool) {
+	if !strings.HasPrefix(text, "/") {
+		return "", "", false
+	}
+
+	command, args, _ := strings.Cut(text[1:], " ")
+	name, _, _ = strings.Cut(command, "@")
+	if name == "" {
+		return "", "", false
+	}
+	return name, strings.TrimSpace(args), true
+}
+
+// Rows возвращает ряды кнопок клавиатуры menu
+func (r *commandRouter) Rows(menu menuID) [][]tgbotapi.KeyboardButton {
+	type cell struct {
+		placement
+		label string
+	}
+
+	var cells []cell
+	for _, rt := range r.routes {
+		for _, place := range rt.Menus {
+			if place.Menu == menu {
+				cells = append(cells, cell{placement: place, label: rt.Label})
+			}
+		}
+	}
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].Row != cells[j].Row {
+			return cells[i].Row < cells[j].Row
+		}
+		return cells[i].Col < cells[j].Col
+	})
+
+	var rows [][]tgbotapi.KeyboardButton
+	for i, c := range cells {
+		if i == 0 || c.Row != cells[i-1].Row {
+			rows = append(rows, nil)
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], tgbotapi.NewKeyboardButton(c.label))
+	}
+	return rows
+}
+
+// Keyboard строит reply-клавиатуру menu
+func (r *commandRouter) Keyboard(menu menuID) tgbotapi.ReplyKeyboardMarkup {
+	return tgbotapi.NewReplyKeyboard(r.Rows(menu)...)
+}
+
+// BotCommands возвращает слеш-команды с описанием для меню команд Telegram
+func (r *commandRouter) BotCommands() []tgbotapi.BotCommand {
+	var commands []tgbotapi.BotCommand
+	for _, rt := range r.routes {
+		if rt.Command != "" && rt.Description != "" {
+			commands = append(commands, tgbotapi.BotCommand{Command: rt.Command, Description: rt.Description})
+		}
+	}
+	return commands
+}
+
+// routes — все команды бота. Заполняется в init: обработчики сами строят
+// клавиатуры по этому реестру.
+var routes = newCommandRouter()
+
+func init() {
+	registerRoutes(routes)
+}
+
+// BotCommands возвращает список команд для setMyCommands
+func BotCommands() []tgbotapi.BotCommand {
+	return routes.BotCommands()
+}
+
+// messageAction — обработчик, которому нужен только ID чата
+func messageAction(action func(*MessageHandler, int64)) routeHandler {
+	return func(h *UpdateHandler, ctx context.Context, message *tgbotapi.Message) {
+		action(h.msgHandler, message.Chat.ID)
+	}
+}
+
+func notesAction(action func(*NotesHandler, int64)) routeHandler {
+	return func(h *UpdateHandler, ctx context.Context, message *tgbotapi.Message) {
+		action(h.notesHandler, message.Chat.ID)
+	}
+}
+
+// weatherPreference сохраняет настройку погоды column со значением value
+func weatherPreference(column, value string) routeHandler {
+	return func(h *UpdateHandler, ctx context.Context, message *tgbotapi.Message) {
+		h.msgHandler.HandleWeatherPreference(message.Chat.ID, column, value)
+	}
+}
+
+// selectCategory предлагает выбрать категорию для цели purpose
+func selectCategory(purpose pmodel.Purpose) routeHandler {
+	return func(h *UpdateHandler, ctx context.Context, message *tgbotapi.Message) {
+		h.notesHandler.SendCategoriesForSelection(message.Chat.ID, purpose)
+	}
+}
+
+// selectNote предлагает выбрать заметку для цели purpose
+func selectNote(purpose pmodel.Purpose) routeHandler {
+	return func(h *UpdateHandler, ctx context.Context, message *tgbotapi.Message) {
+		h.notesHandler.SendNotesForSelection(message.Chat.ID, purpose)
+	}
+}
+
+// registerRoutes описывает все кнопки и команды бота
+func registerRoutes(r *commandRouter) {
+	// Главное меню
+	r.Register(route{Command: "start", Description: "Главное меню", Handle: (*UpdateHandler).onStart})
+	r.Register(route{Label: "ℹ️ Информация", Command: "help", Description: "Информация о боте",
+		Menus: []placement{at(menuMain, 0, 0)}, Handle: messageAction((*MessageHandler).SendInfo)})
+	r.Register(route{Label: "📞 Поддержка",
+		Menus: []placement{at(menuMain, 0, 1)}, Handle: messageAction((*MessageHandler).SendSupport)})
+	r.Register(route{Label: "🌡️Погода", Command: "weather", Description: "Погода",
+		Menus: []placement{at(menuMain, 1, 0)}, Handle: (*UpdateHandler).onWeather})
+	r.Register(route{Label: "📒 Заметки", Command: "notes", Description: "Заметки",
+		Menus: []placement{at(menuMain, 1, 1)}, Handle: notesAction((*NotesHandler).SendNotesMenu)})
+	r.Register(route{Label: "⚙️ Настройки", Command: "settings", Description: "Настройки",
+		Menus: []placement{at(menuMain, 2, 0)}, Handle: messageAction((*MessageHandler).SendSettingsMenu)})
+	// Внутри сценария /cancel обрабатывает машина состояний, здесь — вне сценария
+	r.Register(route{Command: "cancel", Description: "Отменить текущее действие",
+		Handle: messageAction((*MessageHandler).SendMainMenu)})
+
+	// Настройки
+	r.Register(route{Label: "🔔 Уведомления",
+		Menus: []placement{at(menuSettings, 0, 0)}, Handle: messageAction((*MessageHandler).SendNotificationsSettings)})
+	r.Register(route{Label: "👤 Профиль",
+		Menus: []placement{at(menuSettings, 0, 1)}, Handle: messageAction((*MessageHandler).SendProfileSettings)})
+	r.Register(route{Label: "🌡️ Уведомления о погоде",
+		Menus: []placement{at(menuSettings, 1, 0)}, Handle: (*UpdateHandler).onToggleWeatherNotifications})
+	r.Register(route{Label: "⏰ Время уведомлений",
+		Menus: []placement{at(menuSettings, 1, 1)}, Handle: messageAction((*MessageHandler).AskForNotificationTime)})
+	r.Register(route{Label: "🕒 Часовой пояс",
+		Menus: []placement{at(menuSettings, 2, 0)}, Handle: messageAction((*MessageHandler).AskForTimezone)})
+	r.Register(route{Label: "⚠️ Предупреждения",
+		Menus: []placement{at(menuSettings, 2, 1)}, Handle: messageAction((*MessageHandler).SendWeatherAlertSettings)})
+	r.Register(route{Label: "📏 Единицы и язык",
+		Menus: []placement{at(menuSettings, 3, 0)}, Handle: messageAction((*MessageHandler).SendWeatherPreferences)})
+	r.Register(route{Label: "📍 Мои места",
+		Menus: []placement{at(menuSettings, 3, 1)}, Handle: messageAction((*MessageHandler).SendLocations)})
+
+	// Профиль
+	r.Register(route{Label: "✏️ Ваше имя",
+		Menus: []placement{at(menuProfile, 0, 0)}, Handle: (*UpdateHandler).onChangeName})
+	r.Register(route{Label: "🚩 Ваш город",
+		Menus: []placement{at(menuProfile, 0, 1)}, Handle: (*UpdateHandler).onChangeCity})
+
+	// Погодные предупреждения; кнопку включения клавиатура добавляет сама
+	r.Register(route{Label: btnAlertsOn, Handle: func(h *UpdateHandler, ctx context.Context, message *tgbotapi.Message) {
+		h.msgHandler.SetWeatherAlerts(message.Chat.ID, true)
+	}})
+	r.Register(route{Label: btnAlertsOff, Handle: func(h *UpdateHandler, ctx context.Context, message *tgbotapi.Message) {
+		h.msgHandler.SetWeatherAlerts(message.Chat.ID, false)
+	}})
+	r.Register(route{Label: "🥶 Порог мороза",
+		Menus: []placement{at(menuWeatherAlerts, 0, 0)}, Handle: messageAction((*MessageHandler).AskForFrostThreshold)})
+	r.Register(route{Label: "🌙 Тихие часы",
+		Menus: []placement{at(menuWeatherAlerts, 0, 1)}, Handle: messageAction((*MessageHandler).AskForQuietHours)})
+
+	// Единицы и язык погоды
+	r.Register(route{Label: "🌡️ °C, м/с",
+		Menus: []placement{at(menuWeatherPrefs, 0, 0)}, Handle: weatherPreference("units", weather.UnitsMetric)})
+	r.Register(route{Label: "🌡️ °F, mph",
+		Menus: []placement{at(menuWeatherPrefs, 0, 1)}, Handle: weatherPreference("units", weather.UnitsImperial)})
+	r.Register(route{Label: "📊 hPa",
+		Menus: []placement{at(menuWeatherPrefs, 1, 0)}, Handle: weatherPreference("pressure_unit", weather.PressureHPa)})
+	r.Register(route{Label: "📊 мм рт. ст.",
+		Menus: []placement{at(menuWeatherPrefs, 1, 1)}, Handle: weatherPreference("pressure_unit", weather.PressureMmHg)})
+	r.Register(route{Label: "🗣️ Русский",
+		Menus: []placement{at(menuWeatherPrefs, 2, 0)}, Handle: weatherPreference("language", weather.LangRussian)})
+	r.Register(route{Label: "🗣️ English",
+		Menus: []placement{at(menuWeatherPrefs, 2, 1)}, Handle: weatherPreference("language", weather.LangEnglish)})
+
+	// Заметки
+	r.Register(route{Label: "📝 Новая заметка",
+		Menus:  []placement{at(menuNotes, 0, 0), at(menuNotesView, 0, 0)},
+		Handle: selectCategory(pmodel.PurposeNewNote)})
+	r.Register(route{Label: "📁 Мои заметки",
+		Menus: []placement{at(menuNotes, 0, 1)}, Handle: (*UpdateHandler).onMyNotes})
+	r.Register(route{Label: "📸 Медиа-заметки",
+		Menus: []placement{at(menuNotesView, 0, 1)}, Handle: (*UpdateHandler).onMediaNotes})
+	r.Register(route{Label: "🛠️ Управление заметками",
+		Menus:  []placement{at(menuNotes, 1, 0), at(menuNotesView, 1, 0)},
+		Handle: notesAction((*NotesHandler).SendNotesManagementMenu)})
+	r.Register(route{Label: "📂 Управление категориями",
+		Menus: []placement{at(menuNotes, 1, 1)}, Handle: notesAction((*NotesHandler).SendCategoriesMenu)})
+	r.Register(route{Label: "🔍 Поиск", Command: "search", Description: "Поиск по заметкам",
+		Menus: []placement{at(menuNotes, 2, 0)}, Handle: func(h *UpdateHandler, ctx context.Context, message *tgbotapi.Message) {
+			// У кнопки аргументов нет, и поиск спросит запрос
+			_, query, _ := parseCommand(message.Text)
+			h.notesHandler.SearchNotes(message.Chat.ID, query)
+		}})
+	r.Register(route{Label: "🏷️ Теги",
+		Menus:  []placement{at(menuNotes, 2, 1), at(menuTags, 1, 0)},
+		Handle: notesAction((*NotesHandler).SendTagCloud)})
+	r.Register(route{Label: "🗑️ Корзина",
+		Menus: []placement{at(menuNotes, 3, 0)}, Handle: notesAction((*NotesHandler).SendTrash)})
+
+	// Управление заметками
+	r.Register(route{Label: "✏️ Редактировать заметку",
+		Menus: []placement{at(menuNotesManagement, 0, 0)}, Handle: selectNote(pmodel.PurposeEditNote)})
+	r.Register(route{Label: "🗑️ Удалить заметку",
+		Menus: []placement{at(menuNotesManagement, 0, 1)}, Handle: selectNote(pmodel.PurposeDeleteNote)})
+
+	// Категории
+	r.Register(route{Label: "➕ Создать категорию",
+		Menus: []placement{at(menuCategories, 0, 0)}, Handle: notesAction((*NotesHandler).AskForCategoryName)})
+	r.Register(route{Label: "✏️ Редактировать категории",
+		Menus: []placement{at(menuCategories, 0, 1)}, Handle: notesAction((*NotesHandler).SendEditCategoriesMenu)})
+	r.Register(route{Label: "🗑️ Удалить категорию",
+		Menus: []placement{at(menuCategories, 1, 0)}, Handle: selectCategory(pmodel.PurposeDeleteCategory)})
+	r.Register(route{Label: btnNewCategory, Handle: notesAction((*NotesHandler).AskForCategoryName)})
+
+	// Теги
+	r.Register(route{Label: "✏️ Переименовать тег",
+		Menus: []placement{at(menuTags, 0, 0)}, Handle: notesAction((*NotesHandler).AskForTagRename)})
+	r.Register(route{Label: "🔀 Объединить теги",
+		Menus: []placement{at(menuTags, 0, 1)}, Handle: notesAction((*NotesHandler).AskForTagMerge)})
+
+	// Навигация
+	r.Register(route{Label: btnBackToNotes,
+		Menus: []placement{
+			at(menuNotesView, 1, 1), at(menuNotesManagement, 1, 0),
+			at(menuCategories, 1, 1), at(menuTags, 1, 1),
+		},
+		Handle: notesAction((*NotesHandler).SendNotesMenu)})
+	r.Register(route{Label: "⬅️ Назад к списку", Handle: func(h *UpdateHandler, ctx context.Context, message *tgbotapi.Message) {
+		h.notesHandler.SendUserNotes(message.Chat.ID, 0)
+	}})
+	r.Register(route{Label: btnBack,
+		Menus:  []placement{at(menuSettings, 4, 0), at(menuWeatherAlerts, 1, 0), at(menuWeatherPrefs, 3, 0), at(menuNotes, 3, 1)},
+		Handle: messageAction((*MessageHandler).SendMainMenu)})
+	r.Register(route{Label: btnHome,
+		Menus: []placement{at(menuProfile, 1, 0)}, Handle: messageAction((*MessageHandler).SendMainMenu)})
+}
+
+// onStart приветствует пользователя и, пока анкета не заполнена, начинает ее
+func (h *UpdateHandler) onStart(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	h.msgHandler.SendStartMessage(chatID)
+	// Обычно пользователя уже зарегистрировал middleware, но его можно отключить
+	h.registerUser(message)
+	if profileIncomplete(chatID) {
+		h.msgHandler.AskForName(chatID)
+	}
+}
+
+func (h *UpdateHandler) onChangeName(ctx context.Context, message *tgbotapi.Message) {
+	h.msgHandler.AskForName(message.Chat.ID)
+	h.storage.SetUserState(message.Chat.ID, StateChangingNameFromProfile)
+}
+
+func (h *UpdateHandler) onChangeCity(ctx context.Context, message *tgbotapi.Message) {
+	h.msgHandler.AskForCity(message.Chat.ID)
+	h.storage.SetUserState(message.Chat.ID, StateChangingCityFromProfile)
+}
+
+// onWeather показывает погоду в городе пользователя или предлагает выбрать место
+func (h *UpdateHandler) onWeather(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	user, err := database.GetUserByTelegramID(chatID)
+	var locations []models.UserLocation
+	if err == nil {
+		locations, _ = database.GetUserLocations(chatID)
+	}
+	if len(locations) > 0 {
+		h.msgHandler.AskForWeatherPlace(chatID, user, locations)
+	} else if err == nil && user.City != "" {
+		h.msgHandler.SendUserWeather(chatID, user)
+	} else {
+		h.msgHandler.sendMessage(chatID, "🌍 Введите название города или отправьте местоположение:", CreateCityInputKeyboard())
+		h.storage.SetUserState(chatID, StateWaitingForWeatherCity)
+	}
+}
+
+// onToggleWeatherNotifications включает или выключает ежедневную погоду
+func (h *UpdateHandler) onToggleWeatherNotifications(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	// Получаем текущее состояние уведомлений пользователя
+	user, err := database.GetUserByTelegramID(chatID)
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		h.msgHandler.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.", CreateSettingsMenuKeyboard())
+		return
+	}
+
+	// Изменяем состояние уведомлений
+	user.WeatherNotifications = !user.WeatherNotifications
+	err = database.SaveOrUpdateUser(user)
+	if err != nil {
+		log.Printf("Error updating user: %v", err)
+		h.msgHandler.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.", CreateSettingsMenuKeyboard())
+		return
+	}
+
+	// Отправляем подтверждение
+	status := fmt.Sprintf("включены и приходят в %s", database.UserNotificationTime(user))
+	if user.Timezone != "" {
+		status += fmt.Sprintf(" (%s)", user.Timezone)
+	}
+	if !user.WeatherNotifications {
+		status = "выключены"
+	}
+	h.msgHandler.sendMessage(chatID, fmt.Sprintf("Уведомления о погоде %s", status), CreateSettingsMenuKeyboard())
+}
+
+// onMyNotes предлагает выбрать категорию или сразу показывает все заметки
+func (h *UpdateHandler) onMyNotes(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	categories, err := database.GetUserCategories(chatID)
+	if err != nil || len(categories) == 0 {
+		// Если категорий нет, показываем все заметки
+		h.notesHandler.SendUserNotes(chatID, 0)
+	} else {
+		// Если есть категории, предлагаем выбрать
+		h.notesHandler.SendCategoriesForViewing(chatID)
+	}
+}
+
+func (h *UpdateHandler) onMediaNotes(ctx context.Context, message *tgbotapi.Message) {
+	// Берем ID категории из сессии или используем 0 (все категории)
+	session, _ := h.storage.GetSession(message.Chat.ID)
+	h.notesHandler.SendMediaNotes(message.Chat.ID, session.CategoryID)
+}
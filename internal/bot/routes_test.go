@@ -0,0 +1,137 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func keyboardLabels(keyboard tgbotapi.ReplyKeyboardMarkup) [][]string {
+	var rows [][]string
+	for _, row := range keyboard.Keyboard {
+		var labels []string
+		for _, button := range row {
+			labels = append(labels, button.Text)
+		}
+		rows = append(rows, labels)
+	}
+	return rows
+}
+
+func TestRouteKeyboards(t *testing.T) {
+	tests := []struct {
+		name     string
+		keyboard tgbotapi.ReplyKeyboardMarkup
+		want     [][]string
+	}{
+		{"main", CreateMainMenuKeyboard(), [][]string{
+			{"ℹ️ Информация", "📞 Поддержка"},
+			{"🌡️Погода", "📒 Заметки"},
+			{"⚙️ Настройки"},
+		}},
+		{"notes", CreateNotesMenuKeyboard(), [][]string{
+			{"📝 Новая заметка", "📁 Мои заметки"},
+			{"🛠️ Управление заметками", "📂 Управление категориями"},
+			{"🔍 Поиск", "🏷️ Теги"},
+			{"🗑️ Корзина", "⬅️ Назад"},
+		}},
+		{"notes view", CreateNotesViewKeyboard(), [][]string{
+			{"📝 Новая заметка", "📸 Медиа-заметки"},
+			{"🛠️ Управление заметками", "⬅️ Назад к заметкам"},
+		}},
+		{"weather alerts", CreateWeatherAlertsKeyboard(true), [][]string{
+			{"🔕 Выключить предупреждения"},
+			{"🥶 Порог мороза", "🌙 Тихие часы"},
+			{"⬅️ Назад"},
+		}},
+		{"weather preferences", CreateWeatherPreferencesKeyboard(), [][]string{
+			{"🌡️ °C, м/с", "🌡️ °F, mph"},
+			{"📊 hPa", "📊 мм рт. ст."},
+			{"🗣️ Русский", "🗣️ English"},
+			{"⬅️ Назад"},
+		}},
+	}
+
+	for _, tt := range tests {
+		if got := keyboardLabels(tt.keyboard); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s keyboard:\ngot  %v\nwant %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Каждая кнопка reply-клавиатур меню должна распознаваться как команда,
+// иначе нажатие будет предложено сохранить как заметку
+func TestMenuButtonsAreCommands(t *testing.T) {
+	keyboards := []tgbotapi.ReplyKeyboardMarkup{
+		CreateMainMenuKeyboard(), CreateSettingsMenuKeyboard(), CreateProfileMenuKeyboard(),
+		CreateNotesMenuKeyboard(), CreateNotesViewKeyboard(), CreateNotesManagementKeyboard(),
+		CreateCategoriesManagementKeyboard(), CreateTagsMenuKeyboard(), CreateWeatherPreferencesKeyboard(),
+		CreateWeatherAlertsKeyboard(false), CreateWeatherAlertsKeyboard(true),
+	}
+	for _, keyboard := range keyboards {
+		for _, row := range keyboard.Keyboard {
+			for _, button := range row {
+				if !isCommand(button.Text) {
+					t.Errorf("button %q is not a registered command", button.Text)
+				}
+			}
+		}
+	}
+
+	for _, rt := range routes.routes {
+		if rt.Handle == nil {
+			t.Errorf("route %q /%s has no handler", rt.Label, rt.Command)
+		}
+	}
+}
+
+func TestRouteMatch(t *testing.T) {
+	tests := []struct {
+		text      string
+		wantLabel string
+		wantMatch bool
+	}{
+		{"🔍 Поиск", "🔍 Поиск", true},
+		{"/search кофе", "🔍 Поиск", true},
+		{"/search@GreenAssistantBot", "🔍 Поиск", true},
+		{"/start", "", true},
+		{"/unknown", "", false},
+		{"поиск", "", false},
+		{"/", "", false},
+	}
+
+	for _, tt := range tests {
+		rt, ok := routes.Match(tt.text)
+		if ok != tt.wantMatch {
+			t.Errorf("Match(%q) ok = %v, want %v", tt.text, ok, tt.wantMatch)
+			continue
+		}
+		if ok && rt.Label != tt.wantLabel {
+			t.Errorf("Match(%q) = %q, want %q", tt.text, rt.Label, tt.wantLabel)
+		}
+	}
+
+	if _, args, _ := parseCommand("/search  молоко и хлеб "); args != "молоко и хлеб" {
+		t.Errorf("unexpected search args %q", args)
+	}
+}
+
+func TestBotCommands(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, command := range BotCommands() {
+		if seen[command.Command] {
+			t.Errorf("command /%s listed twice", command.Command)
+		}
+		seen[command.Command] = true
+		if command.Description == "" {
+			t.Errorf("command /%s has no description", command.Command)
+		}
+	}
+
+	for _, name := range []string{"start", "search", "cancel"} {
+		if !seen[name] {
+			t.Errorf("command /%s missing from setMyCommands list", name)
+		}
+	}
+}
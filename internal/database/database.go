@@ -51,6 +51,13 @@ func GetConnect() *gorm.DB {
 	return instance
 }
 
+// SetConnect подменяет подключение к базе, например базой SQLite в тестах.
+// После вызова GetConnect возвращает db и не подключается к MySQL.
+func SetConnect(db *gorm.DB) {
+	once.Do(func() {})
+	instance = db
+}
+
 func AutoMigrate() error {
 	db := GetConnect()
 
@@ -58,12 +65,22 @@ func AutoMigrate() error {
 		&models.User{},
 		&models.Category{},
 		&models.Note{},
+		&models.Tag{},
+		&models.NoteRevision{},
+		&models.Reminder{},
+		&models.WeatherAlert{},
+		&models.UserLocation{},
+		&models.Session{},
 	)
 
 	if err != nil {
 		return err
 	}
 
+	if err := ensureNotesFullTextIndex(db); err != nil {
+		return err
+	}
+
 	log.Println("GORM migrations completed successfully")
 	return nil
 }
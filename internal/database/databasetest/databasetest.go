@@ -0,0 +1,37 @@
+// Package databasetest подключает тесты к временной базе SQLite
+package databasetest
+
+import (
+	"GreenAssistantBot/internal/database"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Open открывает пустую базу SQLite во временном каталоге теста
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "bot.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	return db
+}
+
+// Setup открывает временную базу, делает ее подключением пакета database и создает таблицы
+func Setup(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	db := Open(t)
+	database.SetConnect(db)
+	if err := database.AutoMigrate(); err != nil {
+		t.Fatalf("migrate database: %v", err)
+	}
+	return db
+}
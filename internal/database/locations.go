@@ -0,0 +1,88 @@
+package database
+
+import (
+	"GreenAssistantBot/internal/database/models"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Сколько дополнительных мест может сохранить пользователь
+const MaxUserLocations = 5
+
+var (
+	ErrTooManyLocations = errors.New("too many saved locations")
+	ErrLocationExists   = errors.New("location with this label already exists")
+)
+
+// GetUserLocations возвращает сохраненные места пользователя в порядке добавления
+func GetUserLocations(telegramID int64) ([]models.UserLocation, error) {
+	db := GetConnect()
+
+	var locations []models.UserLocation
+	result := db.Where("telegram_id = ?", telegramID).Order("id ASC").Find(&locations)
+	return locations, result.Error
+}
+
+// GetNotifyLocations возвращает места, включенные в утреннее уведомление
+func GetNotifyLocations(telegramID int64) ([]models.UserLocation, error) {
+	db := GetConnect()
+
+	var locations []models.UserLocation
+	result := db.Where("telegram_id = ? AND notify = ?", telegramID, true).Order("id ASC").Find(&locations)
+	return locations, result.Error
+}
+
+// CreateUserLocation сохраняет новое место пользователя
+func CreateUserLocation(location *models.UserLocation) error {
+	db := GetConnect()
+
+	var count int64
+	if err := db.Model(&models.UserLocation{}).Where("telegram_id = ?", location.TelegramID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count >= MaxUserLocations {
+		return ErrTooManyLocations
+	}
+
+	var existing models.UserLocation
+	err := db.Where("telegram_id = ? AND label = ?", location.TelegramID, location.Label).First(&existing).Error
+	if err == nil {
+		return ErrLocationExists
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	return db.Create(location).Error
+}
+
+// ToggleUserLocationNotify переключает участие места в утреннем уведомлении
+func ToggleUserLocationNotify(telegramID int64, locationID uint) (*models.UserLocation, error) {
+	db := GetConnect()
+
+	var location models.UserLocation
+	if err := db.Where("id = ? AND telegram_id = ?", locationID, telegramID).First(&location).Error; err != nil {
+		return nil, err
+	}
+
+	location.Notify = !location.Notify
+	if err := db.Model(&location).Update("notify", location.Notify).Error; err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+// DeleteUserLocation удаляет место пользователя
+func DeleteUserLocation(telegramID int64, locationID uint) error {
+	db := GetConnect()
+
+	result := db.Where("id = ? AND telegram_id = ?", locationID, telegramID).Delete(&models.UserLocation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
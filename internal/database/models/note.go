@@ -2,6 +2,7 @@ package models
 
 import (
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 	"time"
 )
 
@@ -16,16 +17,27 @@ const (
 	NoteTypeFile  NoteType = "file"
 )
 
+// GormDBDataType хранит тип заметки в MySQL как ENUM, а в остальных базах
+// (например, SQLite в тестах) — обычной строкой
+func (NoteType) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if db.Dialector.Name() == "mysql" {
+		return "enum('text','photo','video','voice','link','file')"
+	}
+	return "varchar(16)"
+}
+
 type Note struct {
 	gorm.Model
 	TelegramID int64    `gorm:"not null"`
 	CategoryID uint     `gorm:"not null"`
-	Type       NoteType `gorm:"type:enum('text','photo','video','voice','link','file');not null"`
+	Type       NoteType `gorm:"not null"`
 	Content    string   `gorm:"type:text"`
 	FileID     string   `gorm:"size:500"`
 	Caption    string   `gorm:"type:text"`
+	Pinned     bool     `gorm:"default:false"`
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
 
 	Category Category `gorm:"foreignKey:CategoryID"`
+	Tags     []Tag    `gorm:"many2many:note_tags"`
 }
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// NoteRevision — состояние заметки до изменения текста, подписи или категории
+type NoteRevision struct {
+	ID         uint      `gorm:"primaryKey"`
+	NoteID     uint      `gorm:"not null;index"`
+	TelegramID int64     `gorm:"not null"`
+	CategoryID uint      `gorm:"not null"`
+	Content    string    `gorm:"type:text"`
+	Caption    string    `gorm:"type:text"`
+	CreatedAt  time.Time `gorm:"index"`
+
+	Category Category `gorm:"foreignKey:CategoryID"`
+}
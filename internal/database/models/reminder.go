@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Reminder — напоминание о заметке. После отправки заполняется SentAt,
+// отложенное напоминание получает новое RemindAt и снова ждет отправки.
+type Reminder struct {
+	ID         uint      `gorm:"primaryKey"`
+	NoteID     uint      `gorm:"not null;index"`
+	TelegramID int64     `gorm:"not null;index"`
+	RemindAt   time.Time `gorm:"not null;index"`
+	SentAt     *time.Time
+	Done       bool `gorm:"default:false"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+
+	Note Note `gorm:"foreignKey:NoteID"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Session хранит состояние диалога пользователя между перезапусками бота
+type Session struct {
+	ChatID int64  `gorm:"primaryKey;autoIncrement:false"`
+	State  string `gorm:"size:255"`
+	// StateChangedAt задается только SetUserState: строку могут создать и другие
+	// данные сессии, тогда состояния у чата нет и колонка остается NULL
+	StateChangedAt *time.Time
+	Data           string `gorm:"type:text"`
+	LastMessageID  int
+	MessageHistory string `gorm:"type:text"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time `gorm:"index"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// Tag — метка заметки. В отличие от категории, у заметки может быть несколько тегов.
+type Tag struct {
+	gorm.Model
+	TelegramID int64  `gorm:"not null;uniqueIndex:idx_tags_owner_name"`
+	Name       string `gorm:"size:100;not null;uniqueIndex:idx_tags_owner_name"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+
+	Notes []Note `gorm:"many2many:note_tags"`
+}
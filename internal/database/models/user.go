@@ -7,14 +7,61 @@ import (
 
 type User struct {
 	gorm.Model
-	TelegramID           int64  `gorm:"uniqueIndex;not null"`
-	UserName             string `gorm:"size:255"`
-	FirstName            string `gorm:"size:255"`
-	LastName             string `gorm:"size:255"`
-	City                 string `gorm:"size:255"`
-	WeatherNotifications bool   `gorm:"default:true"`
-	CreatedAt            time.Time
-	UpdatedAt            time.Time
+	TelegramID                int64  `gorm:"uniqueIndex;not null"`
+	UserName                  string `gorm:"size:255"`
+	FirstName                 string `gorm:"size:255"`
+	LastName                  string `gorm:"size:255"`
+	City                      string `gorm:"size:255"`
+	Country                   string `gorm:"size:2"` // код страны ISO 3166, например RU
+	Latitude                  float64
+	Longitude                 float64
+	WeatherNotifications      bool   `gorm:"default:true"`
+	Timezone                  string `gorm:"size:64"` // IANA-имя, например Europe/Moscow
+	NotificationTime          string `gorm:"size:5"`  // время уведомления о погоде, ЧЧ:ММ
+	LastWeatherNotificationAt *time.Time
+	WeatherAlerts             bool   `gorm:"default:false"`
+	FrostThreshold            int    `gorm:"default:-15"`           // °C, предупреждать о морозе при этой температуре и ниже
+	QuietHoursStart           string `gorm:"size:5"`                // начало тихих часов, ЧЧ:ММ
+	QuietHoursEnd             string `gorm:"size:5"`                // конец тихих часов, ЧЧ:ММ
+	Units                     string `gorm:"size:8;default:metric"` // metric или imperial
+	PressureUnit              string `gorm:"size:8;default:hpa"`    // hpa или mmhg
+	Language                  string `gorm:"size:5;default:ru"`     // язык описаний погоды
+	CreatedAt                 time.Time
+	UpdatedAt                 time.Time
+}
+
+// HasCoords сообщает, известны ли координаты города пользователя
+func (u *User) HasCoords() bool {
+	return u.Latitude != 0 || u.Longitude != 0
+}
+
+// Location возвращает часовой пояс пользователя или часовой пояс сервера, если он не задан
+func (u *User) Location() *time.Location {
+	if u.Timezone != "" {
+		if location, err := time.LoadLocation(u.Timezone); err == nil {
+			return location
+		}
+	}
+	return time.Local
+}
+
+// InQuietHours проверяет, попадает ли момент t в тихие часы пользователя
+// по его местному времени. Интервал может переходить через полночь.
+func (u *User) InQuietHours(t time.Time) bool {
+	start, errStart := time.Parse("15:04", u.QuietHoursStart)
+	end, errEnd := time.Parse("15:04", u.QuietHoursEnd)
+	if errStart != nil || errEnd != nil || start.Equal(end) {
+		return false
+	}
+
+	local := t.In(u.Location())
+	minutes := local.Hour()*60 + local.Minute()
+	from := start.Hour()*60 + start.Minute()
+	to := end.Hour()*60 + end.Minute()
+	if from < to {
+		return minutes >= from && minutes < to
+	}
+	return minutes >= from || minutes < to
 }
 
 // Добавляем константы состояний для заметок
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// UserLocation — дополнительное место пользователя (дом, офис, поездка).
+// Основной город хранится в User.City.
+type UserLocation struct {
+	ID         uint    `gorm:"primaryKey"`
+	TelegramID int64   `gorm:"not null;uniqueIndex:idx_user_location_label"`
+	Label      string  `gorm:"size:32;not null;uniqueIndex:idx_user_location_label"`
+	City       string  `gorm:"size:255;not null"`
+	Country    string  `gorm:"size:2"`
+	Latitude   float64 `gorm:"not null"`
+	Longitude  float64 `gorm:"not null"`
+	// Notify — включать ли место в утреннее уведомление о погоде
+	Notify    bool `gorm:"default:false"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
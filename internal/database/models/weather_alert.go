@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// WeatherAlert — отправленное пользователю погодное предупреждение. По этой
+// таблице одно и то же явление не отправляется дважды за местный день.
+type WeatherAlert struct {
+	ID         uint      `gorm:"primaryKey"`
+	TelegramID int64     `gorm:"not null;uniqueIndex:idx_weather_alert_event"`
+	Kind       string    `gorm:"size:32;not null;uniqueIndex:idx_weather_alert_event"`
+	EventDate  string    `gorm:"size:10;not null;uniqueIndex:idx_weather_alert_event"` // местная дата явления, ГГГГ-ММ-ДД
+	EventAt    time.Time `gorm:"not null"`
+	CreatedAt  time.Time `gorm:"index"`
+}
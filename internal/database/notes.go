@@ -3,6 +3,7 @@ package database
 import (
 	"GreenAssistantBot/internal/database/models"
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -55,14 +56,21 @@ func GetCategoryByID(telegramID int64, categoryID uint) (*models.Category, error
 func DeleteCategory(telegramID int64, categoryID uint) error {
 	db := GetConnect()
 
-	// Удаляем все заметки в категории
-	if err := db.Where("telegram_id = ? AND category_id = ?", telegramID, categoryID).Delete(&models.Note{}).Error; err != nil {
-		return err
-	}
-
-	// Удаляем саму категорию
-	result := db.Where("telegram_id = ? AND id = ?", telegramID, categoryID).Delete(&models.Category{})
-	return result.Error
+	// Заметки и категория получают одно время удаления,
+	// по нему RestoreCategory восстанавливает их вместе
+	deletedAt := time.Now()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Перемещаем в корзину все заметки категории
+		if err := tx.Model(&models.Note{}).Where("telegram_id = ? AND category_id = ?", telegramID, categoryID).
+			Update("deleted_at", deletedAt).Error; err != nil {
+			return err
+		}
+
+		// Перемещаем в корзину саму категорию
+		return tx.Model(&models.Category{}).Where("telegram_id = ? AND id = ?", telegramID, categoryID).
+			Update("deleted_at", deletedAt).Error
+	})
 }
 
 // Note operations
@@ -83,7 +91,7 @@ func GetUserNotes(telegramID int64, categoryID uint) ([]models.Note, error) {
 	}
 
 	// Исключаем удаленные заметки (deleted_at IS NULL)
-	result := query.Where("deleted_at IS NULL").Preload("Category").Order("created_at ASC").Find(&notes)
+	result := query.Where("deleted_at IS NULL").Preload("Category").Preload("Tags").Order("pinned DESC, created_at ASC").Find(&notes)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -91,11 +99,66 @@ func GetUserNotes(telegramID int64, categoryID uint) ([]models.Note, error) {
 	return notes, nil
 }
 
+// NoteFilter задает условия выборки заметок для постраничного просмотра
+type NoteFilter struct {
+	CategoryID uint   // 0 - все категории
+	MediaOnly  bool   // только фото, видео и голосовые
+	Query      string // поиск по тексту и подписи во всех категориях
+	TagIDs     []uint // заметки, у которых есть все перечисленные теги
+}
+
+// GetUserNotesPage возвращает одну страницу заметок и общее число заметок,
+// подходящих под фильтр. Порядок совпадает с GetUserNotes, а при поиске
+// сначала идут наиболее релевантные заметки.
+func GetUserNotesPage(telegramID int64, filter NoteFilter, limit, offset int) ([]models.Note, int64, error) {
+	db := GetConnect()
+	terms := SearchTerms(filter.Query)
+
+	scope := func(query *gorm.DB) *gorm.DB {
+		query = query.Where("telegram_id = ? AND deleted_at IS NULL", telegramID)
+		if filter.CategoryID > 0 {
+			query = query.Where("category_id = ?", filter.CategoryID)
+		}
+		if filter.MediaOnly {
+			query = query.Where("type IN ?", []models.NoteType{models.NoteTypePhoto, models.NoteTypeVideo, models.NoteTypeVoice})
+		}
+		if len(terms) > 0 {
+			query = searchCondition(query, terms)
+		}
+		if len(filter.TagIDs) > 0 {
+			query = query.Where("id IN (?)", GetConnect().Table("note_tags").
+				Select("note_id").
+				Where("tag_id IN ?", filter.TagIDs).
+				Group("note_id").
+				Having("COUNT(DISTINCT tag_id) = ?", len(filter.TagIDs)))
+		}
+		return query
+	}
+
+	var total int64
+	if err := db.Model(&models.Note{}).Scopes(scope).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := db.Scopes(scope).Preload("Category").Preload("Tags")
+	if len(terms) > 0 {
+		page = page.Order(searchRank(db, terms))
+	}
+
+	var notes []models.Note
+	result := page.Order("pinned DESC, created_at ASC, id ASC").Limit(limit).Offset(offset).Find(&notes)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	return notes, total, nil
+}
+
 func GetNoteByID(telegramID int64, noteID uint) (*models.Note, error) {
 	db := GetConnect()
 
 	var note models.Note
-	result := db.Where("telegram_id = ? AND id = ? AND deleted_at IS NULL", telegramID, noteID).Preload("Category").First(&note)
+	result := db.Where("telegram_id = ? AND id = ? AND deleted_at IS NULL", telegramID, noteID).Preload("Category").Preload("Tags").First(&note)
 	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 		return nil, result.Error
 	}
@@ -112,6 +175,37 @@ func DeleteNote(telegramID int64, noteID uint) error {
 	return result.Error
 }
 
+// SetNotePinned закрепляет или открепляет заметку
+func SetNotePinned(telegramID int64, noteID uint, pinned bool) error {
+	db := GetConnect()
+
+	if _, err := GetNoteByID(telegramID, noteID); err != nil {
+		return err
+	}
+
+	result := db.Model(&models.Note{}).Where("telegram_id = ? AND id = ?", telegramID, noteID).Update("pinned", pinned)
+	return result.Error
+}
+
+// MoveNote переносит заметку в другую категорию пользователя
+func MoveNote(telegramID int64, noteID, categoryID uint) error {
+	db := GetConnect()
+
+	note, err := GetNoteByID(telegramID, noteID)
+	if err != nil {
+		return err
+	}
+	if _, err := GetCategoryByID(telegramID, categoryID); err != nil {
+		return err
+	}
+
+	updates := *note
+	updates.CategoryID = categoryID
+	return db.Transaction(func(tx *gorm.DB) error {
+		return updateNoteFields(tx, note, updates)
+	})
+}
+
 func GetNotesCountByCategory(telegramID int64, categoryID uint) (int64, error) {
 	db := GetConnect()
 	var count int64
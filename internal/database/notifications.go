@@ -0,0 +1,74 @@
+package database
+
+import (
+	"GreenAssistantBot/internal/database/models"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// DefaultNotificationTime возвращает время уведомления о погоде для пользователей,
+// которые не выбрали свое. Берется из WEATHER_NOTIFICATION_HOUR/MINUTE, по умолчанию 09:00.
+func DefaultNotificationTime() string {
+	hour := 9
+	if h, err := strconv.Atoi(os.Getenv("WEATHER_NOTIFICATION_HOUR")); err == nil && h >= 0 && h < 24 {
+		hour = h
+	}
+
+	minute := 0
+	if m, err := strconv.Atoi(os.Getenv("WEATHER_NOTIFICATION_MINUTE")); err == nil && m >= 0 && m < 60 {
+		minute = m
+	}
+
+	return fmt.Sprintf("%02d:%02d", hour, minute)
+}
+
+// UserNotificationTime возвращает время уведомления пользователя в формате ЧЧ:ММ
+func UserNotificationTime(user *models.User) string {
+	if user.NotificationTime != "" {
+		return user.NotificationTime
+	}
+	return DefaultNotificationTime()
+}
+
+// SetUserName сохраняет имя, которое пользователь ввел в анкете или профиле
+func SetUserName(telegramID int64, firstName string) error {
+	return updateUser(telegramID, map[string]interface{}{"first_name": firstName})
+}
+
+// SetUserCity сохраняет город пользователя вместе с найденными координатами и страной
+func SetUserCity(telegramID int64, city, country string, lat, lon float64) error {
+	return updateUser(telegramID, map[string]interface{}{
+		"city":      city,
+		"country":   country,
+		"latitude":  lat,
+		"longitude": lon,
+	})
+}
+
+// SetUserWeatherPreference сохраняет одну из настроек погоды: units, pressure_unit или language
+func SetUserWeatherPreference(telegramID int64, column, value string) error {
+	return updateUser(telegramID, map[string]interface{}{column: value})
+}
+
+// SetUserTimezone сохраняет часовой пояс пользователя
+func SetUserTimezone(telegramID int64, timezone string) error {
+	return updateUser(telegramID, map[string]interface{}{"timezone": timezone})
+}
+
+// SetUserNotificationTime сохраняет время уведомления о погоде
+func SetUserNotificationTime(telegramID int64, clock string) error {
+	return updateUser(telegramID, map[string]interface{}{"notification_time": clock})
+}
+
+// MarkWeatherNotified запоминает время последнего уведомления о погоде
+func MarkWeatherNotified(telegramID int64, at time.Time) error {
+	return updateUser(telegramID, map[string]interface{}{"last_weather_notification_at": at})
+}
+
+func updateUser(telegramID int64, updates map[string]interface{}) error {
+	db := GetConnect()
+
+	return db.Model(&models.User{}).Where("telegram_id = ?", telegramID).Updates(updates).Error
+}
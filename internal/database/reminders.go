@@ -0,0 +1,82 @@
+package database
+
+import (
+	"GreenAssistantBot/internal/database/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CreateReminder создает напоминание о заметке пользователя
+func CreateReminder(telegramID int64, noteID uint, remindAt time.Time) (*models.Reminder, error) {
+	db := GetConnect()
+
+	if _, err := GetNoteByID(telegramID, noteID); err != nil {
+		return nil, err
+	}
+
+	reminder := &models.Reminder{NoteID: noteID, TelegramID: telegramID, RemindAt: remindAt}
+	if err := db.Create(reminder).Error; err != nil {
+		return nil, err
+	}
+
+	return reminder, nil
+}
+
+// GetDueReminders возвращает неотправленные напоминания, время которых наступило.
+// Напоминания о заметках в корзине пропускаются до их восстановления.
+func GetDueReminders(now time.Time, limit int) ([]models.Reminder, error) {
+	db := GetConnect()
+
+	var reminders []models.Reminder
+	result := db.
+		Joins("JOIN notes ON notes.id = reminders.note_id AND notes.deleted_at IS NULL").
+		Where("reminders.remind_at <= ? AND reminders.sent_at IS NULL AND reminders.done = ?", now, false).
+		Preload("Note.Category").
+		Order("reminders.remind_at ASC").
+		Limit(limit).
+		Find(&reminders)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return reminders, nil
+}
+
+// MarkReminderSent отмечает напоминание отправленным
+func MarkReminderSent(reminderID uint, sentAt time.Time) error {
+	db := GetConnect()
+
+	return db.Model(&models.Reminder{}).Where("id = ?", reminderID).Update("sent_at", sentAt).Error
+}
+
+// SnoozeReminder откладывает напоминание пользователя до указанного времени.
+// Закрытые напоминания не откладываются, чтобы старая кнопка «Отложить» не
+// вернула напоминание, которое пользователь уже отметил выполненным.
+func SnoozeReminder(telegramID int64, reminderID uint, until time.Time) error {
+	db := GetConnect()
+
+	return updateReminder(db.Where("done = ?", false), telegramID, reminderID, map[string]interface{}{
+		"remind_at": until,
+		"sent_at":   nil,
+	})
+}
+
+// CompleteReminder закрывает напоминание пользователя
+func CompleteReminder(telegramID int64, reminderID uint) error {
+	return updateReminder(GetConnect(), telegramID, reminderID, map[string]interface{}{"done": true})
+}
+
+func updateReminder(db *gorm.DB, telegramID int64, reminderID uint, updates map[string]interface{}) error {
+	result := db.Model(&models.Reminder{}).
+		Where("telegram_id = ? AND id = ?", telegramID, reminderID).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
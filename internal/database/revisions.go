@@ -0,0 +1,119 @@
+package database
+
+import (
+	"GreenAssistantBot/internal/database/models"
+
+	"gorm.io/gorm"
+)
+
+// saveRevision сохраняет текущее состояние заметки перед изменением
+func saveRevision(tx *gorm.DB, note *models.Note) error {
+	revision := &models.NoteRevision{
+		NoteID:     note.ID,
+		TelegramID: note.TelegramID,
+		CategoryID: note.CategoryID,
+		Content:    note.Content,
+		Caption:    note.Caption,
+	}
+	return tx.Create(revision).Error
+}
+
+// updateNoteFields меняет заметку, предварительно сохранив ревизию.
+// Если значения не изменились, ревизия не создается.
+func updateNoteFields(tx *gorm.DB, note *models.Note, updates models.Note) error {
+	if note.Content == updates.Content && note.Caption == updates.Caption && note.CategoryID == updates.CategoryID {
+		return nil
+	}
+
+	if err := saveRevision(tx, note); err != nil {
+		return err
+	}
+
+	return tx.Model(&models.Note{}).Where("id = ?", note.ID).Updates(map[string]interface{}{
+		"content":     updates.Content,
+		"caption":     updates.Caption,
+		"category_id": updates.CategoryID,
+	}).Error
+}
+
+// UpdateNoteContent меняет текст заметки, сохраняя предыдущий в истории
+func UpdateNoteContent(telegramID int64, noteID uint, content string) (*models.Note, error) {
+	db := GetConnect()
+
+	note, err := GetNoteByID(telegramID, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := *note
+	updates.Content = content
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return updateNoteFields(tx, note, updates)
+	}); err != nil {
+		return nil, err
+	}
+
+	note.Content = content
+	return note, nil
+}
+
+// GetNoteRevisions возвращает историю заметки, начиная с последней ревизии
+func GetNoteRevisions(telegramID int64, noteID uint, limit int) ([]models.NoteRevision, error) {
+	db := GetConnect()
+
+	var revisions []models.NoteRevision
+	result := db.Where("telegram_id = ? AND note_id = ?", telegramID, noteID).
+		Preload("Category", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&revisions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return revisions, nil
+}
+
+func GetNoteRevision(telegramID int64, revisionID uint) (*models.NoteRevision, error) {
+	db := GetConnect()
+
+	var revision models.NoteRevision
+	result := db.Where("telegram_id = ? AND id = ?", telegramID, revisionID).
+		Preload("Category", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		First(&revision)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &revision, nil
+}
+
+// RollbackNote возвращает заметку к состоянию ревизии. Текущее состояние
+// тоже попадает в историю, поэтому откат можно отменить.
+func RollbackNote(telegramID int64, revisionID uint) (*models.Note, error) {
+	db := GetConnect()
+
+	revision, err := GetNoteRevision(telegramID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	note, err := GetNoteByID(telegramID, revision.NoteID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Если категория ревизии уже удалена, заметка остается в текущей
+	categoryID := revision.CategoryID
+	if _, err := GetCategoryByID(telegramID, categoryID); err != nil {
+		categoryID = note.CategoryID
+	}
+
+	updates := models.Note{Content: revision.Content, Caption: revision.Caption, CategoryID: categoryID}
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return updateNoteFields(tx, note, updates)
+	}); err != nil {
+		return nil, err
+	}
+
+	return GetNoteByID(telegramID, note.ID)
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"GreenAssistantBot/internal/database/models"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const notesFullTextIndex = "idx_notes_fulltext"
+
+// InnoDB не индексирует слова короче innodb_ft_min_token_size (3 по умолчанию)
+const minFullTextTermLength = 3
+
+// ensureNotesFullTextIndex создает FULLTEXT-индекс по тексту и подписи заметок.
+// Для драйверов кроме MySQL поиск работает через LIKE и индекс не нужен.
+func ensureNotesFullTextIndex(db *gorm.DB) error {
+	if db.Dialector.Name() != "mysql" {
+		return nil
+	}
+	if db.Migrator().HasIndex(&models.Note{}, notesFullTextIndex) {
+		return nil
+	}
+
+	log.Printf("Creating full-text index %s", notesFullTextIndex)
+	return db.Exec(fmt.Sprintf("CREATE FULLTEXT INDEX %s ON notes (content, caption)", notesFullTextIndex)).Error
+}
+
+// SearchTerms разбивает поисковый запрос на слова без служебных символов
+func SearchTerms(query string) []string {
+	cleaner := strings.NewReplacer(`"`, " ", "+", " ", "-", " ", "<", " ", ">", " ",
+		"(", " ", ")", " ", "~", " ", "*", " ", "@", " ")
+
+	return strings.Fields(cleaner.Replace(strings.ToLower(query)))
+}
+
+// useFullText решает, можно ли выполнить запрос через MATCH ... AGAINST
+func useFullText(db *gorm.DB, terms []string) bool {
+	if db.Dialector.Name() != "mysql" {
+		return false
+	}
+	for _, term := range terms {
+		if utf8.RuneCountInString(term) < minFullTextTermLength {
+			return false
+		}
+	}
+	return true
+}
+
+// fullTextQuery собирает запрос BOOLEAN MODE: любое из слов, с совпадением по началу слова
+func fullTextQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = term + "*"
+	}
+	return strings.Join(parts, " ")
+}
+
+// likePattern экранирует спецсимволы LIKE (экранирующий символ — "!")
+func likePattern(term string) string {
+	escaper := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+	return "%" + escaper.Replace(term) + "%"
+}
+
+// searchCondition ограничивает выборку заметками, содержащими хотя бы одно слово запроса
+func searchCondition(query *gorm.DB, terms []string) *gorm.DB {
+	if useFullText(query, terms) {
+		return query.Where("MATCH (content, caption) AGAINST (? IN BOOLEAN MODE)", fullTextQuery(terms))
+	}
+
+	var conditions []string
+	var vars []interface{}
+	for _, term := range terms {
+		pattern := likePattern(term)
+		conditions = append(conditions, "LOWER(content) LIKE ? ESCAPE '!' OR LOWER(caption) LIKE ? ESCAPE '!'")
+		vars = append(vars, pattern, pattern)
+	}
+	return query.Where("("+strings.Join(conditions, " OR ")+")", vars...)
+}
+
+// searchRank упорядочивает результаты по релевантности. Для LIKE релевантность —
+// число найденных слов, совпадение в тексте весит больше, чем в подписи.
+func searchRank(db *gorm.DB, terms []string) clause.OrderBy {
+	if useFullText(db, terms) {
+		return clause.OrderBy{Expression: clause.Expr{
+			SQL:  "MATCH (content, caption) AGAINST (? IN BOOLEAN MODE) DESC",
+			Vars: []interface{}{fullTextQuery(terms)},
+		}}
+	}
+
+	var scores []string
+	var vars []interface{}
+	for _, term := range terms {
+		pattern := likePattern(term)
+		scores = append(scores,
+			"CASE WHEN LOWER(content) LIKE ? ESCAPE '!' THEN 2 ELSE 0 END",
+			"CASE WHEN LOWER(caption) LIKE ? ESCAPE '!' THEN 1 ELSE 0 END")
+		vars = append(vars, pattern, pattern)
+	}
+	return clause.OrderBy{Expression: clause.Expr{
+		SQL:  "(" + strings.Join(scores, " + ") + ") DESC",
+		Vars: vars,
+	}}
+}
@@ -0,0 +1,193 @@
+package database
+
+import (
+	"GreenAssistantBot/internal/database/models"
+	"errors"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// Максимальная длина имени тега (совпадает с размером колонки tags.name)
+const maxTagNameLength = 100
+
+var (
+	hashtagPattern = regexp.MustCompile(`#([\p{L}\p{N}_]+)`)
+	tagNamePattern = regexp.MustCompile(`^[\p{L}\p{N}_]+$`)
+)
+
+var (
+	ErrTagExists    = errors.New("tag already exists")
+	ErrSameTag      = errors.New("cannot merge tag into itself")
+	ErrInvalidTag   = errors.New("invalid tag name")
+	ErrTagsNotFound = errors.New("tags not found")
+)
+
+// TagUsage — тег и количество заметок с ним (для облака тегов)
+type TagUsage struct {
+	ID    uint
+	Name  string
+	Count int64
+}
+
+// ExtractHashtags находит #хештеги в тексте. Имена приводятся к нижнему
+// регистру, повторы убираются, порядок появления сохраняется.
+func ExtractHashtags(text string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+
+	for _, match := range hashtagPattern.FindAllStringSubmatch(text, -1) {
+		name := NormalizeTagName(match[1])
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		tags = append(tags, name)
+	}
+	return tags
+}
+
+// NormalizeTagName приводит имя тега к виду, в котором он хранится в БД.
+// Возвращает пустую строку, если имя недопустимо.
+func NormalizeTagName(name string) string {
+	name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+	if !tagNamePattern.MatchString(name) || utf8.RuneCountInString(name) > maxTagNameLength {
+		return ""
+	}
+	return name
+}
+
+// SetNoteTags заменяет теги заметки, создавая недостающие теги пользователя
+func SetNoteTags(telegramID int64, note *models.Note, names []string) error {
+	db := GetConnect()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		tags := make([]models.Tag, 0, len(names))
+		for _, name := range names {
+			tag := models.Tag{TelegramID: telegramID, Name: name}
+			if err := tx.Where("telegram_id = ? AND name = ?", telegramID, name).FirstOrCreate(&tag).Error; err != nil {
+				return err
+			}
+			tags = append(tags, tag)
+		}
+
+		return tx.Model(note).Association("Tags").Replace(tags)
+	})
+}
+
+// GetTagCloud возвращает теги пользователя, отсортированные по числу заметок
+func GetTagCloud(telegramID int64) ([]TagUsage, error) {
+	db := GetConnect()
+
+	var cloud []TagUsage
+	result := db.Table("tags").
+		Select("tags.id, tags.name, COUNT(notes.id) AS count").
+		Joins("JOIN note_tags ON note_tags.tag_id = tags.id").
+		Joins("JOIN notes ON notes.id = note_tags.note_id AND notes.deleted_at IS NULL").
+		Where("tags.telegram_id = ? AND tags.deleted_at IS NULL", telegramID).
+		Group("tags.id, tags.name").
+		Order("count DESC, tags.name ASC").
+		Scan(&cloud)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return cloud, nil
+}
+
+func GetTagByName(telegramID int64, name string) (*models.Tag, error) {
+	db := GetConnect()
+
+	var tag models.Tag
+	result := db.Where("telegram_id = ? AND name = ?", telegramID, NormalizeTagName(name)).First(&tag)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &tag, nil
+}
+
+// GetTagsByIDs возвращает теги пользователя с указанными ID
+func GetTagsByIDs(telegramID int64, ids []uint) ([]models.Tag, error) {
+	db := GetConnect()
+
+	var tags []models.Tag
+	result := db.Where("telegram_id = ? AND id IN ?", telegramID, ids).Order("name ASC").Find(&tags)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(tags) == 0 {
+		return nil, ErrTagsNotFound
+	}
+
+	return tags, nil
+}
+
+// RenameTag переименовывает тег. Если тег с новым именем уже есть,
+// возвращает ErrTagExists — такие теги нужно объединять через MergeTags.
+func RenameTag(telegramID int64, oldName, newName string) error {
+	db := GetConnect()
+
+	newName = NormalizeTagName(newName)
+	if newName == "" {
+		return ErrInvalidTag
+	}
+
+	tag, err := GetTagByName(telegramID, oldName)
+	if err != nil {
+		return err
+	}
+	if tag.Name == newName {
+		return nil
+	}
+
+	if _, err := GetTagByName(telegramID, newName); err == nil {
+		return ErrTagExists
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	return db.Model(tag).Update("name", newName).Error
+}
+
+// MergeTags переносит заметки тега source в тег target и удаляет source
+func MergeTags(telegramID int64, sourceName, targetName string) error {
+	db := GetConnect()
+
+	source, err := GetTagByName(telegramID, sourceName)
+	if err != nil {
+		return err
+	}
+	target, err := GetTagByName(telegramID, targetName)
+	if err != nil {
+		return err
+	}
+	if source.ID == target.ID {
+		return ErrSameTag
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Заметки из корзины тоже переносятся, иначе после восстановления они
+		// останутся без тега: связи с source удаляются для всех заметок
+		var notes []models.Note
+		if err := tx.Unscoped().Model(source).Association("Notes").Find(&notes); err != nil {
+			return err
+		}
+
+		// Append не дублирует связь, если у заметки уже есть target
+		for i := range notes {
+			if err := tx.Unscoped().Model(&notes[i]).Association("Tags").Append(target); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(source).Association("Notes").Clear(); err != nil {
+			return err
+		}
+
+		// Удаляем физически, чтобы имя можно было использовать снова
+		return tx.Unscoped().Delete(source).Error
+	})
+}
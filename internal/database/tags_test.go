@@ -0,0 +1,62 @@
+package database_test
+
+import (
+	"GreenAssistantBot/internal/database"
+	"GreenAssistantBot/internal/database/databasetest"
+	"GreenAssistantBot/internal/database/models"
+	"testing"
+)
+
+func noteTagNames(t *testing.T, telegramID int64, noteID uint) []string {
+	t.Helper()
+
+	note, err := database.GetNoteByID(telegramID, noteID)
+	if err != nil {
+		t.Fatalf("get note: %v", err)
+	}
+	var names []string
+	for _, tag := range note.Tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+// Заметка из корзины после объединения тегов восстанавливается с новым тегом
+func TestMergeTagsKeepsTrashedNotes(t *testing.T) {
+	databasetest.Setup(t)
+	const telegramID = 1
+
+	category, err := database.CreateCategory(telegramID, "Общее", "")
+	if err != nil {
+		t.Fatalf("create category: %v", err)
+	}
+	var notes [2]models.Note
+	for i := range notes {
+		notes[i] = models.Note{TelegramID: telegramID, CategoryID: category.ID, Type: models.NoteTypeText, Content: "#работа"}
+		if err := database.CreateNote(&notes[i]); err != nil {
+			t.Fatalf("create note: %v", err)
+		}
+		if err := database.SetNoteTags(telegramID, &notes[i], []string{"работа"}); err != nil {
+			t.Fatalf("set tags: %v", err)
+		}
+	}
+	if err := database.GetConnect().Create(&models.Tag{TelegramID: telegramID, Name: "дела"}).Error; err != nil {
+		t.Fatalf("create tag: %v", err)
+	}
+
+	if err := database.DeleteNote(telegramID, notes[1].ID); err != nil {
+		t.Fatalf("delete note: %v", err)
+	}
+	if err := database.MergeTags(telegramID, "работа", "дела"); err != nil {
+		t.Fatalf("MergeTags: %v", err)
+	}
+	if err := database.RestoreNote(telegramID, notes[1].ID); err != nil {
+		t.Fatalf("restore note: %v", err)
+	}
+
+	for _, note := range notes {
+		if names := noteTagNames(t, telegramID, note.ID); len(names) != 1 || names[0] != "дела" {
+			t.Errorf("note %d: expected tag дела, got %v", note.ID, names)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package database
+
+import (
+	"GreenAssistantBot/internal/database/models"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Срок хранения удаленных заметок и категорий по умолчанию
+const defaultTrashRetentionDays = 30
+
+var ErrCategoryDeleted = errors.New("note category is in trash")
+
+// TrashRetention возвращает срок хранения корзины из TRASH_RETENTION_DAYS
+func TrashRetention() time.Duration {
+	days := defaultTrashRetentionDays
+	if value := os.Getenv("TRASH_RETENTION_DAYS"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			days = parsed
+		} else {
+			log.Printf("Invalid TRASH_RETENTION_DAYS %q, using %d", value, defaultTrashRetentionDays)
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// DeletedCategory — категория в корзине и число удаленных вместе с ней заметок
+type DeletedCategory struct {
+	models.Category
+	NotesCount int64
+}
+
+// GetDeletedNotes возвращает заметки, удаленные по одной. Заметки, удаленные
+// вместе с категорией, восстанавливаются и удаляются вместе с ней.
+func GetDeletedNotes(telegramID int64) ([]models.Note, error) {
+	db := GetConnect()
+
+	var notes []models.Note
+	result := db.Unscoped().
+		Joins("LEFT JOIN categories ON categories.id = notes.category_id").
+		Where("notes.telegram_id = ? AND notes.deleted_at IS NOT NULL", telegramID).
+		Where("categories.deleted_at IS NULL OR categories.deleted_at <> notes.deleted_at").
+		Preload("Category", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Order("notes.deleted_at DESC").
+		Find(&notes)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return notes, nil
+}
+
+// GetDeletedCategories возвращает категории в корзине
+func GetDeletedCategories(telegramID int64) ([]DeletedCategory, error) {
+	db := GetConnect()
+
+	var categories []models.Category
+	result := db.Unscoped().
+		Where("telegram_id = ? AND deleted_at IS NOT NULL", telegramID).
+		Order("deleted_at DESC").
+		Find(&categories)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	deleted := make([]DeletedCategory, len(categories))
+	for i, category := range categories {
+		deleted[i].Category = category
+		if err := db.Unscoped().Model(&models.Note{}).
+			Where("category_id = ? AND deleted_at = ?", category.ID, category.DeletedAt.Time).
+			Count(&deleted[i].NotesCount).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return deleted, nil
+}
+
+// RestoreNote возвращает заметку из корзины
+func RestoreNote(telegramID int64, noteID uint) error {
+	db := GetConnect()
+
+	var note models.Note
+	if err := db.Unscoped().Where("telegram_id = ? AND id = ? AND deleted_at IS NOT NULL", telegramID, noteID).First(&note).Error; err != nil {
+		return err
+	}
+
+	if _, err := GetCategoryByID(telegramID, note.CategoryID); errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrCategoryDeleted
+	} else if err != nil {
+		return err
+	}
+
+	return db.Unscoped().Model(&note).Update("deleted_at", nil).Error
+}
+
+// RestoreCategory возвращает категорию из корзины вместе с заметками,
+// удаленными вместе с ней
+func RestoreCategory(telegramID int64, categoryID uint) error {
+	db := GetConnect()
+
+	var category models.Category
+	if err := db.Unscoped().Where("telegram_id = ? AND id = ? AND deleted_at IS NOT NULL", telegramID, categoryID).First(&category).Error; err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Note{}).
+			Where("category_id = ? AND deleted_at = ?", category.ID, category.DeletedAt.Time).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&category).Update("deleted_at", nil).Error
+	})
+}
+
+// PurgeNote окончательно удаляет заметку из корзины
+func PurgeNote(telegramID int64, noteID uint) error {
+	db := GetConnect()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		purged, err := purgeNotes(tx, tx.Unscoped().Where("telegram_id = ? AND id = ? AND deleted_at IS NOT NULL", telegramID, noteID))
+		if err == nil && purged == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return err
+	})
+}
+
+// PurgeCategory окончательно удаляет категорию из корзины вместе с ее заметками
+func PurgeCategory(telegramID int64, categoryID uint) error {
+	db := GetConnect()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("telegram_id = ? AND id = ? AND deleted_at IS NOT NULL", telegramID, categoryID).Delete(&models.Category{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		_, err := purgeNotes(tx, tx.Unscoped().Where("category_id = ? AND deleted_at IS NOT NULL", categoryID))
+		return err
+	})
+}
+
+// EmptyTrash окончательно удаляет все заметки и категории пользователя из корзины
+func EmptyTrash(telegramID int64) error {
+	db := GetConnect()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if _, err := purgeNotes(tx, tx.Unscoped().Where("telegram_id = ? AND deleted_at IS NOT NULL", telegramID)); err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("telegram_id = ? AND deleted_at IS NOT NULL", telegramID).Delete(&models.Category{}).Error
+	})
+}
+
+// PurgeDeletedBefore окончательно удаляет все, что лежит в корзине дольше срока хранения.
+// Возвращает число удаленных заметок и категорий.
+func PurgeDeletedBefore(cutoff time.Time) (int64, int64, error) {
+	db := GetConnect()
+
+	var notesCount, categoriesCount int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if notesCount, err = purgeNotes(tx, tx.Unscoped().Where("deleted_at < ?", cutoff)); err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Where("deleted_at < ?", cutoff).Delete(&models.Category{})
+		categoriesCount = result.RowsAffected
+		return result.Error
+	})
+
+	return notesCount, categoriesCount, err
+}
+
+// purgeNotes физически удаляет заметки, выбранные запросом, их связи с тегами,
+// историю и напоминания.
+// Возвращает число удаленных заметок.
+func purgeNotes(tx *gorm.DB, query *gorm.DB) (int64, error) {
+	var ids []uint
+	if err := query.Model(&models.Note{}).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	if err := tx.Exec("DELETE FROM note_tags WHERE note_id IN ?", ids).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Where("note_id IN ?", ids).Delete(&models.NoteRevision{}).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Where("note_id IN ?", ids).Delete(&models.Reminder{}).Error; err != nil {
+		return 0, err
+	}
+	result := tx.Unscoped().Where("id IN ?", ids).Delete(&models.Note{})
+	return result.RowsAffected, result.Error
+}
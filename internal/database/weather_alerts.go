@@ -0,0 +1,65 @@
+package database
+
+import (
+	"GreenAssistantBot/internal/database/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GetWeatherAlertSubscribers возвращает пользователей с включенными предупреждениями и городом
+func GetWeatherAlertSubscribers() ([]models.User, error) {
+	db := GetConnect()
+
+	var users []models.User
+	result := db.Where("weather_alerts = ? AND city <> ''", true).Find(&users)
+	return users, result.Error
+}
+
+// SetUserWeatherAlerts включает или выключает погодные предупреждения
+func SetUserWeatherAlerts(telegramID int64, enabled bool) error {
+	return updateUser(telegramID, map[string]interface{}{"weather_alerts": enabled})
+}
+
+// SetUserFrostThreshold сохраняет температуру, ниже которой предупреждать о морозе
+func SetUserFrostThreshold(telegramID int64, temp int) error {
+	return updateUser(telegramID, map[string]interface{}{"frost_threshold": temp})
+}
+
+// SetUserQuietHours сохраняет тихие часы в формате ЧЧ:ММ. Пустые значения отключают их.
+func SetUserQuietHours(telegramID int64, start, end string) error {
+	return updateUser(telegramID, map[string]interface{}{"quiet_hours_start": start, "quiet_hours_end": end})
+}
+
+// WeatherAlertSent проверяет, отправлялось ли предупреждение этого вида за местную дату
+func WeatherAlertSent(telegramID int64, kind, eventDate string) (bool, error) {
+	db := GetConnect()
+
+	var alert models.WeatherAlert
+	result := db.Where("telegram_id = ? AND kind = ? AND event_date = ?", telegramID, kind, eventDate).First(&alert)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return result.Error == nil, result.Error
+}
+
+// RecordWeatherAlert запоминает отправленное предупреждение
+func RecordWeatherAlert(telegramID int64, kind string, eventAt time.Time) error {
+	db := GetConnect()
+
+	return db.Create(&models.WeatherAlert{
+		TelegramID: telegramID,
+		Kind:       kind,
+		EventDate:  eventAt.Format("2006-01-02"),
+		EventAt:    eventAt,
+	}).Error
+}
+
+// PurgeWeatherAlerts удаляет записи о предупреждениях, отправленных раньше before
+func PurgeWeatherAlerts(before time.Time) (int64, error) {
+	db := GetConnect()
+
+	result := db.Where("created_at < ?", before).Delete(&models.WeatherAlert{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,244 @@
+// Package fsm описывает сценарии диалогов бота как конечные автоматы:
+// набор состояний, разрешенные переходы, проверку ввода, таймауты и
+// общую для всех сценариев отмену.
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// State — имя состояния диалога, хранится в BotStorage как строка
+type State string
+
+// None — пустое состояние: пользователь не находится ни в одном сценарии
+const None State = ""
+
+// Input — входящее сообщение пользователя
+type Input struct {
+	ChatID int64
+	Text   string
+	// Payload — исходное обновление (например, tgbotapi.Update)
+	Payload interface{}
+}
+
+// Validator проверяет ввод перед выполнением действия состояния.
+// Текст ошибки показывается пользователю.
+type Validator func(in Input) error
+
+// Action выполняет работу состояния и возвращает следующее состояние
+type Action func(in Input) State
+
+// StateDef описывает одно состояние сценария
+type StateDef struct {
+	Name     State
+	Validate Validator
+	Action   Action
+	// Next — состояния, в которые разрешен переход (None разрешено всегда)
+	Next []State
+	// Timeout — сколько ждать ввода; 0 — без ограничения
+	Timeout time.Duration
+	// OnInvalid переопределяет Flow.OnInvalid для этого состояния
+	OnInvalid func(in Input, err error)
+}
+
+// Flow — сценарий диалога из нескольких состояний
+type Flow struct {
+	Name   string
+	States []StateDef
+
+	// OnInvalid вызывается, если ввод не прошел проверку; состояние не меняется
+	OnInvalid func(in Input, err error)
+	// OnCancel вызывается после отмены сценария пользователем
+	OnCancel func(chatID int64)
+	// OnTimeout вызывается, если пользователь не ответил вовремя
+	OnTimeout func(chatID int64)
+}
+
+// Store — хранилище текущего состояния пользователя
+type Store interface {
+	GetUserState(chatID int64) (string, bool)
+	SetUserState(chatID int64, state string)
+	GetStateChangedAt(chatID int64) (time.Time, bool)
+}
+
+type binding struct {
+	flow *Flow
+	def  *StateDef
+}
+
+// Machine направляет ввод пользователя в действие его текущего состояния
+type Machine struct {
+	store       Store
+	states      map[State]binding
+	cancelWords map[string]bool
+	now         func() time.Time
+}
+
+// NewMachine собирает автомат из сценариев и проверяет их описание
+func NewMachine(store Store, flows ...*Flow) (*Machine, error) {
+	m := &Machine{
+		store:       store,
+		states:      make(map[State]binding),
+		cancelWords: make(map[string]bool),
+		now:         time.Now,
+	}
+
+	for _, flow := range flows {
+		for i := range flow.States {
+			def := &flow.States[i]
+			if def.Name == None {
+				return nil, fmt.Errorf("fsm: flow %q has a state without name", flow.Name)
+			}
+			if def.Action == nil {
+				return nil, fmt.Errorf("fsm: state %q has no action", def.Name)
+			}
+			if existing, ok := m.states[def.Name]; ok {
+				return nil, fmt.Errorf("fsm: state %q declared in flows %q and %q", def.Name, existing.flow.Name, flow.Name)
+			}
+			m.states[def.Name] = binding{flow: flow, def: def}
+		}
+	}
+
+	for name, b := range m.states {
+		for _, next := range b.def.Next {
+			if next == None {
+				continue
+			}
+			if _, ok := m.states[next]; !ok {
+				return nil, fmt.Errorf("fsm: state %q has transition to unknown state %q", name, next)
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// SetCancelWords задает тексты, отменяющие любой сценарий
+func (m *Machine) SetCancelWords(words ...string) {
+	m.cancelWords = make(map[string]bool, len(words))
+	for _, word := range words {
+		m.cancelWords[strings.TrimSpace(word)] = true
+	}
+}
+
+// SetClock подменяет источник времени (используется в тестах)
+func (m *Machine) SetClock(now func() time.Time) {
+	m.now = now
+}
+
+// Has сообщает, описано ли состояние в каком-либо сценарии
+func (m *Machine) Has(state State) bool {
+	_, ok := m.states[state]
+	return ok
+}
+
+// Handle обрабатывает ввод в текущем состоянии пользователя.
+// Возвращает false, если пользователь вне сценария и ввод нужно
+// обработать как обычную команду.
+func (m *Machine) Handle(in Input) bool {
+	raw, exists := m.store.GetUserState(in.ChatID)
+	if !exists || State(raw) == None {
+		return false
+	}
+
+	current := State(raw)
+	b, ok := m.states[current]
+	if !ok {
+		log.Printf("FSM: unknown state %q for chat %d, resetting", current, in.ChatID)
+		m.store.SetUserState(in.ChatID, string(None))
+		return false
+	}
+
+	if m.cancelWords[strings.TrimSpace(in.Text)] {
+		log.Printf("FSM: flow %q cancelled by chat %d", b.flow.Name, in.ChatID)
+		m.store.SetUserState(in.ChatID, string(None))
+		if b.flow.OnCancel != nil {
+			b.flow.OnCancel(in.ChatID)
+		}
+		return true
+	}
+
+	if b.def.Timeout > 0 {
+		if changedAt, ok := m.store.GetStateChangedAt(in.ChatID); ok && m.now().Sub(changedAt) > b.def.Timeout {
+			log.Printf("FSM: state %q timed out for chat %d", current, in.ChatID)
+			m.store.SetUserState(in.ChatID, string(None))
+			if b.flow.OnTimeout != nil {
+				b.flow.OnTimeout(in.ChatID)
+			}
+			return false
+		}
+	}
+
+	if b.def.Validate != nil {
+		if err := b.def.Validate(in); err != nil {
+			onInvalid := b.def.OnInvalid
+			if onInvalid == nil {
+				onInvalid = b.flow.OnInvalid
+			}
+			if onInvalid != nil {
+				onInvalid(in, err)
+			}
+			return true
+		}
+	}
+
+	next := b.def.Action(in)
+	if !b.def.allows(next) {
+		log.Printf("FSM: transition %q -> %q is not declared, resetting", current, next)
+		next = None
+	}
+
+	m.store.SetUserState(in.ChatID, string(next))
+	return true
+}
+
+func (d *StateDef) allows(next State) bool {
+	if next == None || next == d.Name {
+		return true
+	}
+	for _, allowed := range d.Next {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// NotEmpty отклоняет пустой ввод
+func NotEmpty(message string) Validator {
+	return func(in Input) error {
+		if strings.TrimSpace(in.Text) == "" {
+			return errors.New(message)
+		}
+		return nil
+	}
+}
+
+// OneOf принимает только перечисленные варианты ответа (без учета регистра)
+func OneOf(message string, options ...string) Validator {
+	return func(in Input) error {
+		text := strings.ToLower(strings.TrimSpace(in.Text))
+		for _, option := range options {
+			if text == strings.ToLower(option) {
+				return nil
+			}
+		}
+		return errors.New(message)
+	}
+}
+
+// All объединяет несколько проверок, возвращая первую ошибку
+func All(validators ...Validator) Validator {
+	return func(in Input) error {
+		for _, validate := range validators {
+			if err := validate(in); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
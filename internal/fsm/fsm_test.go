@@ -0,0 +1,306 @@
+package fsm
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type memStore struct {
+	states    map[int64]string
+	changedAt map[int64]time.Time
+	now       time.Time
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		states:    make(map[int64]string),
+		changedAt: make(map[int64]time.Time),
+		now:       time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func (s *memStore) GetUserState(chatID int64) (string, bool) {
+	state, ok := s.states[chatID]
+	return state, ok
+}
+
+func (s *memStore) SetUserState(chatID int64, state string) {
+	s.states[chatID] = state
+	s.changedAt[chatID] = s.now
+}
+
+func (s *memStore) GetStateChangedAt(chatID int64) (time.Time, bool) {
+	at, ok := s.changedAt[chatID]
+	return at, ok
+}
+
+const (
+	stateName State = "name"
+	stateCity State = "city"
+)
+
+// onboarding повторяет сценарий заполнения профиля: имя, затем город
+type onboarding struct {
+	name, city string
+	invalid    []string
+	cancelled  int
+	timedOut   int
+}
+
+func (o *onboarding) flow() *Flow {
+	return &Flow{
+		Name: "profile",
+		States: []StateDef{
+			{
+				Name:     stateName,
+				Validate: NotEmpty("empty name"),
+				Next:     []State{stateCity},
+				Action: func(in Input) State {
+					o.name = in.Text
+					return stateCity
+				},
+			},
+			{
+				Name:     stateCity,
+				Validate: NotEmpty("empty city"),
+				Timeout:  10 * time.Minute,
+				Action: func(in Input) State {
+					o.city = in.Text
+					return None
+				},
+			},
+		},
+		OnInvalid: func(in Input, err error) { o.invalid = append(o.invalid, err.Error()) },
+		OnCancel:  func(chatID int64) { o.cancelled++ },
+		OnTimeout: func(chatID int64) { o.timedOut++ },
+	}
+}
+
+func newTestMachine(t *testing.T, store *memStore, flows ...*Flow) *Machine {
+	t.Helper()
+	m, err := NewMachine(store, flows...)
+	if err != nil {
+		t.Fatalf("NewMachine: %v", err)
+	}
+	m.SetCancelWords("/cancel")
+	m.SetClock(func() time.Time { return store.now })
+	return m
+}
+
+func TestMachineRunsFlowToCompletion(t *testing.T) {
+	store := newMemStore()
+	o := &onboarding{}
+	m := newTestMachine(t, store, o.flow())
+
+	store.SetUserState(1, string(stateName))
+
+	if !m.Handle(Input{ChatID: 1, Text: "Иван"}) {
+		t.Fatal("name input was not handled")
+	}
+	if got := store.states[1]; got != string(stateCity) {
+		t.Fatalf("state after name = %q, want %q", got, stateCity)
+	}
+
+	if !m.Handle(Input{ChatID: 1, Text: "Москва"}) {
+		t.Fatal("city input was not handled")
+	}
+	if got := store.states[1]; got != string(None) {
+		t.Fatalf("state after city = %q, want empty", got)
+	}
+	if o.name != "Иван" || o.city != "Москва" {
+		t.Fatalf("profile = %q/%q", o.name, o.city)
+	}
+}
+
+func TestMachineIgnoresUsersOutsideFlows(t *testing.T) {
+	store := newMemStore()
+	m := newTestMachine(t, store, (&onboarding{}).flow())
+
+	if m.Handle(Input{ChatID: 1, Text: "hello"}) {
+		t.Fatal("input without state must not be handled")
+	}
+
+	store.SetUserState(1, "")
+	if m.Handle(Input{ChatID: 1, Text: "hello"}) {
+		t.Fatal("input in empty state must not be handled")
+	}
+}
+
+func TestMachineValidatorKeepsState(t *testing.T) {
+	store := newMemStore()
+	o := &onboarding{}
+	m := newTestMachine(t, store, o.flow())
+
+	store.SetUserState(1, string(stateName))
+
+	if !m.Handle(Input{ChatID: 1, Text: "   "}) {
+		t.Fatal("invalid input must be consumed")
+	}
+	if got := store.states[1]; got != string(stateName) {
+		t.Fatalf("state = %q, want %q", got, stateName)
+	}
+	if len(o.invalid) != 1 || o.invalid[0] != "empty name" {
+		t.Fatalf("invalid = %v", o.invalid)
+	}
+	if o.name != "" {
+		t.Fatal("action must not run for invalid input")
+	}
+}
+
+func TestMachineStateOnInvalidOverridesFlow(t *testing.T) {
+	store := newMemStore()
+	var stateCalls, flowCalls int
+	flow := &Flow{
+		Name: "confirm",
+		States: []StateDef{{
+			Name:      "confirm",
+			Validate:  OneOf("use buttons", "да", "нет"),
+			Action:    func(in Input) State { return None },
+			OnInvalid: func(Input, error) { stateCalls++ },
+		}},
+		OnInvalid: func(Input, error) { flowCalls++ },
+	}
+	m := newTestMachine(t, store, flow)
+
+	store.SetUserState(1, "confirm")
+	m.Handle(Input{ChatID: 1, Text: "может быть"})
+	if stateCalls != 1 || flowCalls != 0 {
+		t.Fatalf("state handler calls = %d, flow handler calls = %d", stateCalls, flowCalls)
+	}
+
+	if !m.Handle(Input{ChatID: 1, Text: "ДА"}) {
+		t.Fatal("confirmation was not handled")
+	}
+	if got := store.states[1]; got != "" {
+		t.Fatalf("state = %q, want empty", got)
+	}
+}
+
+func TestMachineCancel(t *testing.T) {
+	store := newMemStore()
+	o := &onboarding{}
+	m := newTestMachine(t, store, o.flow())
+
+	store.SetUserState(1, string(stateCity))
+
+	if !m.Handle(Input{ChatID: 1, Text: " /cancel "}) {
+		t.Fatal("cancel was not handled")
+	}
+	if got := store.states[1]; got != "" {
+		t.Fatalf("state = %q, want empty", got)
+	}
+	if o.cancelled != 1 {
+		t.Fatalf("cancelled = %d, want 1", o.cancelled)
+	}
+	if o.city != "" {
+		t.Fatal("action must not run on cancel")
+	}
+}
+
+func TestMachineTimeout(t *testing.T) {
+	store := newMemStore()
+	o := &onboarding{}
+	m := newTestMachine(t, store, o.flow())
+
+	store.SetUserState(1, string(stateCity))
+	store.now = store.now.Add(11 * time.Minute)
+
+	if m.Handle(Input{ChatID: 1, Text: "Москва"}) {
+		t.Fatal("input after timeout must be passed through")
+	}
+	if o.timedOut != 1 {
+		t.Fatalf("timedOut = %d, want 1", o.timedOut)
+	}
+	if got := store.states[1]; got != "" {
+		t.Fatalf("state = %q, want empty", got)
+	}
+	if o.city != "" {
+		t.Fatal("action must not run after timeout")
+	}
+}
+
+func TestMachineNoTimeoutWithinLimit(t *testing.T) {
+	store := newMemStore()
+	o := &onboarding{}
+	m := newTestMachine(t, store, o.flow())
+
+	store.SetUserState(1, string(stateCity))
+	store.now = store.now.Add(9 * time.Minute)
+
+	if !m.Handle(Input{ChatID: 1, Text: "Москва"}) {
+		t.Fatal("input within timeout must be handled")
+	}
+	if o.timedOut != 0 || o.city != "Москва" {
+		t.Fatalf("timedOut = %d, city = %q", o.timedOut, o.city)
+	}
+}
+
+func TestMachineRejectsUndeclaredTransition(t *testing.T) {
+	store := newMemStore()
+	flow := &Flow{
+		Name: "broken",
+		States: []StateDef{
+			{Name: "a", Action: func(Input) State { return "b" }},
+			{Name: "b", Action: func(Input) State { return None }},
+		},
+	}
+	m := newTestMachine(t, store, flow)
+
+	store.SetUserState(1, "a")
+	m.Handle(Input{ChatID: 1, Text: "x"})
+	if got := store.states[1]; got != "" {
+		t.Fatalf("state = %q, want reset to empty", got)
+	}
+}
+
+func TestMachineResetsUnknownState(t *testing.T) {
+	store := newMemStore()
+	m := newTestMachine(t, store, (&onboarding{}).flow())
+
+	store.SetUserState(1, "legacy_state")
+	if m.Handle(Input{ChatID: 1, Text: "x"}) {
+		t.Fatal("unknown state must not consume input")
+	}
+	if got := store.states[1]; got != "" {
+		t.Fatalf("state = %q, want empty", got)
+	}
+}
+
+func TestNewMachineValidatesFlows(t *testing.T) {
+	noop := func(Input) State { return None }
+
+	tests := []struct {
+		name  string
+		flows []*Flow
+	}{
+		{"missing action", []*Flow{{Name: "f", States: []StateDef{{Name: "a"}}}}},
+		{"empty name", []*Flow{{Name: "f", States: []StateDef{{Action: noop}}}}},
+		{"duplicate state", []*Flow{
+			{Name: "f1", States: []StateDef{{Name: "a", Action: noop}}},
+			{Name: "f2", States: []StateDef{{Name: "a", Action: noop}}},
+		}},
+		{"unknown transition", []*Flow{{Name: "f", States: []StateDef{{Name: "a", Action: noop, Next: []State{"b"}}}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewMachine(newMemStore(), tt.flows...); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestAllReturnsFirstError(t *testing.T) {
+	first := errors.New("first")
+	validate := All(
+		func(Input) error { return nil },
+		func(Input) error { return first },
+		func(Input) error { return errors.New("second") },
+	)
+
+	if err := validate(Input{}); err != first {
+		t.Fatalf("err = %v, want %v", err, first)
+	}
+}
@@ -3,87 +3,277 @@ package scheduler
 import (
 	"GreenAssistantBot/internal/bot"
 	"GreenAssistantBot/internal/database"
+	"GreenAssistantBot/internal/database/models"
+	"GreenAssistantBot/internal/telegram"
 	"GreenAssistantBot/internal/weather"
 	"log"
-	"os"
-	"strconv"
 	"time"
 )
 
+const (
+	// Как часто проверять, не пора ли отправить уведомления о погоде
+	weatherCheckInterval = time.Minute
+	// Насколько может опоздать уведомление о погоде (например, после перезапуска бота)
+	weatherNotificationGrace = 15 * time.Minute
+	// Как часто проверять корзину на устаревшие элементы
+	trashPurgeInterval = 24 * time.Hour
+	// Как часто проверять наступившие напоминания
+	reminderCheckInterval = time.Minute
+	// Сколько напоминаний отправлять за одну проверку
+	reminderBatchSize = 100
+	// Как часто проверять прогноз на опасные явления
+	weatherAlertInterval = 30 * time.Minute
+	// На сколько часов вперед предупреждать об опасных явлениях
+	weatherAlertLookahead = 12 * time.Hour
+	// Сколько хранить записи об отправленных предупреждениях
+	weatherAlertRetention = 7 * 24 * time.Hour
+)
+
 type Scheduler struct {
 	bot            *bot.MessageHandler
+	sender         telegram.Sender
 	weatherService *weather.WeatherService
 }
 
-func NewScheduler(botHandler *bot.MessageHandler) *Scheduler {
+func NewScheduler(botHandler *bot.MessageHandler, sender telegram.Sender, weatherService *weather.WeatherService) *Scheduler {
 	return &Scheduler{
 		bot:            botHandler,
-		weatherService: weather.NewWeatherService(),
+		sender:         sender,
+		weatherService: weatherService,
 	}
 }
 
-// StartWeatherNotifications запускает отправку уведомлений о погоде по расписанию
+// sendMessage отправляет уведомление с клавиатурой главного меню
+func (s *Scheduler) sendMessage(chatID int64, text string) error {
+	_, err := s.sender.SendText(telegram.Text{ChatID: chatID, Text: text, ReplyMarkup: bot.CreateMainMenuKeyboard()})
+	return err
+}
+
+// StartWeatherNotifications запускает отправку уведомлений о погоде. Раз в минуту
+// для каждого пользователя вычисляется ближайшее время отправки в его часовом поясе.
 func (s *Scheduler) StartWeatherNotifications() {
-	// Запускаем горутину для проверки времени отправки уведомлений
+	go func() {
+		ticker := time.NewTicker(weatherCheckInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			s.sendDueWeatherNotifications(time.Now())
+		}
+	}()
+}
+
+// nextWeatherNotification возвращает первое время уведомления пользователя после after
+func nextWeatherNotification(user *models.User, after time.Time) time.Time {
+	clock, err := time.Parse("15:04", database.UserNotificationTime(user))
+	if err != nil {
+		clock, _ = time.Parse("15:04", database.DefaultNotificationTime())
+	}
+
+	local := after.In(user.Location())
+	next := time.Date(local.Year(), local.Month(), local.Day(), clock.Hour(), clock.Minute(), 0, 0, local.Location())
+	if !next.After(local) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// sendDueWeatherNotifications отправляет погоду пользователям, у которых наступило время уведомления
+func (s *Scheduler) sendDueWeatherNotifications(now time.Time) {
+	users, err := database.GetAllUsers()
+	if err != nil {
+		log.Printf("Error getting users: %v", err)
+		return
+	}
+
+	for i := range users {
+		user := &users[i]
+		if user.City == "" || !user.WeatherNotifications {
+			continue
+		}
+
+		// Отсчитываем от последней отправки, но не раньше окна опоздания,
+		// чтобы после простоя бота не рассылать пропущенные уведомления
+		from := now.Add(-weatherNotificationGrace)
+		if user.LastWeatherNotificationAt != nil && user.LastWeatherNotificationAt.After(from) {
+			from = *user.LastWeatherNotificationAt
+		}
+		if nextWeatherNotification(user, from).After(now) {
+			continue
+		}
+
+		s.sendWeatherNotification(user, now)
+	}
+}
+
+// sendWeatherNotification отправляет пользователю погоду в его городе
+func (s *Scheduler) sendWeatherNotification(user *models.User, now time.Time) {
+	weatherData, err := s.bot.UserWeather(user)
+	if err != nil {
+		log.Printf("Error getting weather data for user %d: %v", user.TelegramID, err)
+		return
+	}
+
+	text := greeting(now.In(user.Location())) + " Вот прогноз погоды на сегодня:\n\n" + s.weatherService.FormatWeatherMessage(weatherData, bot.WeatherPreferences(user))
+	if digest := s.bot.LocationsDigest(user); digest != "" {
+		text += "\n\n" + digest
+	}
+	if err := s.sendMessage(user.TelegramID, text); err != nil {
+		log.Printf("Error sending weather notification to user %d: %v", user.TelegramID, err)
+		return
+	}
+
+	if err := database.MarkWeatherNotified(user.TelegramID, now); err != nil {
+		log.Printf("Error saving weather notification time for user %d: %v", user.TelegramID, err)
+	}
+}
+
+// greeting возвращает приветствие по местному времени пользователя
+func greeting(local time.Time) string {
+	switch hour := local.Hour(); {
+	case hour >= 5 && hour < 12:
+		return "🌅 Доброе утро!"
+	case hour >= 12 && hour < 17:
+		return "☀️ Добрый день!"
+	case hour >= 17 && hour < 23:
+		return "🌆 Добрый вечер!"
+	default:
+		return "🌙 Доброй ночи!"
+	}
+}
+
+// StartTrashPurge запускает окончательное удаление заметок и категорий,
+// пролежавших в корзине дольше TRASH_RETENTION_DAYS
+func (s *Scheduler) StartTrashPurge() {
 	go func() {
 		for {
-			now := time.Now()
-
-			// Получаем время отправки из переменных окружения
-			hour := 9 // значение по умолчанию
-			if h := os.Getenv("WEATHER_NOTIFICATION_HOUR"); h != "" {
-				if parsedHour, err := strconv.Atoi(h); err == nil {
-					hour = parsedHour
-				}
-			}
+			s.purgeTrash()
+			time.Sleep(trashPurgeInterval)
+		}
+	}()
+}
 
-			minute := 0 // значение по умолчанию
-			if m := os.Getenv("WEATHER_NOTIFICATION_MINUTE"); m != "" {
-				if parsedMinute, err := strconv.Atoi(m); err == nil {
-					minute = parsedMinute
-				}
-			}
+// purgeTrash удаляет из корзины все, что старше срока хранения
+func (s *Scheduler) purgeTrash() {
+	cutoff := time.Now().Add(-database.TrashRetention())
 
-			// Вычисляем время до следующего запланированного времени
-			nextRun := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
-			if now.After(nextRun) {
-				nextRun = nextRun.Add(24 * time.Hour)
-			}
+	notes, categories, err := database.PurgeDeletedBefore(cutoff)
+	if err != nil {
+		log.Printf("Error purging trash: %v", err)
+		return
+	}
 
-			// Ждем до запланированного времени
-			duration := nextRun.Sub(now)
-			log.Printf("Next weather notification will be sent at %v (in %v)", nextRun, duration)
-			time.Sleep(duration)
+	if notes > 0 || categories > 0 {
+		log.Printf("Trash purged: %d notes, %d categories deleted before %v", notes, categories, cutoff.Format("02.01.2006 15:04"))
+	}
+}
+
+// StartReminders запускает отправку напоминаний о заметках
+func (s *Scheduler) StartReminders() {
+	go func() {
+		ticker := time.NewTicker(reminderCheckInterval)
+		defer ticker.Stop()
 
-			// Отправляем уведомления о погоде всем пользователям
-			s.sendWeatherToAllUsers()
+		for range ticker.C {
+			s.sendDueReminders()
 		}
 	}()
 }
 
-// sendWeatherToAllUsers отправляет уведомления о погоде всем пользователям
-func (s *Scheduler) sendWeatherToAllUsers() {
-	// Получаем всех пользователей из базы данных
-	users, err := database.GetAllUsers()
+// sendDueReminders отправляет напоминания, время которых наступило.
+// Неотправленное из-за временной ошибки напоминание повторяется при следующей
+// проверке. Если Telegram отклонил его окончательно (бот заблокирован, чат не
+// найден), напоминание закрывается как отправленное, чтобы не занимать место
+// в пачке перед более новыми.
+func (s *Scheduler) sendDueReminders() {
+	reminders, err := database.GetDueReminders(time.Now(), reminderBatchSize)
 	if err != nil {
-		log.Printf("Error getting users: %v", err)
+		log.Printf("Error getting due reminders: %v", err)
 		return
 	}
 
-	// Отправляем погоду каждому пользователю с включенными уведомлениями
-	for _, user := range users {
-		if user.City != "" && user.WeatherNotifications {
-			weatherData, err := s.weatherService.GetWeatherData(user.City)
-			if err != nil {
-				log.Printf("Error getting weather data for user %d: %v", user.TelegramID, err)
+	for _, reminder := range reminders {
+		if err := s.bot.SendReminder(reminder); err != nil {
+			log.Printf("Error sending reminder %d to user %d: %v", reminder.ID, reminder.TelegramID, err)
+			if !telegram.IsPermanent(err) {
 				continue
 			}
+		}
 
-			text := "🌅 Доброе утро! Вот прогноз погоды на сегодня:\n\n" + s.weatherService.FormatWeatherMessage(weatherData)
-			err = s.bot.SendMessage(user.TelegramID, text, bot.CreateMainMenuKeyboard())
-			if err != nil {
-				log.Printf("Error sending weather notification to user %d: %v", user.TelegramID, err)
-			}
+		if err := database.MarkReminderSent(reminder.ID, time.Now()); err != nil {
+			log.Printf("Error marking reminder %d as sent: %v", reminder.ID, err)
+		}
+	}
+}
+
+// StartWeatherAlerts запускает проверку прогноза на опасные явления для
+// пользователей, включивших погодные предупреждения
+func (s *Scheduler) StartWeatherAlerts() {
+	go func() {
+		ticker := time.NewTicker(weatherAlertInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			s.sendWeatherAlerts(time.Now())
+		}
+	}()
+}
+
+// sendWeatherAlerts отправляет предупреждения о явлениях, о которых пользователь еще не знает.
+// В тихие часы предупреждения откладываются до следующей проверки после их окончания.
+func (s *Scheduler) sendWeatherAlerts(now time.Time) {
+	if _, err := database.PurgeWeatherAlerts(now.Add(-weatherAlertRetention)); err != nil {
+		log.Printf("Error purging weather alerts: %v", err)
+	}
+
+	users, err := database.GetWeatherAlertSubscribers()
+	if err != nil {
+		log.Printf("Error getting weather alert subscribers: %v", err)
+		return
+	}
+
+	for i := range users {
+		user := &users[i]
+		if user.InQuietHours(now) {
+			continue
+		}
+		s.sendUserWeatherAlerts(user, now)
+	}
+}
+
+func (s *Scheduler) sendUserWeatherAlerts(user *models.User, now time.Time) {
+	// Пороги предупреждений заданы в метрических единицах
+	forecast, err := s.bot.UserForecast(user, weather.DefaultPreferences())
+	if err != nil {
+		log.Printf("Error getting forecast for user %d: %v", user.TelegramID, err)
+		return
+	}
+
+	thresholds := weather.AlertThresholds{FrostTemp: float64(user.FrostThreshold)}
+	var fresh []weather.Alert
+	for _, alert := range weather.DetectAlerts(forecast, thresholds, now.Add(weatherAlertLookahead)) {
+		eventDate := alert.Time.In(user.Location()).Format("2006-01-02")
+		sent, err := database.WeatherAlertSent(user.TelegramID, alert.Kind, eventDate)
+		if err != nil {
+			log.Printf("Error checking weather alert for user %d: %v", user.TelegramID, err)
+			continue
+		}
+		if !sent {
+			fresh = append(fresh, alert)
+		}
+	}
+	if len(fresh) == 0 {
+		return
+	}
+
+	text := weather.FormatAlerts(user.City, fresh, bot.WeatherPreferences(user))
+	if err := s.sendMessage(user.TelegramID, text); err != nil {
+		log.Printf("Error sending weather alert to user %d: %v", user.TelegramID, err)
+		return
+	}
+
+	for _, alert := range fresh {
+		if err := database.RecordWeatherAlert(user.TelegramID, alert.Kind, alert.Time.In(user.Location())); err != nil {
+			log.Printf("Error saving weather alert for user %d: %v", user.TelegramID, err)
 		}
 	}
 }
@@ -0,0 +1,116 @@
+package scheduler
+
+import (
+	"GreenAssistantBot/internal/bot"
+	"GreenAssistantBot/internal/database"
+	"GreenAssistantBot/internal/database/databasetest"
+	"GreenAssistantBot/internal/database/models"
+	"GreenAssistantBot/internal/storage"
+	"GreenAssistantBot/internal/telegram"
+	"GreenAssistantBot/internal/telegram/telegramtest"
+	"GreenAssistantBot/internal/weather"
+	"errors"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// failingSender возвращает заданные ошибки при отправке в отдельные чаты
+type failingSender struct {
+	*telegramtest.Recorder
+	errs map[int64]error
+}
+
+func (f *failingSender) SendText(msg telegram.Text) (int, error) {
+	if err := f.errs[msg.ChatID]; err != nil {
+		return 0, err
+	}
+	return f.Recorder.SendText(msg)
+}
+
+func newTestScheduler(t *testing.T, errs map[int64]error) (*Scheduler, *telegramtest.Recorder) {
+	t.Helper()
+	t.Setenv("CALLBACK_SECRET", "test-secret")
+
+	databasetest.Setup(t)
+
+	store, err := storage.NewMemoryStorage()
+	if err != nil {
+		t.Fatalf("create storage: %v", err)
+	}
+
+	recorder := telegramtest.NewRecorder()
+	sender := &failingSender{Recorder: recorder, errs: errs}
+	weatherService := weather.NewWeatherServiceWithProvider(nil)
+	return NewScheduler(bot.NewMessageHandler(sender, store, weatherService), sender, weatherService), recorder
+}
+
+// createDueReminder создает заметку и напоминание о ней, наступившее at
+func createDueReminder(t *testing.T, telegramID int64, at time.Time) uint {
+	t.Helper()
+
+	category, err := database.CreateCategory(telegramID, "Общее", "")
+	if err != nil {
+		t.Fatalf("create category: %v", err)
+	}
+	note := &models.Note{TelegramID: telegramID, CategoryID: category.ID, Type: models.NoteTypeText, Content: "Купить молоко"}
+	if err := database.CreateNote(note); err != nil {
+		t.Fatalf("create note: %v", err)
+	}
+	reminder, err := database.CreateReminder(telegramID, note.ID, at)
+	if err != nil {
+		t.Fatalf("create reminder: %v", err)
+	}
+	return reminder.ID
+}
+
+func reminderSent(t *testing.T, reminderID uint) bool {
+	t.Helper()
+
+	var reminder models.Reminder
+	if err := database.GetConnect().First(&reminder, reminderID).Error; err != nil {
+		t.Fatalf("get reminder: %v", err)
+	}
+	return reminder.SentAt != nil
+}
+
+func TestSendDueRemindersPermanentErrors(t *testing.T) {
+	const blocked, flaky, ok = 1, 2, 3
+	s, recorder := newTestScheduler(t, map[int64]error{
+		blocked: &tgbotapi.Error{Code: 403, Message: "Forbidden: bot was blocked by the user"},
+		flaky:   errors.New("connection reset by peer"),
+	})
+
+	now := time.Now()
+	blockedID := createDueReminder(t, blocked, now.Add(-2*time.Hour))
+	flakyID := createDueReminder(t, flaky, now.Add(-time.Hour))
+	okID := createDueReminder(t, ok, now.Add(-time.Minute))
+
+	s.sendDueReminders()
+
+	if !reminderSent(t, blockedID) {
+		t.Error("reminder rejected by Telegram must not be retried")
+	}
+	if reminderSent(t, flakyID) {
+		t.Error("reminder failed with a transient error must be retried")
+	}
+	if !reminderSent(t, okID) {
+		t.Error("reminder must be marked as sent")
+	}
+	if sent := recorder.Take(); len(sent) != 1 || sent[0].ChatID != ok {
+		t.Errorf("expected one reminder to chat %d, got %+v", ok, sent)
+	}
+
+	due, err := database.GetDueReminders(time.Now(), reminderBatchSize)
+	if err != nil {
+		t.Fatalf("get due reminders: %v", err)
+	}
+	if len(due) != 1 || due[0].ID != flakyID {
+		var ids []uint
+		for _, reminder := range due {
+			ids = append(ids, reminder.ID)
+		}
+		t.Errorf("only reminder %d should stay due, got %v", flakyID, ids)
+	}
+}
@@ -0,0 +1,212 @@
+package storage
+
+import (
+	dbmodels "GreenAssistantBot/internal/database/models"
+	"GreenAssistantBot/pkg/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SQLStorage хранит состояние диалогов в таблице sessions,
+// поэтому оно переживает перезапуск бота
+type SQLStorage struct {
+	db *gorm.DB
+
+	// Защищает операции чтение-изменение-запись (история сообщений)
+	mu sync.Mutex
+}
+
+func NewSQLStorage(db *gorm.DB) (*SQLStorage, error) {
+	if db == nil {
+		return nil, errors.New("sql storage: db is nil")
+	}
+
+	if err := db.AutoMigrate(&dbmodels.Session{}); err != nil {
+		return nil, err
+	}
+
+	storage := &SQLStorage{db: db}
+
+	// Запускаем фоновую очистку
+	go storage.startCleanupRoutine()
+
+	return storage, nil
+}
+
+func (s *SQLStorage) startCleanupRoutine() {
+	ticker := time.NewTicker(30 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.CleanupExpiredData()
+	}
+}
+
+func (s *SQLStorage) CleanupExpiredData() {
+	maxAge := 24 * time.Hour // Удаляем записи старше 24 часов
+
+	result := s.db.Where("updated_at < ?", time.Now().Add(-maxAge)).Delete(&dbmodels.Session{})
+	if result.Error != nil {
+		log.Printf("Storage: cleanup error: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Storage: removed %d expired sessions", result.RowsAffected)
+	}
+}
+
+// getSession загружает сессию чата, возвращает false если записи нет
+func (s *SQLStorage) getSession(chatID int64) (*dbmodels.Session, bool) {
+	var session dbmodels.Session
+	result := s.db.Where("chat_id = ?", chatID).Limit(1).Find(&session)
+	if result.Error != nil {
+		log.Printf("Storage: error loading session for chat %d: %v", chatID, result.Error)
+		return nil, false
+	}
+	if result.RowsAffected == 0 {
+		return nil, false
+	}
+	return &session, true
+}
+
+// upsert создает сессию или обновляет указанные колонки
+func (s *SQLStorage) upsert(session *dbmodels.Session, columns ...string) {
+	session.UpdatedAt = time.Now()
+	columns = append(columns, "updated_at")
+
+	result := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chat_id"}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).Create(session)
+	if result.Error != nil {
+		log.Printf("Storage: error saving session for chat %d: %v", session.ChatID, result.Error)
+	}
+}
+
+func (s *SQLStorage) GetUserState(chatID int64) (string, bool) {
+	session, exists := s.getSession(chatID)
+	if !exists || session.StateChangedAt == nil {
+		log.Printf("Storage: GetUserState for chat %d: NOT FOUND", chatID)
+		return "", false
+	}
+
+	log.Printf("Storage: GetUserState for chat %d: %s", chatID, session.State)
+	return session.State, true
+}
+
+func (s *SQLStorage) SetUserState(chatID int64, state string) {
+	log.Printf("Storage: SetUserState for chat %d: %s", chatID, state)
+	now := time.Now()
+	s.upsert(&dbmodels.Session{ChatID: chatID, State: state, StateChangedAt: &now}, "state", "state_changed_at")
+}
+
+func (s *SQLStorage) GetStateChangedAt(chatID int64) (time.Time, bool) {
+	session, exists := s.getSession(chatID)
+	if !exists || session.StateChangedAt == nil {
+		return time.Time{}, false
+	}
+	return *session.StateChangedAt, true
+}
+
+func (s *SQLStorage) GetSession(chatID int64) (models.Session, bool) {
+	row, exists := s.getSession(chatID)
+	if !exists || row.Data == "" {
+		log.Printf("Storage: GetSession for chat %d: NOT FOUND", chatID)
+		return models.Session{}, false
+	}
+
+	session, err := models.DecodeSession([]byte(row.Data))
+	if err != nil {
+		log.Printf("Storage: error decoding session for chat %d: %v", chatID, err)
+		return models.Session{}, false
+	}
+
+	log.Printf("Storage: GetSession for chat %d: %+v", chatID, session)
+	return session, true
+}
+
+func (s *SQLStorage) SetSession(chatID int64, session models.Session) {
+	log.Printf("Storage: SetSession for chat %d: %+v", chatID, session)
+
+	encoded, err := session.Encode()
+	if err != nil {
+		log.Printf("Storage: error encoding session for chat %d: %v", chatID, err)
+		return
+	}
+
+	s.upsert(&dbmodels.Session{ChatID: chatID, Data: string(encoded)}, "data")
+}
+
+func (s *SQLStorage) GetLastMessageID(chatID int64) (int, bool) {
+	session, exists := s.getSession(chatID)
+	if !exists || session.LastMessageID == 0 {
+		return 0, false
+	}
+	return session.LastMessageID, true
+}
+
+func (s *SQLStorage) SetLastMessageID(chatID int64, messageID int) {
+	s.upsert(&dbmodels.Session{ChatID: chatID, LastMessageID: messageID}, "last_message_id")
+}
+
+func (s *SQLStorage) AddMessageToHistory(chatID int64, messageID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := s.GetMessageHistory(chatID)
+	history = append(history, messageID)
+
+	// Ограничиваем историю 100 сообщениями
+	if len(history) > 100 {
+		history = history[len(history)-100:]
+	}
+
+	encoded, err := json.Marshal(history)
+	if err != nil {
+		log.Printf("Storage: error encoding message history for chat %d: %v", chatID, err)
+		return
+	}
+
+	s.upsert(&dbmodels.Session{ChatID: chatID, MessageHistory: string(encoded)}, "message_history")
+}
+
+func (s *SQLStorage) GetMessageHistory(chatID int64) []int {
+	session, exists := s.getSession(chatID)
+	if !exists || session.MessageHistory == "" {
+		return nil
+	}
+
+	var history []int
+	if err := json.Unmarshal([]byte(session.MessageHistory), &history); err != nil {
+		log.Printf("Storage: error decoding message history for chat %d: %v", chatID, err)
+		return nil
+	}
+	return history
+}
+
+func (s *SQLStorage) ClearUserData(chatID int64) {
+	result := s.db.Where("chat_id = ?", chatID).Delete(&dbmodels.Session{})
+	if result.Error != nil {
+		log.Printf("Storage: error clearing data for chat %d: %v", chatID, result.Error)
+		return
+	}
+	log.Printf("Storage: Cleared all data for chat %d", chatID)
+}
+
+func (s *SQLStorage) GetStats() map[string]interface{} {
+	var total, withState int64
+	s.db.Model(&dbmodels.Session{}).Count(&total)
+	s.db.Model(&dbmodels.Session{}).Where("state <> ''").Count(&withState)
+
+	return map[string]interface{}{
+		"driver":          "sql",
+		"sessions":        total,
+		"active_sessions": withState,
+	}
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"GreenAssistantBot/internal/database/databasetest"
+	dbmodels "GreenAssistantBot/internal/database/models"
+	"GreenAssistantBot/pkg/models"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func newTestSQLStorage(t *testing.T) (*SQLStorage, *gorm.DB) {
+	t.Helper()
+
+	db := databasetest.Open(t)
+	store, err := NewSQLStorage(db)
+	if err != nil {
+		t.Fatalf("create storage: %v", err)
+	}
+	return store, db
+}
+
+// Данные сессии без состояния сохраняются, а время смены состояния остается пустым
+func TestSQLStorageSessionWithoutState(t *testing.T) {
+	store, db := newTestSQLStorage(t)
+	const chatID = 42
+
+	store.SetLastMessageID(chatID, 7)
+	store.SetSession(chatID, models.Session{Draft: &models.NoteDraft{Type: models.NoteTypeText, Content: "пересланный текст"}})
+
+	var row dbmodels.Session
+	if err := db.First(&row, chatID).Error; err != nil {
+		t.Fatalf("load session row: %v", err)
+	}
+	if row.StateChangedAt != nil {
+		t.Errorf("state_changed_at must stay NULL until a state is set, got %v", row.StateChangedAt)
+	}
+
+	if id, ok := store.GetLastMessageID(chatID); !ok || id != 7 {
+		t.Errorf("GetLastMessageID = %d, %v; want 7, true", id, ok)
+	}
+	if session, ok := store.GetSession(chatID); !ok || session.Draft == nil || session.Draft.Content != "пересланный текст" {
+		t.Errorf("GetSession = %+v, %v; want the forwarded draft", session, ok)
+	}
+	if _, ok := store.GetStateChangedAt(chatID); ok {
+		t.Error("GetStateChangedAt must report no state")
+	}
+
+	store.SetUserState(chatID, "waiting_for_city")
+	if state, ok := store.GetUserState(chatID); !ok || state != "waiting_for_city" {
+		t.Errorf("GetUserState = %q, %v; want waiting_for_city, true", state, ok)
+	}
+	if changedAt, ok := store.GetStateChangedAt(chatID); !ok || changedAt.IsZero() {
+		t.Errorf("GetStateChangedAt = %v, %v; want the time of SetUserState", changedAt, ok)
+	}
+	if id, _ := store.GetLastMessageID(chatID); id != 7 {
+		t.Errorf("SetUserState must keep the last message ID, got %d", id)
+	}
+}
+
+// SQLStorage и MemoryStorage одинаково сообщают о наличии состояния
+func TestStoragesAgreeOnUserState(t *testing.T) {
+	memory, err := NewMemoryStorage()
+	if err != nil {
+		t.Fatalf("create memory storage: %v", err)
+	}
+	sql, _ := newTestSQLStorage(t)
+
+	for name, store := range map[string]BotStorage{"memory": memory, "sql": sql} {
+		store.SetLastMessageID(1, 10)
+		store.SetSession(2, models.Session{Query: "черновик"})
+		store.AddMessageToHistory(3, 30)
+		store.SetUserState(4, "")
+
+		for _, chatID := range []int64{1, 2, 3, 5} {
+			if state, ok := store.GetUserState(chatID); ok {
+				t.Errorf("%s: chat %d has no state, got %q", name, chatID, state)
+			}
+		}
+		if state, ok := store.GetUserState(4); !ok || state != "" {
+			t.Errorf("%s: GetUserState(4) = %q, %v; want the reset state", name, state, ok)
+		}
+
+		store.ClearUserData(4)
+		if _, ok := store.GetUserState(4); ok {
+			t.Errorf("%s: state must be gone after ClearUserData", name)
+		}
+	}
+}
@@ -10,8 +10,9 @@ import (
 type BotStorage interface {
 	GetUserState(chatID int64) (string, bool)
 	SetUserState(chatID int64, state string)
-	GetUserData(chatID int64) (models.UserData, bool)
-	SetUserData(chatID int64, data models.UserData)
+	GetStateChangedAt(chatID int64) (time.Time, bool)
+	GetSession(chatID int64) (models.Session, bool)
+	SetSession(chatID int64, session models.Session)
 	GetLastMessageID(chatID int64) (int, bool)
 	SetLastMessageID(chatID int64, messageID int)
 	AddMessageToHistory(chatID int64, messageID int)
@@ -25,7 +26,8 @@ type MemoryStorage struct {
 
 	// Простые мапы вместо LRU для надежности
 	userStates      map[int64]string
-	userData        map[int64]models.UserData
+	stateChangedAt  map[int64]time.Time
+	sessions        map[int64]models.Session
 	lastBotMessages map[int64]int
 	messageHistory  map[int64][]int
 
@@ -36,7 +38,8 @@ type MemoryStorage struct {
 func NewMemoryStorage() (*MemoryStorage, error) {
 	storage := &MemoryStorage{
 		userStates:      make(map[int64]string),
-		userData:        make(map[int64]models.UserData),
+		stateChangedAt:  make(map[int64]time.Time),
+		sessions:        make(map[int64]models.Session),
 		lastBotMessages: make(map[int64]int),
 		messageHistory:  make(map[int64][]int),
 		lastAccess:      make(map[int64]time.Time),
@@ -67,7 +70,8 @@ func (s *MemoryStorage) CleanupExpiredData() {
 	for chatID, lastAccess := range s.lastAccess {
 		if now.Sub(lastAccess) > maxAge {
 			delete(s.userStates, chatID)
-			delete(s.userData, chatID)
+			delete(s.stateChangedAt, chatID)
+			delete(s.sessions, chatID)
 			delete(s.lastBotMessages, chatID)
 			delete(s.messageHistory, chatID)
 			delete(s.lastAccess, chatID)
@@ -75,13 +79,15 @@ func (s *MemoryStorage) CleanupExpiredData() {
 	}
 }
 
+// updateLastAccess запоминает время обращения к чату. Вызывается под s.mu.Lock,
+// поэтому геттеры, которые обновляют время доступа, берут полную блокировку.
 func (s *MemoryStorage) updateLastAccess(chatID int64) {
 	s.lastAccess[chatID] = time.Now()
 }
 
 func (s *MemoryStorage) GetUserState(chatID int64) (string, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	state, exists := s.userStates[chatID]
 	if exists {
@@ -99,38 +105,44 @@ func (s *MemoryStorage) SetUserState(chatID int64, state string) {
 
 	log.Printf("Storage: SetUserState for chat %d: %s", chatID, state)
 	s.userStates[chatID] = state
+	s.stateChangedAt[chatID] = time.Now()
 	s.updateLastAccess(chatID)
 }
 
-func (s *MemoryStorage) GetUserData(chatID int64) (models.UserData, bool) {
+func (s *MemoryStorage) GetStateChangedAt(chatID int64) (time.Time, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	data, exists := s.userData[chatID]
+	changedAt, exists := s.stateChangedAt[chatID]
+	return changedAt, exists
+}
+
+func (s *MemoryStorage) GetSession(chatID int64) (models.Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.sessions[chatID]
 	if exists {
 		s.updateLastAccess(chatID)
-		log.Printf("Storage: GetUserData for chat %d: Name='%s', City='%s', Data='%s', Category='%s'",
-			chatID, data.Name, data.City, data.Data, data.Category)
+		log.Printf("Storage: GetSession for chat %d: %+v", chatID, session)
 	} else {
-		log.Printf("Storage: GetUserData for chat %d: NOT FOUND", chatID)
+		log.Printf("Storage: GetSession for chat %d: NOT FOUND", chatID)
 	}
-	return data, exists
+	return session, exists
 }
 
-func (s *MemoryStorage) SetUserData(chatID int64, data models.UserData) {
+func (s *MemoryStorage) SetSession(chatID int64, session models.Session) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	log.Printf("Storage: SetUserData for chat %d: Name='%s', City='%s', Data='%s', Category='%s'",
-		chatID, data.Name, data.City, data.Data, data.Category)
-	s.userData[chatID] = data
+	log.Printf("Storage: SetSession for chat %d: %+v", chatID, session)
+	s.sessions[chatID] = session
 	s.updateLastAccess(chatID)
-	log.Printf("Storage: User data set successfully for chat %d", chatID)
 }
 
 func (s *MemoryStorage) GetLastMessageID(chatID int64) (int, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	messageID, exists := s.lastBotMessages[chatID]
 	if exists {
@@ -185,7 +197,8 @@ func (s *MemoryStorage) ClearUserData(chatID int64) {
 	defer s.mu.Unlock()
 
 	delete(s.userStates, chatID)
-	delete(s.userData, chatID)
+	delete(s.stateChangedAt, chatID)
+	delete(s.sessions, chatID)
 	delete(s.lastBotMessages, chatID)
 	delete(s.messageHistory, chatID)
 	delete(s.lastAccess, chatID)
@@ -198,7 +211,7 @@ func (s *MemoryStorage) GetStats() map[string]interface{} {
 
 	return map[string]interface{}{
 		"user_states_size":     len(s.userStates),
-		"user_data_size":       len(s.userData),
+		"sessions_size":        len(s.sessions),
 		"last_messages_size":   len(s.lastBotMessages),
 		"message_history_size": len(s.messageHistory),
 		"active_users":         len(s.lastAccess),
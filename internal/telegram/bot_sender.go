@@ -0,0 +1,83 @@
+package telegram
+
+import (
+	"fmt"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// BotSender отправляет запросы через клиент tgbotapi
+type BotSender struct {
+	api *tgbotapi.BotAPI
+}
+
+func NewBotSender(api *tgbotapi.BotAPI) *BotSender {
+	return &BotSender{api: api}
+}
+
+func (s *BotSender) SendText(msg Text) (int, error) {
+	config := tgbotapi.NewMessage(msg.ChatID, msg.Text)
+	config.ParseMode = msg.ParseMode
+	config.ReplyMarkup = msg.ReplyMarkup
+
+	sent, err := s.api.Send(config)
+	return sent.MessageID, err
+}
+
+func (s *BotSender) SendMedia(msg Media) (int, error) {
+	file := tgbotapi.FileID(msg.FileID)
+
+	var config tgbotapi.Chattable
+	switch msg.Kind {
+	case MediaPhoto:
+		photo := tgbotapi.NewPhoto(msg.ChatID, file)
+		photo.Caption, photo.ParseMode, photo.ReplyMarkup = msg.Caption, msg.ParseMode, msg.ReplyMarkup
+		config = photo
+	case MediaVideo:
+		video := tgbotapi.NewVideo(msg.ChatID, file)
+		video.Caption, video.ParseMode, video.ReplyMarkup = msg.Caption, msg.ParseMode, msg.ReplyMarkup
+		config = video
+	case MediaVoice:
+		voice := tgbotapi.NewVoice(msg.ChatID, file)
+		voice.Caption, voice.ParseMode, voice.ReplyMarkup = msg.Caption, msg.ParseMode, msg.ReplyMarkup
+		config = voice
+	case MediaDocument:
+		document := tgbotapi.NewDocument(msg.ChatID, file)
+		document.Caption, document.ParseMode, document.ReplyMarkup = msg.Caption, msg.ParseMode, msg.ReplyMarkup
+		config = document
+	default:
+		return 0, fmt.Errorf("unsupported media kind: %s", msg.Kind)
+	}
+
+	sent, err := s.api.Send(config)
+	return sent.MessageID, err
+}
+
+func (s *BotSender) Edit(edit Edit) error {
+	var config tgbotapi.Chattable
+	if edit.Text == "" {
+		markup := tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}
+		if edit.Markup != nil {
+			markup = *edit.Markup
+		}
+		config = tgbotapi.NewEditMessageReplyMarkup(edit.ChatID, edit.MessageID, markup)
+	} else {
+		text := tgbotapi.NewEditMessageText(edit.ChatID, edit.MessageID, edit.Text)
+		text.ParseMode = edit.ParseMode
+		text.ReplyMarkup = edit.Markup
+		config = text
+	}
+
+	_, err := s.api.Request(config)
+	return err
+}
+
+func (s *BotSender) Delete(chatID int64, messageID int) error {
+	_, err := s.api.Request(tgbotapi.NewDeleteMessage(chatID, messageID))
+	return err
+}
+
+func (s *BotSender) AnswerCallback(queryID, text string) error {
+	_, err := s.api.Request(tgbotapi.NewCallback(queryID, text))
+	return err
+}
@@ -0,0 +1,83 @@
+package telegram_test
+
+import (
+	"GreenAssistantBot/internal/telegram"
+	"GreenAssistantBot/internal/telegram/telegramtest"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func newBotSender(t *testing.T) (*telegram.BotSender, *telegramtest.Server) {
+	t.Helper()
+	server := telegramtest.NewServer()
+	t.Cleanup(server.Close)
+
+	api, err := server.NewBot()
+	if err != nil {
+		t.Fatalf("connect to fake Bot API: %v", err)
+	}
+	return telegram.NewBotSender(api), server
+}
+
+func TestBotSenderMethods(t *testing.T) {
+	sender, server := newBotSender(t)
+	markup := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("OK", "ok"),
+	))
+
+	id, err := sender.SendText(telegram.Text{ChatID: 7, Text: "<b>hi</b>", ParseMode: tgbotapi.ModeHTML, ReplyMarkup: markup})
+	if err != nil || id == 0 {
+		t.Fatalf("SendText: id=%d err=%v", id, err)
+	}
+	if _, err := sender.SendMedia(telegram.Media{ChatID: 7, Kind: telegram.MediaPhoto, FileID: "photo-id", Caption: "cap"}); err != nil {
+		t.Fatalf("SendMedia: %v", err)
+	}
+	if err := sender.Edit(telegram.Edit{ChatID: 7, MessageID: id, Text: "edited", Markup: &markup}); err != nil {
+		t.Fatalf("Edit text: %v", err)
+	}
+	if err := sender.Edit(telegram.Edit{ChatID: 7, MessageID: id}); err != nil {
+		t.Fatalf("Edit markup: %v", err)
+	}
+	if err := sender.Delete(7, id); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := sender.AnswerCallback("query", "done"); err != nil {
+		t.Fatalf("AnswerCallback: %v", err)
+	}
+
+	calls := server.Calls()
+	want := []string{"sendMessage", "sendPhoto", "editMessageText", "editMessageReplyMarkup", "deleteMessage", "answerCallbackQuery"}
+	if len(calls) != len(want) {
+		t.Fatalf("expected %d calls, got %v", len(want), calls)
+	}
+	for i, method := range want {
+		if calls[i].Method != method {
+			t.Errorf("call %d: expected %s, got %s", i, method, calls[i].Method)
+		}
+	}
+
+	if calls[0].Params.Get("parse_mode") != tgbotapi.ModeHTML || len(calls[0].Buttons()) != 1 {
+		t.Errorf("sendMessage lost parse mode or keyboard: %v", calls[0].Params)
+	}
+	if calls[1].Params.Get("photo") != "photo-id" || calls[1].Text() != "cap" {
+		t.Errorf("unexpected sendPhoto params: %v", calls[1].Params)
+	}
+	if len(calls[3].Buttons()) != 0 {
+		t.Errorf("markup-only edit without Markup should remove buttons, got %v", calls[3].Buttons())
+	}
+	if calls[5].Params.Get("callback_query_id") != "query" || calls[5].Params.Get("text") != "done" {
+		t.Errorf("unexpected answerCallbackQuery params: %v", calls[5].Params)
+	}
+}
+
+func TestBotSenderRejectsUnknownMedia(t *testing.T) {
+	sender, server := newBotSender(t)
+
+	if _, err := sender.SendMedia(telegram.Media{ChatID: 7, Kind: "sticker", FileID: "x"}); err == nil {
+		t.Fatal("expected error for unsupported media kind")
+	}
+	if calls := server.Calls(); len(calls) != 0 {
+		t.Errorf("expected no requests, got %v", calls)
+	}
+}
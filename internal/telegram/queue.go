@@ -0,0 +1,281 @@
+package telegram
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// QueueConfig — лимиты исходящих запросов и параметры повторов
+type QueueConfig struct {
+	// GlobalRate — сколько запросов в секунду бот отправляет во все чаты вместе
+	GlobalRate int
+	// ChatInterval — средний интервал между сообщениями в один чат
+	ChatInterval time.Duration
+	// ChatBurst — сколько сообщений подряд можно отправить в чат без ожидания,
+	// например ответ из нескольких частей
+	ChatBurst int
+	// MaxRetries — сколько раз повторять запрос после временной ошибки
+	MaxRetries int
+	// BaseBackoff — пауза перед первым повтором, дальше она удваивается до MaxBackoff
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// DefaultQueueConfig возвращает лимиты Telegram: около 30 сообщений в секунду
+// всего и одно сообщение в секунду в каждый чат
+func DefaultQueueConfig() QueueConfig {
+	return QueueConfig{
+		GlobalRate:   30,
+		ChatInterval: time.Second,
+		ChatBurst:    3,
+		MaxRetries:   3,
+		BaseBackoff:  500 * time.Millisecond,
+		MaxBackoff:   30 * time.Second,
+	}
+}
+
+// Через сколько удалять из памяти лимиты чатов, в которые ничего не отправлялось
+const chatLimitsSweepInterval = time.Minute
+
+// Queue — общая очередь исходящих запросов. Запрос ждет своей очереди с учетом
+// общего лимита и лимита чата, после ответа 429 ждет retry_after, а временные
+// ошибки (сеть, 5xx) повторяет с растущей паузой. Ошибки вроде 400 и 403
+// возвращаются сразу. Очередь сама реализует Sender и оборачивает другой Sender.
+type Queue struct {
+	sender Sender
+	config QueueConfig
+
+	// now и sleep подменяются в тестах
+	now   func() time.Time
+	sleep func(time.Duration)
+
+	mu        sync.Mutex
+	global    gcra
+	chats     map[int64]*gcra
+	lastSweep time.Time
+
+	sent      atomic.Int64
+	retried   atomic.Int64
+	throttled atomic.Int64
+	failed    atomic.Int64
+}
+
+var _ Sender = (*Queue)(nil)
+
+func NewQueue(sender Sender, config QueueConfig) *Queue {
+	defaults := DefaultQueueConfig()
+	if config.GlobalRate <= 0 {
+		config.GlobalRate = defaults.GlobalRate
+	}
+	if config.ChatInterval <= 0 {
+		config.ChatInterval = defaults.ChatInterval
+	}
+	if config.ChatBurst <= 0 {
+		config.ChatBurst = defaults.ChatBurst
+	}
+	if config.BaseBackoff <= 0 {
+		config.BaseBackoff = defaults.BaseBackoff
+	}
+	if config.MaxBackoff < config.BaseBackoff {
+		config.MaxBackoff = config.BaseBackoff
+	}
+
+	return &Queue{
+		sender: sender,
+		config: config,
+		now:    time.Now,
+		sleep:  time.Sleep,
+		// Общий лимит без всплесков, чтобы за любую секунду уходило не больше GlobalRate запросов
+		global:    gcra{interval: time.Second / time.Duration(config.GlobalRate), burst: 1},
+		chats:     make(map[int64]*gcra),
+		lastSweep: time.Now(),
+	}
+}
+
+func (q *Queue) SendText(msg Text) (int, error) {
+	var messageID int
+	err := q.do(msg.ChatID, func() (err error) {
+		messageID, err = q.sender.SendText(msg)
+		return err
+	})
+	return messageID, err
+}
+
+func (q *Queue) SendMedia(msg Media) (int, error) {
+	var messageID int
+	err := q.do(msg.ChatID, func() (err error) {
+		messageID, err = q.sender.SendMedia(msg)
+		return err
+	})
+	return messageID, err
+}
+
+func (q *Queue) Edit(edit Edit) error {
+	return q.do(edit.ChatID, func() error {
+		return q.sender.Edit(edit)
+	})
+}
+
+// Delete не расходует лимит чата: Telegram ограничивает только отправку сообщений
+func (q *Queue) Delete(chatID int64, messageID int) error {
+	return q.do(0, func() error {
+		return q.sender.Delete(chatID, messageID)
+	})
+}
+
+func (q *Queue) AnswerCallback(queryID, text string) error {
+	return q.do(0, func() error {
+		return q.sender.AnswerCallback(queryID, text)
+	})
+}
+
+// GetStats возвращает счетчики очереди для мониторинга
+func (q *Queue) GetStats() map[string]interface{} {
+	q.mu.Lock()
+	chats := len(q.chats)
+	q.mu.Unlock()
+
+	return map[string]interface{}{
+		"sent":      q.sent.Load(),
+		"retried":   q.retried.Load(),
+		"throttled": q.throttled.Load(),
+		"failed":    q.failed.Load(),
+		"chats":     chats,
+	}
+}
+
+// do выполняет запрос в свою очередь и повторяет его после временных ошибок.
+// chatID 0 — запрос учитывается только в общем лимите.
+func (q *Queue) do(chatID int64, request func() error) error {
+	backoff := q.config.BaseBackoff
+
+	for attempt := 0; ; attempt++ {
+		q.sleep(q.reserve(chatID))
+
+		err := request()
+		if err == nil {
+			q.sent.Add(1)
+			return nil
+		}
+		if attempt >= q.config.MaxRetries {
+			q.failed.Add(1)
+			return err
+		}
+
+		var apiErr *tgbotapi.Error
+		switch {
+		case errors.As(err, &apiErr) && apiErr.RetryAfter > 0:
+			// 429 Too Many Requests: Telegram сообщает, сколько секунд ждать
+			q.throttled.Add(1)
+			wait := time.Duration(apiErr.RetryAfter) * time.Second
+			log.Printf("Telegram flood limit for chat %d, retrying in %s", chatID, wait)
+			q.pause(chatID, wait)
+		case IsPermanent(err):
+			// Ошибка в самом запросе или бот заблокирован — повтор не поможет
+			q.failed.Add(1)
+			return err
+		default:
+			log.Printf("Telegram request for chat %d failed, retrying in %s: %v", chatID, backoff, err)
+			q.sleep(backoff)
+			backoff = min(backoff*2, q.config.MaxBackoff)
+		}
+		q.retried.Add(1)
+	}
+}
+
+// reserve занимает ближайшее место в общем лимите и лимите чата и возвращает,
+// сколько нужно подождать до отправки
+func (q *Queue) reserve(chatID int64) time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	at := q.global.earliest(now)
+	if chatID != 0 {
+		chat := q.chat(chatID)
+		if chatAt := chat.earliest(now); chatAt.After(at) {
+			at = chatAt
+		}
+		chat.take(at)
+	}
+	q.global.take(at)
+	q.sweepLocked(now)
+
+	return at.Sub(now)
+}
+
+// pause запрещает отправку в чат (или во все чаты, если chatID 0) на wait
+func (q *Queue) pause(chatID int64, wait time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	until := q.now().Add(wait)
+	if chatID == 0 {
+		q.global.block(until)
+		return
+	}
+	q.chat(chatID).block(until)
+}
+
+// chat возвращает лимит чата. Вызывается под q.mu.
+func (q *Queue) chat(chatID int64) *gcra {
+	limit, ok := q.chats[chatID]
+	if !ok {
+		limit = &gcra{interval: q.config.ChatInterval, burst: q.config.ChatBurst}
+		q.chats[chatID] = limit
+	}
+	return limit
+}
+
+// sweepLocked удаляет лимиты чатов, которые уже полностью восстановились.
+// Вызывается под q.mu.
+func (q *Queue) sweepLocked(now time.Time) {
+	if now.Sub(q.lastSweep) < chatLimitsSweepInterval {
+		return
+	}
+	q.lastSweep = now
+
+	for chatID, limit := range q.chats {
+		if !limit.tat.After(now) {
+			delete(q.chats, chatID)
+		}
+	}
+}
+
+// gcra ограничивает частоту запросов по алгоритму GCRA: в среднем один запрос
+// за interval, подряд без ожидания — до burst запросов
+type gcra struct {
+	interval time.Duration
+	burst    int
+	// tat — момент, к которому лимит полностью восстановится
+	tat time.Time
+}
+
+// earliest возвращает ближайший момент не раньше now, когда можно отправить запрос
+func (g *gcra) earliest(now time.Time) time.Time {
+	at := g.tat.Add(-time.Duration(g.burst-1) * g.interval)
+	if at.Before(now) {
+		return now
+	}
+	return at
+}
+
+// take учитывает запрос, отправленный в момент at
+func (g *gcra) take(at time.Time) {
+	if g.tat.Before(at) {
+		g.tat = at
+	}
+	g.tat = g.tat.Add(g.interval)
+}
+
+// block откладывает следующий запрос до until
+func (g *gcra) block(until time.Time) {
+	if tat := until.Add(time.Duration(g.burst-1) * g.interval); tat.After(g.tat) {
+		g.tat = tat
+	}
+}
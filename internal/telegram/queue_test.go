@@ -0,0 +1,178 @@
+package telegram
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// fakeClock — виртуальное время: sleep сдвигает часы вместо ожидания
+type fakeClock struct {
+	now   time.Time
+	slept time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	if d > 0 {
+		c.now = c.now.Add(d)
+		c.slept += d
+	}
+}
+
+// flakySender возвращает ошибки из errs по очереди, а затем отвечает успешно
+type flakySender struct {
+	errs  []error
+	calls []time.Time
+	clock *fakeClock
+}
+
+func (s *flakySender) next() error {
+	s.calls = append(s.calls, s.clock.Now())
+	if len(s.errs) == 0 {
+		return nil
+	}
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	return err
+}
+
+func (s *flakySender) SendText(Text) (int, error)          { return len(s.calls) + 1, s.next() }
+func (s *flakySender) SendMedia(Media) (int, error)        { return len(s.calls) + 1, s.next() }
+func (s *flakySender) Edit(Edit) error                     { return s.next() }
+func (s *flakySender) Delete(int64, int) error             { return s.next() }
+func (s *flakySender) AnswerCallback(string, string) error { return s.next() }
+
+func newTestQueue(config QueueConfig, errs ...error) (*Queue, *flakySender, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	sender := &flakySender{errs: errs, clock: clock}
+	queue := NewQueue(sender, config)
+	queue.now, queue.sleep = clock.Now, clock.Sleep
+	return queue, sender, clock
+}
+
+func TestQueueChatLimit(t *testing.T) {
+	queue, sender, clock := newTestQueue(QueueConfig{GlobalRate: 30, ChatInterval: time.Second, ChatBurst: 2})
+
+	for i := 0; i < 4; i++ {
+		if _, err := queue.SendText(Text{ChatID: 1, Text: "hi"}); err != nil {
+			t.Fatalf("SendText: %v", err)
+		}
+	}
+
+	// Два сообщения уходят сразу, дальше по одному в секунду
+	start := sender.calls[0]
+	want := []time.Duration{0, 33333333, time.Second, 2 * time.Second}
+	for i, at := range sender.calls {
+		if got := at.Sub(start); got != want[i] {
+			t.Errorf("message %d sent at +%s, want +%s", i, got, want[i])
+		}
+	}
+	if clock.slept != 2*time.Second {
+		t.Errorf("expected to wait 2s in total, waited %s", clock.slept)
+	}
+}
+
+func TestQueueGlobalLimit(t *testing.T) {
+	queue, sender, clock := newTestQueue(QueueConfig{GlobalRate: 10, ChatInterval: time.Second, ChatBurst: 1})
+
+	for chatID := int64(1); chatID <= 11; chatID++ {
+		if _, err := queue.SendText(Text{ChatID: chatID, Text: "hi"}); err != nil {
+			t.Fatalf("SendText: %v", err)
+		}
+	}
+
+	// 11 разных чатов при лимите 10 в секунду — последний уходит через секунду после первого
+	if elapsed := sender.calls[10].Sub(sender.calls[0]); elapsed != time.Second {
+		t.Errorf("expected 11th message after 1s, got %s", elapsed)
+	}
+	if clock.slept != time.Second {
+		t.Errorf("expected to wait 1s in total, waited %s", clock.slept)
+	}
+}
+
+func TestQueueRetryAfter(t *testing.T) {
+	flood := &tgbotapi.Error{Code: 429, Message: "Too Many Requests", ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 5}}
+	queue, sender, _ := newTestQueue(DefaultQueueConfig(), flood)
+
+	if _, err := queue.SendText(Text{ChatID: 1, Text: "hi"}); err != nil {
+		t.Fatalf("SendText: %v", err)
+	}
+	if len(sender.calls) != 2 {
+		t.Fatalf("expected one retry, got %d calls", len(sender.calls))
+	}
+	if wait := sender.calls[1].Sub(sender.calls[0]); wait < 5*time.Second {
+		t.Errorf("retried after %s, want at least retry_after 5s", wait)
+	}
+
+	if stats := queue.GetStats(); stats["throttled"] != int64(1) || stats["retried"] != int64(1) {
+		t.Errorf("unexpected stats: %v", stats)
+	}
+}
+
+func TestQueueRetryAfterPausesOnlyChat(t *testing.T) {
+	queue, _, clock := newTestQueue(DefaultQueueConfig())
+	queue.pause(1, 10*time.Second)
+
+	if _, err := queue.SendText(Text{ChatID: 2, Text: "hi"}); err != nil {
+		t.Fatalf("SendText: %v", err)
+	}
+	if clock.slept != 0 {
+		t.Errorf("other chat waited %s", clock.slept)
+	}
+
+	if _, err := queue.SendText(Text{ChatID: 1, Text: "hi"}); err != nil {
+		t.Fatalf("SendText: %v", err)
+	}
+	if clock.slept < 10*time.Second {
+		t.Errorf("paused chat waited only %s", clock.slept)
+	}
+}
+
+func TestQueueTransientErrorBackoff(t *testing.T) {
+	config := QueueConfig{MaxRetries: 3, BaseBackoff: time.Second, MaxBackoff: 3 * time.Second}
+	network := errors.New("connection reset")
+	server := &tgbotapi.Error{Code: 502, Message: "Bad Gateway"}
+	queue, sender, clock := newTestQueue(config, network, server, network)
+
+	if err := queue.Edit(Edit{ChatID: 1, MessageID: 1, Text: "x"}); err != nil {
+		t.Fatalf("Edit: %v", err)
+	}
+	if len(sender.calls) != 4 {
+		t.Fatalf("expected 3 retries, got %d calls", len(sender.calls))
+	}
+	// Паузы 1s, 2s и 3s (ограничены MaxBackoff)
+	if clock.slept < 6*time.Second {
+		t.Errorf("expected backoff of at least 6s, waited %s", clock.slept)
+	}
+}
+
+func TestQueueGivesUp(t *testing.T) {
+	network := errors.New("timeout")
+	queue, sender, _ := newTestQueue(QueueConfig{MaxRetries: 2}, network, network, network, network)
+
+	if _, err := queue.SendText(Text{ChatID: 1, Text: "hi"}); !errors.Is(err, network) {
+		t.Fatalf("expected last error, got %v", err)
+	}
+	if len(sender.calls) != 3 {
+		t.Errorf("expected 3 attempts, got %d", len(sender.calls))
+	}
+	if stats := queue.GetStats(); stats["failed"] != int64(1) {
+		t.Errorf("unexpected stats: %v", stats)
+	}
+}
+
+func TestQueuePermanentErrorNotRetried(t *testing.T) {
+	blocked := &tgbotapi.Error{Code: 403, Message: "Forbidden: bot was blocked by the user"}
+	queue, sender, _ := newTestQueue(DefaultQueueConfig(), blocked)
+
+	if _, err := queue.SendText(Text{ChatID: 1, Text: "hi"}); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(sender.calls) != 1 {
+		t.Errorf("permanent error must not be retried, got %d calls", len(sender.calls))
+	}
+}
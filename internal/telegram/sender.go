@@ -0,0 +1,72 @@
+// Package telegram отделяет обработчики бота от клиента Telegram Bot API
+package telegram
+
+import (
+	"errors"
+	"net/http"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Виды медиа-сообщений
+type MediaKind string
+
+const (
+	MediaPhoto    MediaKind = "photo"
+	MediaVideo    MediaKind = "video"
+	MediaVoice    MediaKind = "voice"
+	MediaDocument MediaKind = "document"
+)
+
+// Text — текстовое сообщение
+type Text struct {
+	ChatID    int64
+	Text      string
+	ParseMode string
+	// ReplyMarkup — обычная или inline-клавиатура, nil — без клавиатуры
+	ReplyMarkup interface{}
+}
+
+// Media — фото, видео, голосовое сообщение или файл, уже загруженные в Telegram
+type Media struct {
+	ChatID      int64
+	Kind        MediaKind
+	FileID      string
+	Caption     string
+	ParseMode   string
+	ReplyMarkup interface{}
+}
+
+// Edit — изменение отправленного сообщения. Если Text пустой, меняются только
+// inline-кнопки.
+type Edit struct {
+	ChatID    int64
+	MessageID int
+	Text      string
+	ParseMode string
+	// Markup — новые inline-кнопки, nil убирает их
+	Markup *tgbotapi.InlineKeyboardMarkup
+}
+
+// Sender отправляет запросы к Telegram от имени бота
+type Sender interface {
+	// SendText отправляет текстовое сообщение и возвращает его ID
+	SendText(msg Text) (int, error)
+	// SendMedia отправляет медиа-сообщение и возвращает его ID
+	SendMedia(msg Media) (int, error)
+	// Edit изменяет текст или inline-кнопки сообщения
+	Edit(edit Edit) error
+	// Delete удаляет сообщение
+	Delete(chatID int64, messageID int) error
+	// AnswerCallback отвечает на нажатие inline-кнопки; пустой text просто
+	// убирает индикатор загрузки
+	AnswerCallback(queryID, text string) error
+}
+
+// IsPermanent сообщает, что Telegram отклонил запрос и повтор не поможет:
+// ошибка в самом запросе, чат не найден или бот заблокирован. Сетевые ошибки,
+// 429 и 5xx считаются временными.
+func IsPermanent(err error) bool {
+	var apiErr *tgbotapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code != http.StatusTooManyRequests && apiErr.Code < http.StatusInternalServerError
+}
@@ -0,0 +1,110 @@
+package telegramtest
+
+import (
+	"GreenAssistantBot/internal/telegram"
+	"sync"
+)
+
+// Виды записанных запросов
+const (
+	SentText     = "text"
+	SentMedia    = "media"
+	SentEdit     = "edit"
+	SentDelete   = "delete"
+	SentCallback = "callback"
+)
+
+// Sent — запрос, записанный Recorder
+type Sent struct {
+	Kind      string
+	ChatID    int64
+	MessageID int
+	// Text — текст сообщения, подпись к медиа или ответ на нажатие кнопки
+	Text        string
+	ParseMode   string
+	ReplyMarkup interface{}
+	Media       telegram.MediaKind
+	FileID      string
+	QueryID     string
+}
+
+// Recorder — telegram.Sender для модульных тестов: ничего не отправляет,
+// а записывает запросы и выдает сообщениям последовательные ID
+type Recorder struct {
+	// Err, если задана, возвращается из всех методов, а запросы не записываются
+	Err error
+
+	mu     sync.Mutex
+	sent   []Sent
+	nextID int
+}
+
+var _ telegram.Sender = (*Recorder)(nil)
+
+func NewRecorder() *Recorder {
+	return &Recorder{nextID: 1}
+}
+
+// Sent возвращает все записанные запросы
+func (r *Recorder) Sent() []Sent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Sent(nil), r.sent...)
+}
+
+// Take возвращает записанные запросы и очищает журнал
+func (r *Recorder) Take() []Sent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sent := r.sent
+	r.sent = nil
+	return sent
+}
+
+func (r *Recorder) SendText(msg telegram.Text) (int, error) {
+	return r.record(Sent{Kind: SentText, ChatID: msg.ChatID, Text: msg.Text, ParseMode: msg.ParseMode, ReplyMarkup: msg.ReplyMarkup}, true)
+}
+
+func (r *Recorder) SendMedia(msg telegram.Media) (int, error) {
+	return r.record(Sent{
+		Kind: SentMedia, ChatID: msg.ChatID, Text: msg.Caption, ParseMode: msg.ParseMode,
+		ReplyMarkup: msg.ReplyMarkup, Media: msg.Kind, FileID: msg.FileID,
+	}, true)
+}
+
+func (r *Recorder) Edit(edit telegram.Edit) error {
+	sent := Sent{Kind: SentEdit, ChatID: edit.ChatID, MessageID: edit.MessageID, Text: edit.Text, ParseMode: edit.ParseMode}
+	if edit.Markup != nil {
+		sent.ReplyMarkup = *edit.Markup
+	}
+	_, err := r.record(sent, false)
+	return err
+}
+
+func (r *Recorder) Delete(chatID int64, messageID int) error {
+	_, err := r.record(Sent{Kind: SentDelete, ChatID: chatID, MessageID: messageID}, false)
+	return err
+}
+
+func (r *Recorder) AnswerCallback(queryID, text string) error {
+	_, err := r.record(Sent{Kind: SentCallback, QueryID: queryID, Text: text}, false)
+	return err
+}
+
+// record записывает запрос; новым сообщениям присваивается следующий ID
+func (r *Recorder) record(sent Sent, newMessage bool) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Err != nil {
+		return 0, r.Err
+	}
+	if newMessage {
+		sent.MessageID = r.nextID
+		r.nextID++
+	}
+	r.sent = append(r.sent, sent)
+	return sent.MessageID, nil
+}
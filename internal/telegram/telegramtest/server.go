@@ -0,0 +1,313 @@
+// Package telegramtest содержит фейковый сервер Telegram Bot API для тестов.
+// Сервер работает в процессе теста, записывает все запросы бота и отвечает
+// так же, как настоящий API, поэтому обработчики можно проверять без сети.
+package telegramtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Token — токен, под которым бот подключается к фейковому серверу
+const Token = "123456:TEST"
+
+// Bot — пользователь, которого возвращает getMe
+var Bot = tgbotapi.User{ID: 123456, IsBot: true, FirstName: "GreenAssistantBot", UserName: "green_assistant_test_bot"}
+
+// maxPollWait ограничивает ожидание новых обновлений в getUpdates, чтобы тесты не зависали
+const maxPollWait = 5 * time.Second
+
+// Call — запрос бота к Bot API
+type Call struct {
+	Method string
+	Params url.Values
+	// Files — имена загруженных файлов по названию поля формы
+	Files map[string]string
+}
+
+// ChatID возвращает чат, которому адресован запрос
+func (c Call) ChatID() int64 {
+	id, _ := strconv.ParseInt(c.Params.Get("chat_id"), 10, 64)
+	return id
+}
+
+// Text возвращает текст сообщения или подпись к медиа
+func (c Call) Text() string {
+	if text := c.Params.Get("text"); text != "" {
+		return text
+	}
+	return c.Params.Get("caption")
+}
+
+// String помогает читать журнал запросов в сообщениях об ошибках тестов
+func (c Call) String() string {
+	return fmt.Sprintf("%s(chat=%d): %q", c.Method, c.ChatID(), c.Text())
+}
+
+// Buttons возвращает подписи всех кнопок обычной или inline-клавиатуры
+func (c Call) Buttons() []string {
+	var markup struct {
+		Keyboard       [][]tgbotapi.KeyboardButton       `json:"keyboard"`
+		InlineKeyboard [][]tgbotapi.InlineKeyboardButton `json:"inline_keyboard"`
+	}
+	if err := json.Unmarshal([]byte(c.Params.Get("reply_markup")), &markup); err != nil {
+		return nil
+	}
+
+	var buttons []string
+	for _, row := range markup.Keyboard {
+		for _, button := range row {
+			buttons = append(buttons, button.Text)
+		}
+	}
+	for _, row := range markup.InlineKeyboard {
+		for _, button := range row {
+			buttons = append(buttons, button.Text)
+		}
+	}
+	return buttons
+}
+
+// CallbackData возвращает callback_data первой inline-кнопки, подпись которой
+// содержит text
+func (c Call) CallbackData(text string) (string, bool) {
+	var markup tgbotapi.InlineKeyboardMarkup
+	if err := json.Unmarshal([]byte(c.Params.Get("reply_markup")), &markup); err != nil {
+		return "", false
+	}
+
+	for _, row := range markup.InlineKeyboard {
+		for _, button := range row {
+			if strings.Contains(button.Text, text) && button.CallbackData != nil {
+				return *button.CallbackData, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Server — фейковый Bot API. Поддерживает getMe, getUpdates, sendMessage, sendPhoto,
+// editMessageText, editMessageReplyMarkup, deleteMessage и answerCallbackQuery.
+// На остальные методы отвечает ошибкой 404, но тоже записывает их.
+type Server struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	calls         []Call
+	updates       []tgbotapi.Update
+	nextMessageID int
+	// newUpdate закрывается и пересоздается при добавлении обновления, чтобы
+	// разбудить ожидающий getUpdates
+	newUpdate chan struct{}
+}
+
+// NewServer запускает фейковый сервер. Его нужно остановить через Close.
+func NewServer() *Server {
+	s := &Server{nextMessageID: 1, newUpdate: make(chan struct{})}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Endpoint возвращает шаблон адреса API для tgbotapi.NewBotAPIWithAPIEndpoint
+func (s *Server) Endpoint() string {
+	return s.URL + "/bot%s/%s"
+}
+
+// NewBot подключает к серверу клиента tgbotapi
+func (s *Server) NewBot() (*tgbotapi.BotAPI, error) {
+	return tgbotapi.NewBotAPIWithAPIEndpoint(Token, s.Endpoint())
+}
+
+// PushUpdate добавляет обновление, которое бот получит через getUpdates
+func (s *Server) PushUpdate(update tgbotapi.Update) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.updates = append(s.updates, update)
+	close(s.newUpdate)
+	s.newUpdate = make(chan struct{})
+}
+
+// Calls возвращает все записанные запросы, кроме getMe и getUpdates
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Call(nil), s.calls...)
+}
+
+// TakeCalls возвращает записанные запросы и очищает журнал
+func (s *Server) TakeCalls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	calls := s.calls
+	s.calls = nil
+	return calls
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	token, method, ok := parsePath(r.URL.Path)
+	if !ok || token != Token {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	call, err := parseCall(method, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Bad Request: "+err.Error())
+		return
+	}
+
+	switch method {
+	case "getMe":
+		writeResult(w, Bot)
+	case "getUpdates":
+		writeResult(w, s.pollUpdates(r, call.Params))
+	case "sendMessage", "sendPhoto":
+		s.record(call)
+		writeResult(w, s.newMessage(call))
+	case "editMessageText", "editMessageReplyMarkup":
+		s.record(call)
+		message := s.message(call)
+		message.MessageID, _ = strconv.Atoi(call.Params.Get("message_id"))
+		writeResult(w, message)
+	case "deleteMessage", "answerCallbackQuery":
+		s.record(call)
+		writeResult(w, true)
+	default:
+		s.record(call)
+		writeError(w, http.StatusNotFound, "Not Found: method "+method+" is not supported")
+	}
+}
+
+func (s *Server) record(call Call) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = append(s.calls, call)
+}
+
+// pollUpdates возвращает обновления начиная с offset. Если их нет, ждет новых
+// не дольше timeout из запроса.
+func (s *Server) pollUpdates(r *http.Request, params url.Values) []tgbotapi.Update {
+	offset, _ := strconv.Atoi(params.Get("offset"))
+	timeout, _ := strconv.Atoi(params.Get("timeout"))
+	wait := time.Duration(timeout) * time.Second
+	if wait > maxPollWait {
+		wait = maxPollWait
+	}
+	deadline := time.After(wait)
+
+	for {
+		s.mu.Lock()
+		updates := []tgbotapi.Update{}
+		for _, update := range s.updates {
+			if update.UpdateID >= offset {
+				updates = append(updates, update)
+			}
+		}
+		newUpdate := s.newUpdate
+		s.mu.Unlock()
+
+		if len(updates) > 0 || wait == 0 {
+			return updates
+		}
+
+		select {
+		case <-newUpdate:
+		case <-deadline:
+			return []tgbotapi.Update{}
+		case <-r.Context().Done():
+			return []tgbotapi.Update{}
+		}
+	}
+}
+
+// newMessage создает сообщение бота, которое API вернул бы на send-запрос
+func (s *Server) newMessage(call Call) tgbotapi.Message {
+	s.mu.Lock()
+	id := s.nextMessageID
+	s.nextMessageID++
+	s.mu.Unlock()
+
+	message := s.message(call)
+	message.MessageID = id
+	if call.Method == "sendPhoto" {
+		fileID := call.Params.Get("photo")
+		if name, ok := call.Files["photo"]; ok {
+			fileID = "uploaded:" + name
+		}
+		message.Photo = []tgbotapi.PhotoSize{{FileID: fileID, FileUniqueID: fileID}}
+		message.Caption = call.Params.Get("caption")
+	}
+	return message
+}
+
+func (s *Server) message(call Call) tgbotapi.Message {
+	bot := Bot
+	return tgbotapi.Message{
+		From: &bot,
+		Date: int(time.Now().Unix()),
+		Chat: &tgbotapi.Chat{ID: call.ChatID(), Type: "private"},
+		Text: call.Params.Get("text"),
+	}
+}
+
+// parsePath разбирает путь вида /bot<token>/<method>
+func parsePath(path string) (string, string, bool) {
+	path = strings.TrimPrefix(path, "/")
+	if !strings.HasPrefix(path, "bot") {
+		return "", "", false
+	}
+	token, method, ok := strings.Cut(strings.TrimPrefix(path, "bot"), "/")
+	return token, method, ok && method != ""
+}
+
+// parseCall читает параметры запроса: tgbotapi отправляет обычную форму, а при
+// загрузке файлов — multipart
+func parseCall(method string, r *http.Request) (Call, error) {
+	call := Call{Method: method, Files: make(map[string]string)}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			return call, err
+		}
+		for field, headers := range r.MultipartForm.File {
+			if len(headers) > 0 {
+				call.Files[field] = headers[0].Filename
+			}
+		}
+	} else if err := r.ParseForm(); err != nil {
+		return call, err
+	}
+
+	call.Params = r.Form
+	return call, nil
+}
+
+func writeResult(w http.ResponseWriter, result interface{}) {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tgbotapi.APIResponse{Ok: true, Result: raw})
+}
+
+func writeError(w http.ResponseWriter, code int, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(tgbotapi.APIResponse{Ok: false, ErrorCode: code, Description: description})
+}
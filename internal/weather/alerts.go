@@ -0,0 +1,105 @@
+package weather
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Виды погодных предупреждений
+const (
+	AlertStorm     = "storm"
+	AlertHeavyRain = "heavy_rain"
+	AlertHeavySnow = "heavy_snow"
+	AlertFrost     = "frost"
+	AlertWind      = "wind"
+)
+
+const (
+	// Осадки за трехчасовой интервал, начиная с которых дождь считается сильным, мм
+	heavyRainThreshold = 10.0
+	// Осадки за трехчасовой интервал, начиная с которых снег считается сильным, мм
+	heavySnowThreshold = 5.0
+	// Скорость ветра, начиная с которой ветер считается сильным, м/с
+	strongWindThreshold = 15.0
+)
+
+// Alert — ожидаемое опасное явление погоды
+type Alert struct {
+	Kind string
+	// Time — начало первого интервала прогноза, в котором ожидается явление
+	Time time.Time
+	// Value — температура, осадки или скорость ветра в этом интервале
+	Value float64
+}
+
+// AlertThresholds — пороги предупреждений, которые выбирает пользователь
+type AlertThresholds struct {
+	// FrostTemp — температура, при которой и ниже предупреждаем о морозе
+	FrostTemp float64
+}
+
+// DetectAlerts ищет опасные явления в прогнозе до момента until. Прогноз должен быть
+// в метрических единицах. Для каждого вида возвращается только первое по времени явление.
+func DetectAlerts(forecast *WeatherForecast, thresholds AlertThresholds, until time.Time) []Alert {
+	var alerts []Alert
+	seen := make(map[string]bool)
+	add := func(kind string, item ForecastItem, value float64) {
+		if !seen[kind] {
+			seen[kind] = true
+			alerts = append(alerts, Alert{Kind: kind, Time: item.Time, Value: value})
+		}
+	}
+
+	for _, item := range forecast.Items {
+		if item.Time.After(until) {
+			break
+		}
+
+		if item.Main == "Thunderstorm" {
+			add(AlertStorm, item, item.Precipitation)
+		}
+		if item.Main == "Snow" && item.Precipitation >= heavySnowThreshold {
+			add(AlertHeavySnow, item, item.Precipitation)
+		} else if item.Main != "Snow" && item.Precipitation >= heavyRainThreshold {
+			add(AlertHeavyRain, item, item.Precipitation)
+		}
+		if item.Temp <= thresholds.FrostTemp {
+			add(AlertFrost, item, item.Temp)
+		}
+		if item.WindSpeed >= strongWindThreshold {
+			add(AlertWind, item, item.WindSpeed)
+		}
+	}
+	return alerts
+}
+
+// FormatAlerts форматирует предупреждения для места в одно сообщение в единицах prefs
+func FormatAlerts(place string, alerts []Alert, prefs Preferences) string {
+	prefs = prefs.normalized()
+	l := prefs.labels()
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("⚠️ %s — %s\n", l.alertTitle, place))
+
+	for _, alert := range alerts {
+		at := alert.Time.Format("15:04")
+		if !sameDay(alert.Time, time.Now().In(alert.Time.Location())) {
+			at = l.weekdays[alert.Time.Weekday()] + " " + at
+		}
+
+		text.WriteString("\n")
+		switch alert.Kind {
+		case AlertStorm:
+			text.WriteString("⛈️ " + fmt.Sprintf(l.storm, at))
+		case AlertHeavyRain:
+			text.WriteString("🌧️ " + fmt.Sprintf(l.heavyRain, at, alert.Value, l.millimeters))
+		case AlertHeavySnow:
+			text.WriteString("🌨️ " + fmt.Sprintf(l.heavySnow, at, alert.Value, l.millimeters))
+		case AlertFrost:
+			text.WriteString("🥶 " + fmt.Sprintf(l.frost, formatTemp(prefs.fromCelsius(alert.Value)), prefs.tempUnit(), at))
+		case AlertWind:
+			text.WriteString("🌬️ " + fmt.Sprintf(l.strongWind, prefs.fromMetersPerSecond(alert.Value), prefs.windUnit(), at))
+		}
+	}
+	return text.String()
+}
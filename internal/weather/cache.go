@@ -0,0 +1,207 @@
+package weather
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// Сколько по умолчанию хранится ответ о погоде и прогнозе
+	defaultCacheTTL = 10 * time.Minute
+	// Результаты поиска города почти не меняются, поэтому хранятся дольше
+	geocodeCacheTTL = 24 * time.Hour
+)
+
+// CacheTTLFromEnv возвращает срок хранения ответов из WEATHER_CACHE_TTL
+// (например, "10m"). Значение "0" отключает кэш.
+func CacheTTLFromEnv() time.Duration {
+	value := os.Getenv("WEATHER_CACHE_TTL")
+	if value == "" {
+		return defaultCacheTTL
+	}
+
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl < 0 {
+		log.Printf("Invalid WEATHER_CACHE_TTL %q, using %s", value, defaultCacheTTL)
+		return defaultCacheTTL
+	}
+	return ttl
+}
+
+type cacheEntry struct {
+	value     interface{}
+	expiresAt time.Time
+}
+
+// errFetchPanicked получают запросы, ожидавшие запроса к провайдеру, который запаниковал
+var errFetchPanicked = errors.New("weather provider request panicked")
+
+// inflight — запрос к провайдеру, результата которого ждут одинаковые запросы
+type inflight struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// CachingProvider кэширует ответы провайдера на ttl и объединяет одновременные
+// одинаковые запросы в один. Ошибки не кэшируются.
+type CachingProvider struct {
+	provider Provider
+	ttl      time.Duration
+	// now подменяется в тестах
+	now func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]cacheEntry
+	calls     map[string]*inflight
+	lastSweep time.Time
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	coalesced atomic.Int64
+}
+
+func NewCachingProvider(provider Provider, ttl time.Duration) *CachingProvider {
+	return &CachingProvider{
+		provider:  provider,
+		ttl:       ttl,
+		now:       time.Now,
+		entries:   make(map[string]cacheEntry),
+		calls:     make(map[string]*inflight),
+		lastSweep: time.Now(),
+	}
+}
+
+func (c *CachingProvider) Name() string {
+	return c.provider.Name()
+}
+
+func (c *CachingProvider) Current(lat, lon float64, prefs Preferences) (*WeatherData, error) {
+	return cached(c, "current:"+prefs.cacheKey()+":"+coordsKey(lat, lon), c.ttl, func() (*WeatherData, error) {
+		return c.provider.Current(lat, lon, prefs)
+	}, func(data *WeatherData) *WeatherData {
+		clone := *data
+		return &clone
+	})
+}
+
+func (c *CachingProvider) Forecast(lat, lon float64, prefs Preferences) (*WeatherForecast, error) {
+	return cached(c, "forecast:"+prefs.cacheKey()+":"+coordsKey(lat, lon), c.ttl, func() (*WeatherForecast, error) {
+		return c.provider.Forecast(lat, lon, prefs)
+	}, func(forecast *WeatherForecast) *WeatherForecast {
+		clone := *forecast
+		clone.Items = append([]ForecastItem(nil), forecast.Items...)
+		return &clone
+	})
+}
+
+func (c *CachingProvider) Geocode(query string) ([]Place, error) {
+	return cached(c, "geocode:"+cityKey(query), geocodeCacheTTL, func() ([]Place, error) {
+		return c.provider.Geocode(query)
+	}, func(places []Place) []Place {
+		return append([]Place(nil), places...)
+	})
+}
+
+func (c *CachingProvider) ReverseGeocode(lat, lon float64) (Place, error) {
+	return cached(c, "reverse:"+coordsKey(lat, lon), geocodeCacheTTL, func() (Place, error) {
+		return c.provider.ReverseGeocode(lat, lon)
+	}, func(place Place) Place {
+		return place
+	})
+}
+
+// GetStats возвращает счетчики кэша для мониторинга
+func (c *CachingProvider) GetStats() map[string]interface{} {
+	c.mu.Lock()
+	size := len(c.entries)
+	c.mu.Unlock()
+
+	return map[string]interface{}{
+		"hits":      c.hits.Load(),
+		"misses":    c.misses.Load(),
+		"coalesced": c.coalesced.Load(),
+		"size":      size,
+	}
+}
+
+// cached возвращает копию значения из кэша или выполняет fetch. Пока fetch выполняется,
+// запросы с тем же ключом ждут его результата вместо повторного обращения к провайдеру.
+// Копия нужна, чтобы вызывающий мог менять результат, не портя кэш.
+func cached[T any](c *CachingProvider, key string, ttl time.Duration, fetch func() (T, error), clone func(T) T) (T, error) {
+	now := c.now()
+
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok && now.Before(entry.expiresAt) {
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return clone(entry.value.(T)), nil
+	}
+
+	if call, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		c.coalesced.Add(1)
+		<-call.done
+		if call.err != nil {
+			var zero T
+			return zero, call.err
+		}
+		return clone(call.value.(T)), nil
+	}
+
+	// Ошибка заменяется результатом fetch; если fetch запаникует, ожидающие
+	// получат ее, а ключ освободится для следующих запросов
+	call := &inflight{done: make(chan struct{}), err: errFetchPanicked}
+	c.calls[key] = call
+	c.mu.Unlock()
+	c.misses.Add(1)
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, key)
+		if call.err == nil {
+			c.entries[key] = cacheEntry{value: call.value, expiresAt: c.now().Add(ttl)}
+			c.sweepLocked(now)
+		}
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	value, err := fetch()
+	call.value, call.err = value, err
+	if err != nil {
+		return value, err
+	}
+	return clone(value), nil
+}
+
+// sweepLocked удаляет устаревшие записи не чаще раза в ttl. Вызывается под c.mu.
+func (c *CachingProvider) sweepLocked(now time.Time) {
+	if now.Sub(c.lastSweep) < c.ttl {
+		return
+	}
+	c.lastSweep = now
+
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// coordsKey округляет координаты до сотых (около километра), чтобы соседние точки
+// использовали один ответ
+func coordsKey(lat, lon float64) string {
+	return fmt.Sprintf("%.2f,%.2f", lat, lon)
+}
+
+// cityKey приводит название города к нижнему регистру и убирает лишние пробелы
+func cityKey(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
@@ -0,0 +1,175 @@
+package weather
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingProvider считает запросы текущей погоды; поведение задается в current
+type countingProvider struct {
+	calls   atomic.Int64
+	current func(call int64) (*WeatherData, error)
+}
+
+func (p *countingProvider) Name() string { return "counting" }
+
+func (p *countingProvider) Current(lat, lon float64, prefs Preferences) (*WeatherData, error) {
+	return p.current(p.calls.Add(1))
+}
+
+func (p *countingProvider) Forecast(lat, lon float64, prefs Preferences) (*WeatherForecast, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p *countingProvider) Geocode(query string) ([]Place, error) {
+	return nil, ErrPlaceNotFound
+}
+
+func (p *countingProvider) ReverseGeocode(lat, lon float64) (Place, error) {
+	return Place{}, ErrPlaceNotFound
+}
+
+func TestCachingProviderTTL(t *testing.T) {
+	provider := &countingProvider{current: func(call int64) (*WeatherData, error) {
+		return &WeatherData{Temp: float64(call)}, nil
+	}}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := NewCachingProvider(provider, 10*time.Minute)
+	cache.now = func() time.Time { return now }
+
+	first, err := cache.Current(55.75, 37.62, DefaultPreferences())
+	if err != nil {
+		t.Fatalf("Current: %v", err)
+	}
+	first.Temp = 100 // изменение копии не должно портить кэш
+
+	now = now.Add(9 * time.Minute)
+	if data, _ := cache.Current(55.751, 37.619, DefaultPreferences()); data.Temp != 1 {
+		t.Errorf("expected cached value from the same area, got %v", data.Temp)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if data, _ := cache.Current(55.75, 37.62, DefaultPreferences()); data.Temp != 2 {
+		t.Errorf("expected fresh value after TTL, got %v", data.Temp)
+	}
+	if calls := provider.calls.Load(); calls != 2 {
+		t.Errorf("expected 2 provider calls, got %d", calls)
+	}
+}
+
+func TestCachingProviderCoalescesConcurrentCalls(t *testing.T) {
+	release := make(chan struct{})
+	provider := &countingProvider{current: func(int64) (*WeatherData, error) {
+		<-release
+		return &WeatherData{Temp: 5}, nil
+	}}
+	cache := NewCachingProvider(provider, time.Minute)
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make([]*WeatherData, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = cache.Current(1, 2, DefaultPreferences())
+		}(i)
+	}
+
+	// Ждем, пока все, кроме первого, присоединятся к его запросу
+	deadline := time.Now().Add(5 * time.Second)
+	for cache.coalesced.Load() < callers-1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if calls := provider.calls.Load(); calls != 1 {
+		t.Errorf("expected one provider call, got %d", calls)
+	}
+	for i, data := range results {
+		if data == nil || data.Temp != 5 {
+			t.Fatalf("caller %d got %+v", i, data)
+		}
+		if i > 0 && data == results[0] {
+			t.Error("callers must get separate copies")
+		}
+	}
+}
+
+func TestCachingProviderDoesNotCacheErrors(t *testing.T) {
+	provider := &countingProvider{current: func(call int64) (*WeatherData, error) {
+		if call == 1 {
+			return nil, errors.New("provider is down")
+		}
+		return &WeatherData{Temp: 7}, nil
+	}}
+	cache := NewCachingProvider(provider, time.Minute)
+
+	if _, err := cache.Current(1, 2, DefaultPreferences()); err == nil {
+		t.Fatal("expected provider error")
+	}
+	if data, err := cache.Current(1, 2, DefaultPreferences()); err != nil || data.Temp != 7 {
+		t.Fatalf("expected retry after error, got %+v, %v", data, err)
+	}
+	if calls := provider.calls.Load(); calls != 2 {
+		t.Errorf("expected 2 provider calls, got %d", calls)
+	}
+}
+
+func TestCachingProviderRecoversAfterPanic(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	provider := &countingProvider{current: func(call int64) (*WeatherData, error) {
+		if call == 1 {
+			close(started)
+			<-release
+			panic("boom")
+		}
+		return &WeatherData{Temp: 3}, nil
+	}}
+	cache := NewCachingProvider(provider, time.Minute)
+
+	go func() {
+		defer func() { recover() }()
+		cache.Current(1, 2, DefaultPreferences())
+	}()
+	<-started
+
+	waiter := make(chan error, 1)
+	go func() {
+		_, err := cache.Current(1, 2, DefaultPreferences())
+		waiter <- err
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for cache.coalesced.Load() < 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+
+	select {
+	case err := <-waiter:
+		if !errors.Is(err, errFetchPanicked) {
+			t.Errorf("waiter: expected errFetchPanicked, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiter blocked after provider panic")
+	}
+
+	done := make(chan *WeatherData, 1)
+	go func() {
+		data, _ := cache.Current(1, 2, DefaultPreferences())
+		done <- data
+	}()
+	select {
+	case data := <-done:
+		if data == nil || data.Temp != 3 {
+			t.Errorf("expected fresh value after panic, got %+v", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("request blocked after provider panic")
+	}
+}
@@ -0,0 +1,180 @@
+package weather
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Сколько шагов прогноза (по 3 часа) показывать, если сегодняшний день уже закончился
+const nextHoursSteps = 8
+
+// ForecastItem — прогноз на один трехчасовой интервал
+type ForecastItem struct {
+	Time        time.Time
+	Temp        float64
+	FeelsLike   float64
+	Humidity    int
+	WindSpeed   float64
+	Main        string
+	Description string
+	// Pop — вероятность осадков от 0 до 1
+	Pop float64
+	// Precipitation — дождь и снег за интервал, мм
+	Precipitation float64
+}
+
+// DailyForecast — сводка прогноза за день
+type DailyForecast struct {
+	Date          time.Time
+	TempMin       float64
+	TempMax       float64
+	Precipitation float64
+	Pop           float64
+	Main          string
+	Description   string
+}
+
+// WeatherForecast — прогноз на 5 дней с шагом 3 часа
+type WeatherForecast struct {
+	City     string
+	Location *time.Location
+	Items    []ForecastItem
+}
+
+// Hourly возвращает интервалы прогноза, приходящиеся на тот же местный день, что и day
+func (f *WeatherForecast) Hourly(day time.Time) []ForecastItem {
+	day = day.In(f.Location)
+
+	var items []ForecastItem
+	for _, item := range f.Items {
+		if sameDay(item.Time, day) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Daily группирует прогноз по местным дням
+func (f *WeatherForecast) Daily() []DailyForecast {
+	var days []DailyForecast
+	for _, item := range f.Items {
+		if len(days) == 0 || !sameDay(days[len(days)-1].Date, item.Time) {
+			days = append(days, DailyForecast{
+				Date:    item.Time,
+				TempMin: math.Inf(1),
+				TempMax: math.Inf(-1),
+			})
+		}
+
+		day := &days[len(days)-1]
+		day.TempMin = math.Min(day.TempMin, item.Temp)
+		day.TempMax = math.Max(day.TempMax, item.Temp)
+		day.Precipitation += item.Precipitation
+		day.Pop = math.Max(day.Pop, item.Pop)
+
+		// Погоду дня описываем по интервалу ближе всего к полудню
+		if day.Main == "" || math.Abs(float64(item.Time.Hour()-12)) < math.Abs(float64(day.Date.Hour()-12)) {
+			day.Date = item.Time
+			day.Main = item.Main
+			day.Description = item.Description
+		}
+	}
+	return days
+}
+
+// FormatDayForecast форматирует почасовой прогноз на день, отстоящий от сегодня на offset дней
+func (ws *WeatherService) FormatDayForecast(forecast *WeatherForecast, offset int, prefs Preferences) string {
+	prefs = prefs.normalized()
+	l := prefs.labels()
+	now := time.Now().In(forecast.Location)
+	day := now.AddDate(0, 0, offset)
+	items := forecast.Hourly(day)
+
+	title := l.today
+	if offset == 1 {
+		title = l.tomorrow
+	}
+
+	// Поздно вечером от сегодняшнего дня уже ничего не осталось — показываем ближайшие часы
+	if len(items) == 0 && offset == 0 {
+		items = forecast.Items
+		if len(items) > nextHoursSteps {
+			items = items[:nextHoursSteps]
+		}
+		title = l.nextHours
+	}
+	if len(items) == 0 {
+		return fmt.Sprintf("📅 %s: %s", title, fmt.Sprintf(l.noForecast, forecast.City))
+	}
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("📅 %s, %s — %s\n\n", title, day.Format("02.01"), forecast.City))
+	for _, item := range items {
+		text.WriteString(fmt.Sprintf("%s %s %s%s, %s, 💨 %.0f %s",
+			item.Time.Format("15:04"), conditionEmoji(item.Main), formatTemp(item.Temp), prefs.tempUnit(),
+			item.Description, item.WindSpeed, prefs.windUnit()))
+		if item.Pop > 0 {
+			text.WriteString(fmt.Sprintf(", 💧 %.0f%%", item.Pop*100))
+		}
+		text.WriteString("\n")
+	}
+
+	summary := summarize(items)
+	text.WriteString("\n🌡️ " + fmt.Sprintf(l.tempRange, formatTemp(summary.TempMin), formatTemp(summary.TempMax), prefs.tempUnit()))
+	text.WriteString(prefs.formatPrecipitation(summary))
+	return text.String()
+}
+
+// FormatDailyForecast форматирует прогноз на 5 дней: минимум, максимум и осадки по дням
+func (ws *WeatherService) FormatDailyForecast(forecast *WeatherForecast, prefs Preferences) string {
+	prefs = prefs.normalized()
+	l := prefs.labels()
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("📆 %s — %s\n", l.dailyForecast, forecast.City))
+
+	for _, day := range forecast.Daily() {
+		text.WriteString(fmt.Sprintf("\n%s %s %s %s…%s%s, %s",
+			l.weekdays[day.Date.Weekday()], day.Date.Format("02.01"), conditionEmoji(day.Main),
+			formatTemp(day.TempMin), formatTemp(day.TempMax), prefs.tempUnit(), day.Description))
+		text.WriteString(prefs.formatPrecipitation(day))
+	}
+	return text.String()
+}
+
+// summarize сводит интервалы прогноза в сводку за день
+func summarize(items []ForecastItem) DailyForecast {
+	forecast := WeatherForecast{Location: items[0].Time.Location(), Items: items}
+	days := forecast.Daily()
+
+	summary := days[0]
+	for _, day := range days[1:] {
+		summary.TempMin = math.Min(summary.TempMin, day.TempMin)
+		summary.TempMax = math.Max(summary.TempMax, day.TempMax)
+		summary.Precipitation += day.Precipitation
+		summary.Pop = math.Max(summary.Pop, day.Pop)
+	}
+	return summary
+}
+
+// formatTemp округляет температуру и добавляет знак ("+5", "-3", "0")
+func formatTemp(temp float64) string {
+	rounded := math.Round(temp)
+	if rounded == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%+.0f", rounded)
+}
+
+func (p Preferences) formatPrecipitation(day DailyForecast) string {
+	l := p.labels()
+	if day.Precipitation < 0.1 && day.Pop < 0.2 {
+		return ", " + l.noPrecipitation
+	}
+	return fmt.Sprintf(", 💧 %.1f %s (%.0f%%)", day.Precipitation, l.millimeters, day.Pop*100)
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
@@ -0,0 +1,105 @@
+package weather
+
+// labels — подписи сообщений о погоде на одном языке
+type labels struct {
+	weatherIn   string
+	temperature string
+	feelsLike   string
+	pressure    string
+	humidity    string
+	wind        string
+	visibility  string
+	noData      string
+
+	kilometers      string
+	metersPerSecond string
+	mmHg            string
+	millimeters     string
+
+	today           string
+	tomorrow        string
+	nextHours       string
+	noForecast      string
+	tempRange       string
+	dailyForecast   string
+	noPrecipitation string
+	weekdays        [7]string
+
+	alertTitle string
+	storm      string
+	heavyRain  string
+	heavySnow  string
+	frost      string
+	strongWind string
+}
+
+// labelsByLang содержит подписи для каждого языка из Preferences.Lang
+var labelsByLang = map[string]labels{
+	LangRussian: {
+		weatherIn:   "Погода в %s",
+		temperature: "Температура",
+		feelsLike:   "Ощущается как",
+		pressure:    "Давление",
+		humidity:    "Влажность",
+		wind:        "Ветер",
+		visibility:  "Видимость",
+		noData:      "Нет данных",
+
+		kilometers:      "км",
+		metersPerSecond: "м/с",
+		mmHg:            "мм рт. ст.",
+		millimeters:     "мм",
+
+		today:           "Сегодня",
+		tomorrow:        "Завтра",
+		nextHours:       "Ближайшие часы",
+		noForecast:      "нет данных прогноза для %s",
+		tempRange:       "От %s до %s%s",
+		dailyForecast:   "Прогноз на 5 дней",
+		noPrecipitation: "без осадков",
+		weekdays:        [7]string{"Вс", "Пн", "Вт", "Ср", "Чт", "Пт", "Сб"},
+
+		alertTitle: "Погодное предупреждение",
+		storm:      "Гроза с %s",
+		heavyRain:  "Сильный дождь с %s: %.1f %s за 3 часа",
+		heavySnow:  "Сильный снег с %s: %.1f %s за 3 часа",
+		frost:      "Мороз %s%s с %s",
+		strongWind: "Сильный ветер %.0f %s с %s",
+	},
+	LangEnglish: {
+		weatherIn:   "Weather in %s",
+		temperature: "Temperature",
+		feelsLike:   "Feels like",
+		pressure:    "Pressure",
+		humidity:    "Humidity",
+		wind:        "Wind",
+		visibility:  "Visibility",
+		noData:      "No data",
+
+		kilometers:      "km",
+		metersPerSecond: "m/s",
+		mmHg:            "mmHg",
+		millimeters:     "mm",
+
+		today:           "Today",
+		tomorrow:        "Tomorrow",
+		nextHours:       "Next hours",
+		noForecast:      "no forecast data for %s",
+		tempRange:       "From %s to %s%s",
+		dailyForecast:   "5-day forecast",
+		noPrecipitation: "no precipitation",
+		weekdays:        [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+
+		alertTitle: "Weather alert",
+		storm:      "Thunderstorm from %s",
+		heavyRain:  "Heavy rain from %s: %.1f %s in 3 hours",
+		heavySnow:  "Heavy snow from %s: %.1f %s in 3 hours",
+		frost:      "Frost %s%s from %s",
+		strongWind: "Strong wind %.0f %s from %s",
+	},
+}
+
+// labels возвращает подписи на языке пользователя
+func (p Preferences) labels() labels {
+	return labelsByLang[p.normalized().Lang]
+}
@@ -0,0 +1,68 @@
+package weather
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode"
+)
+
+func hasCyrillic(text string) bool {
+	return strings.IndexFunc(text, func(r rune) bool { return unicode.Is(unicode.Cyrillic, r) }) >= 0
+}
+
+// Все форматтеры подписывают данные на языке пользователя
+func TestFormattersFollowLanguage(t *testing.T) {
+	ws := NewWeatherServiceWithProvider(nil)
+	current := &WeatherData{Name: "Berlin", Temp: 1, FeelsLike: -2, Pressure: 1013, Humidity: 80, WindSpeed: 3, Visibility: 10000}
+
+	location := time.UTC
+	now := time.Now().In(location)
+	var items []ForecastItem
+	for i := 0; i < 16; i++ {
+		items = append(items, ForecastItem{Time: now.Add(time.Duration(i) * 3 * time.Hour), Temp: -12, WindSpeed: 3, Pop: 0.5, Precipitation: 1})
+	}
+	forecast := &WeatherForecast{City: "Berlin", Location: location, Items: items}
+	alerts := []Alert{
+		{Kind: AlertStorm, Time: now},
+		{Kind: AlertHeavyRain, Time: now, Value: 20},
+		{Kind: AlertFrost, Time: now.AddDate(0, 0, 2), Value: -12},
+		{Kind: AlertWind, Time: now, Value: 20},
+	}
+
+	format := func(prefs Preferences) map[string]string {
+		return map[string]string{
+			"current":  ws.FormatWeatherMessage(current, prefs),
+			"summary":  ws.FormatWeatherSummary("Дом", current, prefs),
+			"today":    ws.FormatDayForecast(forecast, 0, prefs),
+			"tomorrow": ws.FormatDayForecast(forecast, 1, prefs),
+			"daily":    ws.FormatDailyForecast(forecast, prefs),
+			"alerts":   FormatAlerts("Berlin", alerts, prefs),
+		}
+	}
+
+	english := Preferences{Units: UnitsMetric, Pressure: PressureMmHg, Lang: LangEnglish}
+	for name, text := range format(english) {
+		if name == "summary" {
+			text = strings.Replace(text, "Дом", "", 1)
+		}
+		if hasCyrillic(text) {
+			t.Errorf("%s in English contains Russian labels:\n%s", name, text)
+		}
+	}
+	for _, want := range []string{"Weather in Berlin", "Feels like: -2.0°C", "760 mmHg", "10 km", "3.0 m/s", "No data"} {
+		if text := format(english)["current"]; !strings.Contains(text, want) {
+			t.Errorf("English weather message does not contain %q:\n%s", want, text)
+		}
+	}
+
+	russian := Preferences{Units: UnitsMetric, Pressure: PressureMmHg, Lang: LangRussian}
+	for _, want := range []string{"Погода в Berlin", "Ощущается как: -2.0°C", "760 мм рт. ст.", "10 км", "3.0 м/с", "Нет данных"} {
+		if text := format(russian)["current"]; !strings.Contains(text, want) {
+			t.Errorf("Russian weather message does not contain %q:\n%s", want, text)
+		}
+	}
+	if text := format(russian)["alerts"]; !strings.Contains(text, "Погодное предупреждение") || !strings.Contains(text, "Сильный дождь") {
+		t.Errorf("Russian alerts are not labelled in Russian:\n%s", text)
+	}
+}
@@ -0,0 +1,302 @@
+package weather
+
+import (
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Сколько дней прогноза запрашивать у Open-Meteo (как у OpenWeatherMap)
+const openMeteoForecastDays = 5
+
+// OpenMeteo — провайдер https://open-meteo.com, работает без API-ключа
+type OpenMeteo struct{}
+
+func NewOpenMeteo() *OpenMeteo {
+	return &OpenMeteo{}
+}
+
+// openMeteoCurrentResponse — JSON-ответ /v1/forecast с параметром current
+type openMeteoCurrentResponse struct {
+	Latitude         float64 `json:"latitude"`
+	Longitude        float64 `json:"longitude"`
+	Timezone         string  `json:"timezone"`
+	UtcOffsetSeconds int     `json:"utc_offset_seconds"`
+	Current          struct {
+		Temperature         float64 `json:"temperature_2m"`
+		ApparentTemperature float64 `json:"apparent_temperature"`
+		RelativeHumidity    float64 `json:"relative_humidity_2m"`
+		PressureMsl         float64 `json:"pressure_msl"`
+		WindSpeed           float64 `json:"wind_speed_10m"`
+		WeatherCode         int     `json:"weather_code"`
+		Visibility          float64 `json:"visibility"`
+	} `json:"current"`
+}
+
+// openMeteoForecastResponse — JSON-ответ /v1/forecast с параметром hourly
+type openMeteoForecastResponse struct {
+	Timezone         string `json:"timezone"`
+	UtcOffsetSeconds int    `json:"utc_offset_seconds"`
+	Hourly           struct {
+		Time                     []int64   `json:"time"`
+		Temperature              []float64 `json:"temperature_2m"`
+		ApparentTemperature      []float64 `json:"apparent_temperature"`
+		RelativeHumidity         []float64 `json:"relative_humidity_2m"`
+		PrecipitationProbability []float64 `json:"precipitation_probability"`
+		Precipitation            []float64 `json:"precipitation"`
+		WeatherCode              []int     `json:"weather_code"`
+		WindSpeed                []float64 `json:"wind_speed_10m"`
+	} `json:"hourly"`
+}
+
+// openMeteoGeocodeResponse — JSON-ответ geocoding-api.open-meteo.com/v1/search
+type openMeteoGeocodeResponse struct {
+	Results []struct {
+		Name        string  `json:"name"`
+		Latitude    float64 `json:"latitude"`
+		Longitude   float64 `json:"longitude"`
+		CountryCode string  `json:"country_code"`
+		Admin1      string  `json:"admin1"`
+		Timezone    string  `json:"timezone"`
+	} `json:"results"`
+}
+
+// nominatimReverseResponse — JSON-ответ nominatim.openstreetmap.org/reverse.
+// У Open-Meteo нет обратного геокодирования, поэтому используется OpenStreetMap.
+type nominatimReverseResponse struct {
+	Lat     string `json:"lat"`
+	Lon     string `json:"lon"`
+	Address struct {
+		City        string `json:"city"`
+		Town        string `json:"town"`
+		Village     string `json:"village"`
+		State       string `json:"state"`
+		CountryCode string `json:"country_code"`
+	} `json:"address"`
+}
+
+func (p *OpenMeteo) Name() string {
+	return ProviderOpenMeteo
+}
+
+func (p *OpenMeteo) Current(lat, lon float64, prefs Preferences) (*WeatherData, error) {
+	prefs = prefs.normalized()
+	query := coordsQuery("latitude", "longitude", lat, lon)
+	query.Set("current", "temperature_2m,apparent_temperature,relative_humidity_2m,pressure_msl,wind_speed_10m,weather_code,visibility")
+
+	var response openMeteoCurrentResponse
+	if err := getJSON(p.endpoint(query, prefs), &response); err != nil {
+		return nil, err
+	}
+
+	current := response.Current
+	condition, description := wmoCondition(current.WeatherCode, prefs.Lang)
+	return &WeatherData{
+		Lat:         response.Latitude,
+		Lon:         response.Longitude,
+		Temp:        current.Temperature,
+		FeelsLike:   current.ApparentTemperature,
+		Pressure:    int(math.Round(current.PressureMsl)),
+		Humidity:    int(math.Round(current.RelativeHumidity)),
+		WindSpeed:   current.WindSpeed,
+		Visibility:  int(current.Visibility),
+		Condition:   condition,
+		Description: description,
+		Timezone:    response.UtcOffsetSeconds,
+		Zone:        response.Timezone,
+	}, nil
+}
+
+// Forecast сводит почасовой прогноз Open-Meteo в интервалы по 3 часа, как у OpenWeatherMap
+func (p *OpenMeteo) Forecast(lat, lon float64, prefs Preferences) (*WeatherForecast, error) {
+	prefs = prefs.normalized()
+	query := coordsQuery("latitude", "longitude", lat, lon)
+	query.Set("hourly", "temperature_2m,apparent_temperature,relative_humidity_2m,precipitation_probability,precipitation,weather_code,wind_speed_10m")
+	query.Set("forecast_days", strconv.Itoa(openMeteoForecastDays))
+
+	var response openMeteoForecastResponse
+	if err := getJSON(p.endpoint(query, prefs), &response); err != nil {
+		return nil, err
+	}
+
+	location, err := time.LoadLocation(response.Timezone)
+	if err != nil {
+		location = time.FixedZone("", response.UtcOffsetSeconds)
+	}
+
+	hourly := response.Hourly
+	forecast := &WeatherForecast{Location: location}
+	now := time.Now()
+	for i := 0; i < len(hourly.Time); i++ {
+		at := time.Unix(hourly.Time[i], 0).In(location)
+		// Интервал начинается в час, кратный трем, и не должен полностью остаться в прошлом
+		if at.Hour()%3 != 0 || !at.Add(3*time.Hour).After(now) {
+			continue
+		}
+
+		condition, description := wmoCondition(valueAt(hourly.WeatherCode, i), prefs.Lang)
+		item := ForecastItem{
+			Time:        at,
+			Temp:        valueAt(hourly.Temperature, i),
+			FeelsLike:   valueAt(hourly.ApparentTemperature, i),
+			Humidity:    int(math.Round(valueAt(hourly.RelativeHumidity, i))),
+			WindSpeed:   valueAt(hourly.WindSpeed, i),
+			Main:        condition,
+			Description: description,
+		}
+		for j := i; j < i+3 && j < len(hourly.Time); j++ {
+			item.Precipitation += valueAt(hourly.Precipitation, j)
+			item.Pop = math.Max(item.Pop, valueAt(hourly.PrecipitationProbability, j)/100)
+		}
+		forecast.Items = append(forecast.Items, item)
+	}
+
+	return forecast, nil
+}
+
+// Zone возвращает IANA-имя часового пояса по координатам. Подходит для мест от
+// геокодеров, которые сообщают только смещение от UTC или ничего.
+func (p *OpenMeteo) Zone(lat, lon float64) (string, error) {
+	query := coordsQuery("latitude", "longitude", lat, lon)
+	query.Set("forecast_days", "1")
+
+	var response openMeteoCurrentResponse
+	if err := getJSON(p.endpoint(query, DefaultPreferences()), &response); err != nil {
+		return "", err
+	}
+	return response.Timezone, nil
+}
+
+func (p *OpenMeteo) Geocode(query string) ([]Place, error) {
+	params := url.Values{
+		"name":     {query},
+		"count":    {strconv.Itoa(geocodeLimit)},
+		"language": {"ru"},
+		"format":   {"json"},
+	}
+
+	var response openMeteoGeocodeResponse
+	if err := getJSON("https://geocoding-api.open-meteo.com/v1/search?"+params.Encode(), &response); err != nil {
+		return nil, err
+	}
+
+	places := make([]Place, len(response.Results))
+	for i, result := range response.Results {
+		places[i] = Place{
+			Name:    result.Name,
+			Region:  result.Admin1,
+			Country: result.CountryCode,
+			Lat:     result.Latitude,
+			Lon:     result.Longitude,
+			Zone:    result.Timezone,
+		}
+	}
+
+	return places, nil
+}
+
+func (p *OpenMeteo) ReverseGeocode(lat, lon float64) (Place, error) {
+	params := coordsQuery("lat", "lon", lat, lon)
+	params.Set("format", "jsonv2")
+	params.Set("zoom", "10") // уровень города
+	params.Set("accept-language", "ru")
+
+	var response nominatimReverseResponse
+	if err := getJSON("https://nominatim.openstreetmap.org/reverse?"+params.Encode(), &response); err != nil {
+		return Place{}, err
+	}
+
+	address := response.Address
+	name := address.City
+	if name == "" {
+		name = address.Town
+	}
+	if name == "" {
+		name = address.Village
+	}
+	if name == "" {
+		return Place{}, ErrPlaceNotFound
+	}
+
+	return Place{
+		Name:    name,
+		Region:  address.State,
+		Country: strings.ToUpper(address.CountryCode),
+		Lat:     lat,
+		Lon:     lon,
+	}, nil
+}
+
+// endpoint формирует URL метода /v1/forecast. Осадки и видимость всегда
+// запрашиваются в миллиметрах и метрах, как у OpenWeatherMap.
+func (p *OpenMeteo) endpoint(query url.Values, prefs Preferences) string {
+	query.Set("timezone", "auto")
+	query.Set("timeformat", "unixtime")
+	if prefs.imperial() {
+		query.Set("temperature_unit", "fahrenheit")
+		query.Set("wind_speed_unit", "mph")
+	} else {
+		query.Set("wind_speed_unit", "ms")
+	}
+	return "https://api.open-meteo.com/v1/forecast?" + query.Encode()
+}
+
+// valueAt возвращает элемент ряда или ноль, если ряд короче (Open-Meteo может не прислать переменную)
+func valueAt[T int | float64](values []T, i int) T {
+	if i < len(values) {
+		return values[i]
+	}
+	return 0
+}
+
+// wmoCodes — группа условий OpenWeatherMap и описания кодов погоды WMO на русском и английском
+var wmoCodes = map[int]struct {
+	Main, Ru, En string
+}{
+	0:  {"Clear", "ясно", "clear sky"},
+	1:  {"Clear", "преимущественно ясно", "mainly clear"},
+	2:  {"Clouds", "переменная облачность", "partly cloudy"},
+	3:  {"Clouds", "пасмурно", "overcast"},
+	45: {"Fog", "туман", "fog"},
+	48: {"Fog", "туман", "fog"},
+	51: {"Drizzle", "морось", "drizzle"},
+	53: {"Drizzle", "морось", "drizzle"},
+	55: {"Drizzle", "морось", "drizzle"},
+	56: {"Drizzle", "ледяная морось", "freezing drizzle"},
+	57: {"Drizzle", "ледяная морось", "freezing drizzle"},
+	61: {"Rain", "небольшой дождь", "light rain"},
+	63: {"Rain", "дождь", "rain"},
+	65: {"Rain", "сильный дождь", "heavy rain"},
+	66: {"Rain", "ледяной дождь", "freezing rain"},
+	67: {"Rain", "ледяной дождь", "freezing rain"},
+	71: {"Snow", "небольшой снег", "light snow"},
+	73: {"Snow", "снег", "snow"},
+	75: {"Snow", "сильный снег", "heavy snow"},
+	77: {"Snow", "снежная крупа", "snow grains"},
+	80: {"Rain", "небольшой ливень", "light showers"},
+	81: {"Rain", "ливень", "showers"},
+	82: {"Rain", "сильный ливень", "heavy showers"},
+	85: {"Snow", "снегопад", "snow showers"},
+	86: {"Snow", "снегопад", "snow showers"},
+	95: {"Thunderstorm", "гроза", "thunderstorm"},
+	96: {"Thunderstorm", "гроза с градом", "thunderstorm with hail"},
+	99: {"Thunderstorm", "гроза с градом", "thunderstorm with hail"},
+}
+
+// wmoCondition переводит код погоды WMO в группу условий OpenWeatherMap и описание на языке lang
+func wmoCondition(code int, lang string) (string, string) {
+	info, ok := wmoCodes[code]
+	if !ok {
+		if lang == LangEnglish {
+			return "", "no data"
+		}
+		return "", "нет данных"
+	}
+
+	if lang == LangEnglish {
+		return info.Main, info.En
+	}
+	return info.Main, info.Ru
+}
@@ -0,0 +1,202 @@
+package weather
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Сколько вариантов запрашивать при поиске города
+const geocodeLimit = 5
+
+// OpenWeatherMap — провайдер https://openweathermap.org/api, требует API-ключ
+type OpenWeatherMap struct {
+	apiKey string
+}
+
+func NewOpenWeatherMap(apiKey string) *OpenWeatherMap {
+	return &OpenWeatherMap{apiKey: apiKey}
+}
+
+// owmCurrentResponse — JSON-ответ метода /data/2.5/weather
+type owmCurrentResponse struct {
+	Name  string `json:"name"`
+	Coord struct {
+		Lat float64 `json:"lat"`
+		Lon float64 `json:"lon"`
+	} `json:"coord"`
+	Main struct {
+		Temp      float64 `json:"temp"`
+		Humidity  int     `json:"humidity"`
+		FeelsLike float64 `json:"feels_like"`
+		Pressure  int     `json:"pressure"`
+	} `json:"main"`
+	Weather []struct {
+		Description string `json:"description"`
+		Main        string `json:"main"`
+	} `json:"weather"`
+	Wind struct {
+		Speed float64 `json:"speed"`
+	} `json:"wind"`
+	Visibility int `json:"visibility"`
+	Timezone   int `json:"timezone"`
+}
+
+// owmForecastResponse — JSON-ответ метода /data/2.5/forecast
+type owmForecastResponse struct {
+	List []struct {
+		Dt   int64 `json:"dt"`
+		Main struct {
+			Temp      float64 `json:"temp"`
+			FeelsLike float64 `json:"feels_like"`
+			Humidity  int     `json:"humidity"`
+		} `json:"main"`
+		Weather []struct {
+			Main        string `json:"main"`
+			Description string `json:"description"`
+		} `json:"weather"`
+		Wind struct {
+			Speed float64 `json:"speed"`
+		} `json:"wind"`
+		Pop  float64 `json:"pop"`
+		Rain struct {
+			ThreeHours float64 `json:"3h"`
+		} `json:"rain"`
+		Snow struct {
+			ThreeHours float64 `json:"3h"`
+		} `json:"snow"`
+	} `json:"list"`
+	City struct {
+		Name     string `json:"name"`
+		Timezone int    `json:"timezone"`
+	} `json:"city"`
+}
+
+// owmPlace — элемент ответа метода /geo/1.0/direct
+type owmPlace struct {
+	Name       string            `json:"name"`
+	LocalNames map[string]string `json:"local_names"`
+	Lat        float64           `json:"lat"`
+	Lon        float64           `json:"lon"`
+	Country    string            `json:"country"`
+	State      string            `json:"state"`
+}
+
+// place переводит ответ геокодера в Place, предпочитая русское название
+func (p owmPlace) place() Place {
+	name := p.LocalNames["ru"]
+	if name == "" {
+		name = p.Name
+	}
+	return Place{
+		Name:    name,
+		Region:  p.State,
+		Country: p.Country,
+		Lat:     p.Lat,
+		Lon:     p.Lon,
+	}
+}
+
+func (p *OpenWeatherMap) Name() string {
+	return ProviderOpenWeatherMap
+}
+
+func (p *OpenWeatherMap) Current(lat, lon float64, prefs Preferences) (*WeatherData, error) {
+	var response owmCurrentResponse
+	if err := getJSON(p.endpoint("data/2.5/weather", p.weatherQuery(lat, lon, prefs)), &response); err != nil {
+		return nil, err
+	}
+
+	data := &WeatherData{
+		Name:       response.Name,
+		Lat:        response.Coord.Lat,
+		Lon:        response.Coord.Lon,
+		Temp:       response.Main.Temp,
+		FeelsLike:  response.Main.FeelsLike,
+		Pressure:   response.Main.Pressure,
+		Humidity:   response.Main.Humidity,
+		WindSpeed:  response.Wind.Speed,
+		Visibility: response.Visibility,
+		Timezone:   response.Timezone,
+	}
+	if len(response.Weather) > 0 {
+		data.Condition = response.Weather[0].Main
+		data.Description = response.Weather[0].Description
+	}
+
+	return data, nil
+}
+
+func (p *OpenWeatherMap) Forecast(lat, lon float64, prefs Preferences) (*WeatherForecast, error) {
+	var response owmForecastResponse
+	if err := getJSON(p.endpoint("data/2.5/forecast", p.weatherQuery(lat, lon, prefs)), &response); err != nil {
+		return nil, err
+	}
+
+	forecast := &WeatherForecast{
+		City:     response.City.Name,
+		Location: time.FixedZone("", response.City.Timezone),
+	}
+	for _, entry := range response.List {
+		item := ForecastItem{
+			Time:          time.Unix(entry.Dt, 0).In(forecast.Location),
+			Temp:          entry.Main.Temp,
+			FeelsLike:     entry.Main.FeelsLike,
+			Humidity:      entry.Main.Humidity,
+			WindSpeed:     entry.Wind.Speed,
+			Pop:           entry.Pop,
+			Precipitation: entry.Rain.ThreeHours + entry.Snow.ThreeHours,
+		}
+		if len(entry.Weather) > 0 {
+			item.Main = entry.Weather[0].Main
+			item.Description = entry.Weather[0].Description
+		}
+		forecast.Items = append(forecast.Items, item)
+	}
+
+	return forecast, nil
+}
+
+func (p *OpenWeatherMap) Geocode(query string) ([]Place, error) {
+	var response []owmPlace
+	params := url.Values{"q": {query}, "limit": {strconv.Itoa(geocodeLimit)}}
+	if err := getJSON(p.endpoint("geo/1.0/direct", params), &response); err != nil {
+		return nil, err
+	}
+
+	places := make([]Place, len(response))
+	for i, place := range response {
+		places[i] = place.place()
+	}
+
+	return places, nil
+}
+
+func (p *OpenWeatherMap) ReverseGeocode(lat, lon float64) (Place, error) {
+	var response []owmPlace
+	params := coordsQuery("lat", "lon", lat, lon)
+	params.Set("limit", "1")
+	if err := getJSON(p.endpoint("geo/1.0/reverse", params), &response); err != nil {
+		return Place{}, err
+	}
+	if len(response) == 0 {
+		return Place{}, ErrPlaceNotFound
+	}
+
+	return response[0].place(), nil
+}
+
+// weatherQuery формирует параметры запроса погоды в единицах и на языке пользователя
+func (p *OpenWeatherMap) weatherQuery(lat, lon float64, prefs Preferences) url.Values {
+	prefs = prefs.normalized()
+	query := coordsQuery("lat", "lon", lat, lon)
+	query.Set("units", prefs.Units)
+	query.Set("lang", prefs.Lang)
+	return query
+}
+
+// endpoint формирует URL метода API OpenWeatherMap
+func (p *OpenWeatherMap) endpoint(method string, query url.Values) string {
+	query.Set("appid", p.apiKey)
+	return "https://api.openweathermap.org/" + method + "?" + query.Encode()
+}
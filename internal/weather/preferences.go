@@ -0,0 +1,111 @@
+package weather
+
+import (
+	"fmt"
+	"math"
+)
+
+// Системы единиц
+const (
+	UnitsMetric   = "metric"
+	UnitsImperial = "imperial"
+)
+
+// Единицы давления
+const (
+	PressureHPa  = "hpa"
+	PressureMmHg = "mmhg"
+)
+
+// Языки описаний погоды
+const (
+	LangRussian = "ru"
+	LangEnglish = "en"
+)
+
+// 1 гПа в миллиметрах ртутного столба
+const hpaToMmHg = 0.750062
+
+// Preferences — единицы измерения и язык описаний погоды пользователя.
+// Units и Lang передаются провайдеру, Pressure учитывается только при форматировании.
+type Preferences struct {
+	Units    string
+	Pressure string
+	Lang     string
+}
+
+// DefaultPreferences возвращает настройки по умолчанию: °C, м/с, гПа, русский язык
+func DefaultPreferences() Preferences {
+	return Preferences{Units: UnitsMetric, Pressure: PressureHPa, Lang: LangRussian}
+}
+
+// normalized заменяет пустые и неизвестные значения значениями по умолчанию
+func (p Preferences) normalized() Preferences {
+	defaults := DefaultPreferences()
+	if p.Units != UnitsImperial {
+		p.Units = defaults.Units
+	}
+	if p.Pressure != PressureMmHg {
+		p.Pressure = defaults.Pressure
+	}
+	if p.Lang != LangEnglish {
+		p.Lang = defaults.Lang
+	}
+	return p
+}
+
+func (p Preferences) imperial() bool {
+	return p.Units == UnitsImperial
+}
+
+// cacheKey различает ответы провайдера, запрошенные с разными единицами и языком
+func (p Preferences) cacheKey() string {
+	p = p.normalized()
+	return p.Units + "/" + p.Lang
+}
+
+func (p Preferences) tempUnit() string {
+	if p.imperial() {
+		return "°F"
+	}
+	return "°C"
+}
+
+func (p Preferences) windUnit() string {
+	if p.imperial() {
+		return "mph"
+	}
+	return p.labels().metersPerSecond
+}
+
+// formatPressure форматирует давление, полученное от провайдера в гПа
+func (p Preferences) formatPressure(hpa int) string {
+	if p.Pressure == PressureMmHg {
+		return fmt.Sprintf("%.0f %s", math.Round(float64(hpa)*hpaToMmHg), p.labels().mmHg)
+	}
+	return fmt.Sprintf("%d hPa", hpa)
+}
+
+// formatVisibility форматирует видимость, полученную от провайдера в метрах
+func (p Preferences) formatVisibility(meters int) string {
+	if p.imperial() {
+		return fmt.Sprintf("%.1f mi", float64(meters)/1609.344)
+	}
+	return fmt.Sprintf("%d %s", meters/1000, p.labels().kilometers)
+}
+
+// fromCelsius переводит температуру из °C в единицы пользователя
+func (p Preferences) fromCelsius(temp float64) float64 {
+	if p.imperial() {
+		return temp*9/5 + 32
+	}
+	return temp
+}
+
+// fromMetersPerSecond переводит скорость ветра из м/с в единицы пользователя
+func (p Preferences) fromMetersPerSecond(speed float64) float64 {
+	if p.imperial() {
+		return speed * 2.236936
+	}
+	return speed
+}
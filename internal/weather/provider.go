@@ -0,0 +1,153 @@
+package weather
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// Имена провайдеров для WEATHER_PROVIDER
+const (
+	ProviderOpenWeatherMap = "openweathermap"
+	ProviderOpenMeteo      = "openmeteo"
+)
+
+// Place — найденный населенный пункт
+type Place struct {
+	Name    string
+	Region  string
+	Country string
+	Lat     float64
+	Lon     float64
+	// Zone — IANA-имя часового пояса, если провайдер его сообщает
+	Zone string
+}
+
+// Label возвращает название с регионом и страной, например "Москва, RU"
+func (p Place) Label() string {
+	parts := []string{p.Name}
+	if p.Region != "" && p.Region != p.Name {
+		parts = append(parts, p.Region)
+	}
+	if p.Country != "" {
+		parts = append(parts, p.Country)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Provider — источник данных о погоде
+type Provider interface {
+	// Name возвращает имя провайдера для логов
+	Name() string
+	// Current возвращает текущую погоду по координатам в единицах и на языке prefs
+	Current(lat, lon float64, prefs Preferences) (*WeatherData, error)
+	// Forecast возвращает прогноз на 5 дней с шагом 3 часа по координатам
+	Forecast(lat, lon float64, prefs Preferences) (*WeatherForecast, error)
+	// Geocode ищет населенные пункты по названию
+	Geocode(query string) ([]Place, error)
+	// ReverseGeocode возвращает населенный пункт по координатам
+	ReverseGeocode(lat, lon float64) (Place, error)
+}
+
+var ErrPlaceNotFound = errors.New("place not found")
+
+// NewProviderFromEnv создает провайдера по WEATHER_PROVIDER. Основной провайдер
+// дополняется остальными доступными на случай ошибки. OpenWeatherMap доступен
+// только при заданном OPENWEATHER_API_KEY, Open-Meteo работает без ключа.
+func NewProviderFromEnv() Provider {
+	apiKey := os.Getenv("OPENWEATHER_API_KEY")
+
+	available := map[string]Provider{ProviderOpenMeteo: NewOpenMeteo()}
+	if apiKey != "" {
+		available[ProviderOpenWeatherMap] = NewOpenWeatherMap(apiKey)
+	}
+
+	primary := strings.ToLower(os.Getenv("WEATHER_PROVIDER"))
+	switch {
+	case primary == "" && apiKey != "":
+		primary = ProviderOpenWeatherMap
+	case primary == "":
+		primary = ProviderOpenMeteo
+	case available[primary] == nil:
+		log.Printf("Weather provider %q is unavailable, using %s", primary, ProviderOpenMeteo)
+		primary = ProviderOpenMeteo
+	}
+
+	providers := []Provider{available[primary]}
+	for _, name := range []string{ProviderOpenWeatherMap, ProviderOpenMeteo} {
+		if provider, ok := available[name]; ok && name != primary {
+			providers = append(providers, provider)
+		}
+	}
+
+	if len(providers) == 1 {
+		return providers[0]
+	}
+	return NewFailover(providers...)
+}
+
+// failover обращается к провайдерам по очереди, пока один из них не ответит
+type failover struct {
+	providers []Provider
+}
+
+// NewFailover объединяет провайдеров: при ошибке запрос повторяется у следующего
+func NewFailover(providers ...Provider) Provider {
+	return &failover{providers: providers}
+}
+
+func (f *failover) Name() string {
+	names := make([]string, len(f.providers))
+	for i, provider := range f.providers {
+		names[i] = provider.Name()
+	}
+	return strings.Join(names, "+")
+}
+
+func (f *failover) Current(lat, lon float64, prefs Preferences) (*WeatherData, error) {
+	return tryEach(f.providers, "current weather", func(p Provider) (*WeatherData, error) {
+		return p.Current(lat, lon, prefs)
+	})
+}
+
+func (f *failover) Forecast(lat, lon float64, prefs Preferences) (*WeatherForecast, error) {
+	return tryEach(f.providers, "forecast", func(p Provider) (*WeatherForecast, error) {
+		return p.Forecast(lat, lon, prefs)
+	})
+}
+
+func (f *failover) Geocode(query string) ([]Place, error) {
+	return tryEach(f.providers, "geocode", func(p Provider) ([]Place, error) {
+		places, err := p.Geocode(query)
+		// Пустой результат тоже повод спросить другого провайдера
+		if err == nil && len(places) == 0 {
+			err = ErrPlaceNotFound
+		}
+		return places, err
+	})
+}
+
+func (f *failover) ReverseGeocode(lat, lon float64) (Place, error) {
+	return tryEach(f.providers, "reverse geocode", func(p Provider) (Place, error) {
+		return p.ReverseGeocode(lat, lon)
+	})
+}
+
+// tryEach вызывает call у провайдеров по порядку и возвращает первый успешный результат
+func tryEach[T any](providers []Provider, operation string, call func(Provider) (T, error)) (T, error) {
+	var errs []error
+	for _, provider := range providers {
+		result, err := call(provider)
+		if err == nil {
+			return result, nil
+		}
+
+		log.Printf("Weather provider %s failed to get %s: %v", provider.Name(), operation, err)
+		errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+	}
+
+	var zero T
+	return zero, errors.Join(errs...)
+}
@@ -3,107 +3,261 @@ package weather
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"log"
 	"net/http"
-	"os"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 )
 
-// WeatherData — структура под JSON-ответ OpenWeatherMap
+// WeatherData — текущая погода в точке, не зависит от провайдера
 type WeatherData struct {
-	Name string `json:"name"`
-	Main struct {
-		Temp      float64 `json:"temp"`
-		Humidity  int     `json:"humidity"`
-		FeelsLike float64 `json:"feels_like"`
-		Pressure  int     `json:"pressure"`
-	} `json:"main"`
-	Weather []struct {
-		Description string `json:"description"`
-		Main        string `json:"main"`
-		Icon        string `json:"icon"`
-	} `json:"weather"`
-	Wind struct {
-		Speed float64 `json:"speed"`
-	} `json:"wind"`
-	Visibility int `json:"visibility"`
+	Name        string
+	Lat         float64
+	Lon         float64
+	Temp        float64
+	FeelsLike   float64
+	Pressure    int // гПа
+	Humidity    int // %
+	WindSpeed   float64
+	Visibility  int // метры
+	Condition   string
+	Description string
+	// Timezone — смещение местного времени от UTC в секундах
+	Timezone int
+	// Zone — IANA-имя часового пояса, если провайдер его сообщает
+	Zone string
 }
 
+// Таймаут запроса к провайдеру, после которого переключаемся на следующий
+const requestTimeout = 10 * time.Second
+
+var httpClient = &http.Client{Timeout: requestTimeout}
+
+const userAgent = "GreenAssistantBot/1.0"
+
+// WeatherService получает погоду у провайдера (см. NewProviderFromEnv) и форматирует ее
 type WeatherService struct {
-	apiKey string
+	provider Provider
+	// zones определяет часовой пояс мест, для которых его не сообщил геокодер
+	zones ZoneFinder
+}
+
+// ZoneFinder определяет IANA-имя часового пояса по координатам
+type ZoneFinder interface {
+	Zone(lat, lon float64) (string, error)
 }
 
+// NewWeatherService создает сервис с провайдером из настроек окружения.
+// Ответы провайдера кэшируются на WEATHER_CACHE_TTL.
 func NewWeatherService() *WeatherService {
-	apiKey := os.Getenv("OPENWEATHER_API_KEY")
-	if apiKey == "" {
-		panic("OPENWEATHER_API_KEY environment variable is required")
+	provider := NewProviderFromEnv()
+	log.Printf("Using weather provider: %s", provider.Name())
+
+	if ttl := CacheTTLFromEnv(); ttl > 0 {
+		provider = NewCachingProvider(provider, ttl)
 	}
-	return &WeatherService{apiKey: apiKey}
+	// Часовой пояс по координатам знает только Open-Meteo, ключ для него не нужен
+	return &WeatherService{provider: provider, zones: NewOpenMeteo()}
+}
+
+// NewWeatherServiceWithProvider создает сервис с заданным провайдером. Часовые
+// пояса по координатам определяются, если провайдер реализует ZoneFinder.
+func NewWeatherServiceWithProvider(provider Provider) *WeatherService {
+	zones, _ := provider.(ZoneFinder)
+	return &WeatherService{provider: provider, zones: zones}
 }
 
 // GetWeatherData получает данные о погоде для указанного города
-func (ws *WeatherService) GetWeatherData(city string) (*WeatherData, error) {
-	url := fmt.Sprintf("http://api.openweathermap.org/data/2.5/weather?q=%s&appid=%s&units=metric&lang=ru",
-		city, ws.apiKey)
+func (ws *WeatherService) GetWeatherData(city string, prefs Preferences) (*WeatherData, error) {
+	places, err := ws.Geocode(city)
+	if err != nil {
+		return nil, err
+	}
+	place := places[0]
+
+	weather, err := ws.provider.Current(place.Lat, place.Lon, prefs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Название из геокодера локализовано лучше, чем название метеостанции
+	weather.Name = place.Name
+	if weather.Zone == "" {
+		weather.Zone = place.Zone
+	}
+	return weather, nil
+}
+
+// GetWeatherDataByCoords получает данные о погоде по координатам
+func (ws *WeatherService) GetWeatherDataByCoords(lat, lon float64, prefs Preferences) (*WeatherData, error) {
+	return ws.provider.Current(lat, lon, prefs)
+}
+
+// GetForecast получает прогноз на 5 дней с шагом 3 часа по координатам
+func (ws *WeatherService) GetForecast(lat, lon float64, prefs Preferences) (*WeatherForecast, error) {
+	forecast, err := ws.provider.Forecast(lat, lon, prefs)
+	if err != nil {
+		return nil, err
+	}
+	if forecast.City == "" {
+		forecast.City = formatCoords(lat, lon)
+	}
+	return forecast, nil
+}
+
+// Geocode ищет населенные пункты по названию. Если ничего не найдено, возвращает ErrPlaceNotFound.
+func (ws *WeatherService) Geocode(query string) ([]Place, error) {
+	places, err := ws.provider.Geocode(strings.TrimSpace(query))
+	if err != nil {
+		return nil, err
+	}
+	if len(places) == 0 {
+		return nil, ErrPlaceNotFound
+	}
+	return places, nil
+}
+
+// PlaceZone возвращает IANA-имя часового пояса места: от геокодера, а если он его
+// не сообщил — по координатам. Пустая строка, если пояс определить не удалось.
+func (ws *WeatherService) PlaceZone(place Place) string {
+	if validZone(place.Zone) {
+		return place.Zone
+	}
+	if ws.zones == nil {
+		return ""
+	}
+
+	zone, err := ws.zones.Zone(place.Lat, place.Lon)
+	if err != nil {
+		log.Printf("Error detecting timezone for %s: %v", place.Label(), err)
+		return ""
+	}
+	if !validZone(zone) {
+		return ""
+	}
+	return zone
+}
+
+// validZone проверяет, что zone — известное IANA-имя часового пояса
+func validZone(zone string) bool {
+	if zone == "" {
+		return false
+	}
+	_, err := time.LoadLocation(zone)
+	return err == nil
+}
+
+// GetStats возвращает счетчики кэша ответов провайдера или nil, если кэш отключен
+func (ws *WeatherService) GetStats() map[string]interface{} {
+	if statsProvider, ok := ws.provider.(interface{ GetStats() map[string]interface{} }); ok {
+		return statsProvider.GetStats()
+	}
+	return nil
+}
 
-	resp, err := http.Get(url)
+// ReverseGeocode возвращает населенный пункт по координатам
+func (ws *WeatherService) ReverseGeocode(lat, lon float64) (Place, error) {
+	return ws.provider.ReverseGeocode(lat, lon)
+}
+
+// getJSON выполняет GET-запрос и декодирует JSON-ответ в target
+func getJSON(address string, target interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, address, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	// Nominatim отклоняет запросы без User-Agent
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %v", err)
+		return fmt.Errorf("failed to make request: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("error: received status code %d: %s", resp.StatusCode, string(bodyBytes))
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error: received status code %d: %s", resp.StatusCode, string(bodyBytes))
 	}
 
-	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %v", err)
+		return fmt.Errorf("failed to read response: %v", err)
 	}
 
-	var weather WeatherData
-	err = json.Unmarshal(bodyBytes, &weather)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode JSON: %v", err)
+	if err := json.Unmarshal(bodyBytes, target); err != nil {
+		return fmt.Errorf("failed to decode JSON: %v", err)
 	}
 
-	return &weather, nil
+	return nil
 }
 
-// FormatWeatherMessage форматирует данные о погоде в красивое сообщение
-func (ws *WeatherService) FormatWeatherMessage(weather *WeatherData) string {
-	var description string
-	if len(weather.Weather) > 0 {
-		description = strings.Title(weather.Weather[0].Description)
-	} else {
-		description = "Нет данных"
+// coordsQuery формирует параметры запроса с координатами под заданными именами
+func coordsQuery(latKey, lonKey string, lat, lon float64) url.Values {
+	return url.Values{
+		latKey: {strconv.FormatFloat(lat, 'f', -1, 64)},
+		lonKey: {strconv.FormatFloat(lon, 'f', -1, 64)},
+	}
+}
+
+// formatCoords возвращает координаты для показа вместо названия места
+func formatCoords(lat, lon float64) string {
+	return fmt.Sprintf("%.2f, %.2f", lat, lon)
+}
+
+// FormatWeatherMessage форматирует данные о погоде в красивое сообщение.
+// Данные должны быть получены с теми же prefs.
+func (ws *WeatherService) FormatWeatherMessage(weather *WeatherData, prefs Preferences) string {
+	prefs = prefs.normalized()
+	l := prefs.labels()
+
+	description := strings.Title(weather.Description)
+	if description == "" {
+		description = l.noData
 	}
 
 	// Эмодзи для разных погодных условий
-	emoji := ws.getWeatherEmoji(weather)
+	emoji := conditionEmoji(weather.Condition)
 
-	return fmt.Sprintf(`%s Погода в %s:
+	name := weather.Name
+	if name == "" {
+		name = formatCoords(weather.Lat, weather.Lon)
+	}
+
+	return fmt.Sprintf(`%s %s:
 
-🌡️ Температура: %.1f°C
-💨 Ощущается как: %.1f°C
-📊 Давление: %d hPa
-💧 Влажность: %d%%
-🌬️ Ветер: %.1f м/с
-👁️ Видимость: %d км
+🌡️ %s: %.1f%s
+💨 %s: %.1f%s
+📊 %s: %s
+💧 %s: %d%%
+🌬️ %s: %.1f %s
+👁️ %s: %s
 
-%s`, emoji, weather.Name, weather.Main.Temp, weather.Main.FeelsLike,
-		weather.Main.Pressure, weather.Main.Humidity, weather.Wind.Speed,
-		weather.Visibility/1000, description)
+%s`, emoji, fmt.Sprintf(l.weatherIn, name),
+		l.temperature, weather.Temp, prefs.tempUnit(),
+		l.feelsLike, weather.FeelsLike, prefs.tempUnit(),
+		l.pressure, prefs.formatPressure(weather.Pressure),
+		l.humidity, weather.Humidity,
+		l.wind, weather.WindSpeed, prefs.windUnit(),
+		l.visibility, prefs.formatVisibility(weather.Visibility), description)
 }
 
-// getWeatherEmoji возвращает эмодзи в зависимости от погодных условий
-func (ws *WeatherService) getWeatherEmoji(weather *WeatherData) string {
-	if len(weather.Weather) == 0 {
-		return "🌤️"
+// FormatWeatherSummary форматирует погоду одной строкой для сводки по нескольким местам
+func (ws *WeatherService) FormatWeatherSummary(label string, weather *WeatherData, prefs Preferences) string {
+	prefs = prefs.normalized()
+	description := weather.Description
+	if description == "" {
+		description = prefs.labels().noData
 	}
+	return fmt.Sprintf("%s %s: %s%s, %s, 💨 %.0f %s", conditionEmoji(weather.Condition), label,
+		formatTemp(weather.Temp), prefs.tempUnit(), description, weather.WindSpeed, prefs.windUnit())
+}
 
-	main := weather.Weather[0].Main
+// conditionEmoji возвращает эмодзи для группы погодных условий (в терминах OpenWeatherMap)
+func conditionEmoji(main string) string {
 	switch main {
 	case "Clear":
 		return "☀️"
@@ -124,7 +278,43 @@ func (ws *WeatherService) getWeatherEmoji(weather *WeatherData) string {
 	}
 }
 
-// IsValidCity проверяет, существует ли город (базовая проверка)
+// IsValidCity проверяет, что геокодер находит город с таким названием
 func (ws *WeatherService) IsValidCity(city string) bool {
-	return len(strings.TrimSpace(city)) > 1
+	_, err := ws.Geocode(city)
+	return err == nil
+}
+
+// Часовые пояса России по смещению от UTC. Для остальных смещений
+// используются зоны Etc/GMT, которые не учитывают летнее время.
+var russianZones = map[int]string{
+	2:  "Europe/Kaliningrad",
+	3:  "Europe/Moscow",
+	4:  "Europe/Samara",
+	5:  "Asia/Yekaterinburg",
+	6:  "Asia/Omsk",
+	7:  "Asia/Novosibirsk",
+	8:  "Asia/Irkutsk",
+	9:  "Asia/Yakutsk",
+	10: "Asia/Vladivostok",
+	11: "Asia/Magadan",
+	12: "Asia/Kamchatka",
+}
+
+// ZoneForOffset возвращает IANA-имя часового пояса для смещения от UTC в секундах.
+// Для смещений, не кратных часу, возвращает пустую строку. Зоны Etc/GMT не знают
+// о летнем времени, поэтому годятся только когда настоящий пояс места неизвестен.
+func ZoneForOffset(offset int) string {
+	if offset%3600 != 0 {
+		return ""
+	}
+
+	hours := offset / 3600
+	if zone, ok := russianZones[hours]; ok {
+		return zone
+	}
+	if hours == 0 {
+		return "UTC"
+	}
+	// В именах Etc/GMT знак инвертирован: Etc/GMT-5 — это UTC+5
+	return fmt.Sprintf("Etc/GMT%+d", -hours)
 }
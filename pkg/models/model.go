@@ -1,9 +0,0 @@
-package models
-
-type UserData struct {
-	Name        string
-	City        string
-	Data        string
-	Category    string
-	MessageData string
-}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SessionVersion — текущая версия формата сериализации сессии.
+// Увеличивается при несовместимых изменениях структуры Session.
+const SessionVersion = 1
+
+// Purpose определяет, для какого сценария пользователь выбирает категорию или заметку
+type Purpose string
+
+const (
+	PurposeNewNote        Purpose = "new_note"
+	PurposeViewNotes      Purpose = "view_notes"
+	PurposeEditCategory   Purpose = "edit_category"
+	PurposeDeleteCategory Purpose = "delete_category"
+	PurposeSaveForwarded  Purpose = "save_forwarded_message"
+	PurposeEditNote       Purpose = "edit_note"
+	PurposeDeleteNote     Purpose = "delete_note"
+	PurposeSearchNotes    Purpose = "search_notes"
+	PurposeSetReminder    Purpose = "set_reminder"
+)
+
+// NoteDraft — содержимое заметки, ожидающее выбора категории
+type NoteDraft struct {
+	Type    NoteType `json:"type"`
+	Content string   `json:"content,omitempty"`
+	FileID  string   `json:"file_id,omitempty"`
+}
+
+// Session — данные текущего диалога с пользователем
+type Session struct {
+	Purpose    Purpose    `json:"purpose,omitempty"`
+	CategoryID uint       `json:"category_id,omitempty"`
+	NoteID     uint       `json:"note_id,omitempty"`
+	Draft      *NoteDraft `json:"draft,omitempty"`
+	// Query — последний поисковый запрос, нужен для листания результатов
+	Query string `json:"query,omitempty"`
+	// LocationLabel — название добавляемого места, пока пользователь выбирает город
+	LocationLabel string `json:"location_label,omitempty"`
+}
+
+type sessionEnvelope struct {
+	Version int             `json:"v"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Encode сериализует сессию вместе с номером версии формата
+func (s Session) Encode() ([]byte, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(sessionEnvelope{Version: SessionVersion, Data: data})
+}
+
+// DecodeSession восстанавливает сессию, сохраненную через Encode
+func DecodeSession(raw []byte) (Session, error) {
+	var envelope sessionEnvelope
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return Session{}, err
+	}
+
+	if envelope.Version != SessionVersion {
+		return Session{}, fmt.Errorf("unsupported session version %d", envelope.Version)
+	}
+
+	var session Session
+	if err := json.Unmarshal(envelope.Data, &session); err != nil {
+		return Session{}, err
+	}
+	return session, nil
+}